@@ -19,6 +19,7 @@ import (
 const (
 	targetsChannels = "channels"
 	targetsReleases = "releases"
+	targetsYanked   = "yanked"
 
 	channelsDir = targetsChannels
 	releasesDir = targetsReleases
@@ -95,3 +95,21 @@ func NewReleaseBinSeveralFilesFoundError(repoName, version string, names []strin
 func (e ReleaseBinSeveralFilesFoundError) Error() string {
 	return fmt.Sprintf("several binary files found in version %q", e.Version)
 }
+
+type ReleaseYankedError struct {
+	RepoName string
+	Version  string
+	Reason   string
+}
+
+func NewReleaseYankedError(repoName, version, reason string) error {
+	return ReleaseYankedError{
+		RepoName: repoName,
+		Version:  version,
+		Reason:   reason,
+	}
+}
+
+func (e ReleaseYankedError) Error() string {
+	return fmt.Sprintf("version %q is yanked: %s", e.Version, e.Reason)
+}
@@ -1,6 +1,7 @@
 package repo
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path"
@@ -220,6 +221,10 @@ func (c Client) selectAppropriateReleaseTargets(release string) (targets data.Ta
 	}
 
 	if len(targets) == 0 {
+		if err := c.checkReleaseYanked(release); err != nil {
+			return nil, "", err
+		}
+
 		return nil, "", fmt.Errorf(
 			"version %q not found in the repository (os: %q, arch: %q)",
 			release, runtime.GOOS, runtime.GOARCH,
@@ -293,12 +298,52 @@ func (c Client) findRelease(version string) (string, error) {
 	}
 
 	if latestValid == nil {
+		// The yanked release is reported only if there is no other release for the version.
+		for name := range targets {
+			releaseName, ok := strings.CutPrefix(name, targetsYanked+"/")
+			if !ok {
+				continue
+			}
+
+			releaseVersion, err := semver.NewVersion(releaseName)
+			if err != nil || !constraint.Check(releaseVersion) {
+				continue
+			}
+
+			if err := c.checkReleaseYanked(releaseName); err != nil {
+				return "", err
+			}
+		}
+
 		return "", fmt.Errorf("unable to find release for version %q", version)
 	}
 
 	return latestValidName, nil
 }
 
+func (c Client) checkReleaseYanked(release string) error {
+	targets, err := c.tufClient.GetTargets()
+	if err != nil {
+		return fmt.Errorf("get targets: %w", err)
+	}
+
+	targetMeta, ok := targets[path.Join(targetsYanked, release)]
+	if !ok {
+		return nil
+	}
+
+	var custom struct {
+		Reason string `json:"reason"`
+	}
+	if targetMeta.Custom != nil {
+		if err := json.Unmarshal(*targetMeta.Custom, &custom); err != nil {
+			return fmt.Errorf("unable to parse yanked release %q metadata: %w", release, err)
+		}
+	}
+
+	return NewReleaseYankedError(c.repoName, release, custom.Reason)
+}
+
 func isLocalFileUpToDate(path string, targetMeta data.TargetFileMeta) (bool, error) {
 	exist, err := util.IsRegularFileExist(path)
 	if err != nil {
@@ -0,0 +1,63 @@
+package repo
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/theupdateframework/go-tuf/data"
+)
+
+type targetsTufClient struct {
+	TufInterface
+	targets data.TargetFiles
+}
+
+func (c targetsTufClient) GetTargets() (data.TargetFiles, error) {
+	return c.targets, nil
+}
+
+func (c targetsTufClient) DownloadFile(string, string, os.FileMode) error {
+	return errors.New("unexpected download")
+}
+
+func yankedTargetMeta(reason string) data.TargetFileMeta {
+	custom := json.RawMessage(`{"reason":"` + reason + `"}`)
+	return data.TargetFileMeta{Custom: &custom}
+}
+
+func TestFindRelease_yanked(t *testing.T) {
+	c := Client{repoName: "test", tufClient: targetsTufClient{targets: data.TargetFiles{
+		"releases/1.0.0/any-any/bin/app": {},
+		"yanked/1.1.0":                   yankedTargetMeta("broken"),
+	}}}
+
+	if release, err := c.findRelease("1.0"); err != nil || release != "1.0.0" {
+		t.Fatalf("findRelease(1.0): got %q, %v, want the release not affected by the yanked one", release, err)
+	}
+
+	_, err := c.findRelease("1.1.0")
+	var yankedErr ReleaseYankedError
+	if !errors.As(err, &yankedErr) {
+		t.Fatalf("findRelease(1.1.0): got %v, want ReleaseYankedError", err)
+	}
+	if yankedErr.Version != "1.1.0" || yankedErr.Reason != "broken" {
+		t.Fatalf("findRelease(1.1.0): got %+v", yankedErr)
+	}
+
+	if _, err := c.findRelease("2.0.0"); errors.As(err, &yankedErr) {
+		t.Fatalf("findRelease(2.0.0): got %v, want the not found error", err)
+	}
+}
+
+func TestSelectAppropriateReleaseTargets_yanked(t *testing.T) {
+	c := Client{repoName: "test", tufClient: targetsTufClient{targets: data.TargetFiles{
+		"yanked/1.1.0": yankedTargetMeta("broken"),
+	}}}
+
+	_, _, err := c.selectAppropriateReleaseTargets("1.1.0")
+	if err == nil || err.Error() != `version "1.1.0" is yanked: broken` {
+		t.Fatalf("got %v, want the yanked error", err)
+	}
+}
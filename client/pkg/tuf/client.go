@@ -8,7 +8,6 @@ import (
 
 	tufClient "github.com/theupdateframework/go-tuf/client"
 	leveldbstore "github.com/theupdateframework/go-tuf/client/leveldbstore"
-	"github.com/theupdateframework/go-tuf/data"
 
 	"github.com/werf/lockgate"
 	"github.com/werf/lockgate/pkg/file_locker"
@@ -168,6 +167,11 @@ func (c *Client) Update() error {
 		return fmt.Errorf("unable to update tuf meta: %w", err)
 	}
 
+	// Load the delegated targets meta to save it along with the top-level meta.
+	if _, err := c.GetTargets(); err != nil {
+		return fmt.Errorf("unable to update delegated targets meta: %w", err)
+	}
+
 	return lockgate.WithAcquire(
 		c.locker, metaLocalStoreDirLockName,
 		lockgate.AcquireOptions{Shared: false, Timeout: time.Minute * 2},
@@ -203,7 +207,3 @@ func (c *Client) saveMeta() error {
 
 	return nil
 }
-
-func (c Client) GetTargets() (data.TargetFiles, error) {
-	return c.Client.Targets()
-}
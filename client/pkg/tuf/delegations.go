@@ -0,0 +1,226 @@
+package tuf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/theupdateframework/go-tuf/data"
+	tufUtil "github.com/theupdateframework/go-tuf/util"
+	"github.com/theupdateframework/go-tuf/verify"
+)
+
+// GetTargets returns the targets of the top-level targets role and all the delegated targets roles.
+// The top-level targets metadata is verified by the TUF client, the delegated targets metadata
+// is verified here with the keys of the delegating role and is downloaded if it is missing in the local store.
+func (c Client) GetTargets() (data.TargetFiles, error) {
+	topLevelTargets, err := c.Client.Targets()
+	if err != nil {
+		return nil, err
+	}
+
+	allMeta, err := c.ReadOnlyLocalStore.GetMeta()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get meta: %w", err)
+	}
+
+	root := &data.Root{}
+	if err := unmarshalSignedMeta(allMeta, "root.json", root); err != nil {
+		return nil, err
+	}
+
+	snapshot := &data.Snapshot{}
+	if err := unmarshalSignedMeta(allMeta, "snapshot.json", snapshot); err != nil {
+		return nil, err
+	}
+
+	targets := &data.Targets{}
+	if err := unmarshalSignedMeta(allMeta, "targets.json", targets); err != nil {
+		return nil, err
+	}
+
+	result := make(data.TargetFiles)
+	for name, meta := range topLevelTargets {
+		result[name] = meta
+	}
+
+	w := delegationsWalker{
+		client:             c,
+		localMeta:          allMeta,
+		snapshot:           snapshot,
+		consistentSnapshot: root.ConsistentSnapshot,
+		visited:            map[string]bool{"targets": true},
+		result:             result,
+	}
+
+	if err := w.walk(targets.Delegations, nil); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+type delegationsWalker struct {
+	client             Client
+	localMeta          map[string]json.RawMessage
+	snapshot           *data.Snapshot
+	consistentSnapshot bool
+	visited            map[string]bool
+	result             data.TargetFiles
+}
+
+// walk collects the targets of the delegated roles in the pre-order depth-first order,
+// the targets found first take precedence. A delegated target is accepted only if it matches
+// the paths of all the delegations leading to the role.
+func (w *delegationsWalker) walk(delegations *data.Delegations, chain []data.DelegatedRole) error {
+	if delegations == nil {
+		return nil
+	}
+
+	db, err := verify.NewDBFromDelegations(delegations)
+	if err != nil {
+		return fmt.Errorf("unable to init delegations verification db: %w", err)
+	}
+
+	for _, role := range delegations.Roles {
+		if w.visited[role.Name] {
+			continue
+		}
+		w.visited[role.Name] = true
+
+		targets, err := w.loadDelegatedTargets(role.Name, db)
+		if err != nil {
+			return err
+		}
+
+		roleChain := append(append([]data.DelegatedRole{}, chain...), role)
+
+		for name, meta := range targets.Targets {
+			if _, ok := w.result[name]; ok {
+				continue
+			}
+
+			matches, err := matchesDelegationsChain(roleChain, name)
+			if err != nil {
+				return err
+			}
+
+			if matches {
+				w.result[name] = meta
+			}
+		}
+
+		if err := w.walk(targets.Delegations, roleChain); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *delegationsWalker) loadDelegatedTargets(role string, db *verify.DB) (*data.Targets, error) {
+	fileName := role + ".json"
+
+	fileMeta, ok := w.snapshot.Meta[fileName]
+	if !ok {
+		return nil, fmt.Errorf("delegated targets role %q is not in the snapshot", role)
+	}
+
+	raw, alreadyStored := w.localMeta[fileName]
+	if alreadyStored {
+		alreadyStored = snapshotFileMetaMatches(raw, fileMeta)
+	}
+
+	if !alreadyStored {
+		var err error
+		raw, err = w.downloadMeta(fileName, fileMeta)
+		if err != nil {
+			return nil, fmt.Errorf("unable to download %q: %w", fileName, err)
+		}
+	}
+
+	targets := &data.Targets{}
+	if err := db.Unmarshal(raw, targets, role, fileMeta.Version); err != nil {
+		return nil, fmt.Errorf("unable to verify %q: %w", fileName, err)
+	}
+
+	if !alreadyStored {
+		if err := w.client.ReadOnlyLocalStore.SetMeta(fileName, raw); err != nil {
+			return nil, fmt.Errorf("unable to set meta %q: %w", fileName, err)
+		}
+	}
+
+	return targets, nil
+}
+
+func (w *delegationsWalker) downloadMeta(fileName string, fileMeta data.SnapshotFileMeta) ([]byte, error) {
+	remotePath := fileName
+	if w.consistentSnapshot {
+		remotePath = tufUtil.VersionedPath(fileName, fileMeta.Version)
+	}
+
+	r, _, err := w.client.RemoteStore.GetMeta(remotePath)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var stream io.Reader = r
+	if fileMeta.Length != 0 {
+		stream = io.LimitReader(r, fileMeta.Length)
+	}
+
+	raw, err := io.ReadAll(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tufUtil.BytesMatchLenAndHashes(raw, fileMeta.Length, fileMeta.Hashes); err != nil {
+		return nil, err
+	}
+
+	return raw, nil
+}
+
+func snapshotFileMetaMatches(raw []byte, fileMeta data.SnapshotFileMeta) bool {
+	meta, err := tufUtil.GenerateSnapshotFileMeta(bytes.NewReader(raw), fileMeta.Hashes.HashAlgorithms()...)
+	if err != nil {
+		return false
+	}
+
+	return tufUtil.SnapshotFileMetaEqual(meta, fileMeta) == nil
+}
+
+func matchesDelegationsChain(chain []data.DelegatedRole, name string) (bool, error) {
+	for _, role := range chain {
+		matches, err := role.MatchesPath(name)
+		if err != nil {
+			return false, fmt.Errorf("unable to match target %q with delegated role %q paths: %w", name, role.Name, err)
+		}
+
+		if !matches {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func unmarshalSignedMeta(allMeta map[string]json.RawMessage, name string, v interface{}) error {
+	raw, ok := allMeta[name]
+	if !ok {
+		return fmt.Errorf("%q not found in the local store", name)
+	}
+
+	signed := &data.Signed{}
+	if err := json.Unmarshal(raw, signed); err != nil {
+		return fmt.Errorf("unable to unmarshal %q: %w", name, err)
+	}
+
+	if err := json.Unmarshal(signed.Signed, v); err != nil {
+		return fmt.Errorf("unable to unmarshal %q signed data: %w", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package tuf
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/theupdateframework/go-tuf"
+	tufClient "github.com/theupdateframework/go-tuf/client"
+	"github.com/theupdateframework/go-tuf/data"
+	"github.com/theupdateframework/go-tuf/pkg/keys"
+)
+
+// memoryRemoteStore serves the metadata committed into the memory store of the test repository.
+type memoryRemoteStore struct {
+	meta         map[string]json.RawMessage
+	getMetaCalls []string
+}
+
+func (s *memoryRemoteStore) GetMeta(name string) (io.ReadCloser, int64, error) {
+	s.getMetaCalls = append(s.getMetaCalls, name)
+
+	raw, ok := s.meta[name]
+	if !ok {
+		return nil, 0, tufClient.ErrNotFound{File: name}
+	}
+
+	return io.NopCloser(bytes.NewReader(raw)), int64(len(raw)), nil
+}
+
+func (s *memoryRemoteStore) GetTarget(path string) (io.ReadCloser, int64, error) {
+	return nil, 0, tufClient.ErrNotFound{File: path}
+}
+
+// newDelegationsTestRepo returns the committed metadata of the repository delegating releases/* to the releases role,
+// which delegates releases/1.*/* further to the nested role.
+func newDelegationsTestRepo(t *testing.T, consistentSnapshot bool) map[string]json.RawMessage {
+	meta := make(map[string]json.RawMessage)
+	store := tuf.MemoryStore(meta, nil)
+
+	repo, err := tuf.NewRepo(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.Init(consistentSnapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, role := range []string{"root", "targets", "snapshot", "timestamp"} {
+		if _, err := repo.GenKey(role); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	addDelegatedRole := func(delegator, name string, paths []string) {
+		signer, err := keys.GenerateEd25519Key()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := store.SaveSigner(name, signer); err != nil {
+			t.Fatal(err)
+		}
+
+		role := data.DelegatedRole{Name: name, KeyIDs: signer.PublicData().IDs(), Paths: paths, Threshold: 1}
+		if err := repo.AddDelegatedRole(delegator, role, []*data.PublicKey{signer.PublicData()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	addDelegatedRole("targets", "releases", []string{"releases/*/*"})
+	addDelegatedRole("releases", "nested", []string{"releases/1.*/*"})
+
+	for _, name := range []string{"top-level", "releases/0.1.0/app", "releases/1.0.0/app"} {
+		digest := hex.EncodeToString(bytes.Repeat([]byte{1}, 64))
+		if err := repo.AddTargetsWithDigest(digest, "sha512", 1, name, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := repo.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.Timestamp(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	return meta
+}
+
+func newDelegationsTestClient(t *testing.T, remote *memoryRemoteStore) Client {
+	local := tufClient.MemoryLocalStore()
+	c := Client{Client: tufClient.NewClient(local, remote), RemoteStore: remote, ReadOnlyLocalStore: local}
+
+	if err := c.Client.Init(remote.meta["root.json"]); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Client.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func TestClient_GetTargets(t *testing.T) {
+	for _, consistentSnapshot := range []bool{false, true} {
+		remote := &memoryRemoteStore{meta: newDelegationsTestRepo(t, consistentSnapshot)}
+		c := newDelegationsTestClient(t, remote)
+
+		targets, err := c.GetTargets()
+		if err != nil {
+			t.Fatalf("consistent snapshot %t: %s", consistentSnapshot, err)
+		}
+
+		for _, name := range []string{"top-level", "releases/0.1.0/app", "releases/1.0.0/app"} {
+			if _, ok := targets[name]; !ok {
+				t.Fatalf("consistent snapshot %t: target %q not found in %v", consistentSnapshot, name, targets)
+			}
+		}
+
+		if len(targets) != 3 {
+			t.Fatalf("consistent snapshot %t: got %d targets, want 3", consistentSnapshot, len(targets))
+		}
+
+		// The verified delegated metadata is stored and is not downloaded again.
+		localMeta, err := c.ReadOnlyLocalStore.GetMeta()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, name := range []string{"releases.json", "nested.json"} {
+			if _, ok := localMeta[name]; !ok {
+				t.Fatalf("consistent snapshot %t: %q not stored", consistentSnapshot, name)
+			}
+		}
+
+		remote.getMetaCalls = nil
+		if _, err := c.GetTargets(); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(remote.getMetaCalls) != 0 {
+			t.Fatalf("consistent snapshot %t: got downloads %v, want none", consistentSnapshot, remote.getMetaCalls)
+		}
+	}
+}
+
+func TestClient_GetTargets_tamperedDelegatedMeta(t *testing.T) {
+	remote := &memoryRemoteStore{meta: newDelegationsTestRepo(t, false)}
+	c := newDelegationsTestClient(t, remote)
+
+	tampered := bytes.Replace(remote.meta["releases.json"], []byte(`"version":1`), []byte(`"version":2`), 1)
+	if bytes.Equal(tampered, remote.meta["releases.json"]) {
+		t.Fatal("releases.json is not tampered")
+	}
+	remote.meta["releases.json"] = tampered
+
+	if _, err := c.GetTargets(); err == nil {
+		t.Fatal("got no error for the tampered delegated metadata")
+	}
+}
+
+func TestMatchesDelegationsChain(t *testing.T) {
+	chain := []data.DelegatedRole{
+		{Name: "releases", Paths: []string{"releases/*/*"}},
+		{Name: "nested", Paths: []string{"*/*/*"}},
+	}
+
+	for name, expected := range map[string]bool{
+		"releases/1.0.0/app": true,
+		"channels/1/stable":  false,
+	} {
+		matches, err := matchesDelegationsChain(chain, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if matches != expected {
+			t.Fatalf("matchesDelegationsChain(%q): got %t, want %t", name, matches, expected)
+		}
+	}
+}
@@ -20,6 +20,8 @@ func CreateCommands() []*cobra.Command {
 
 func createPublishCommand() *cobra.Command {
 	cmdData := &common.CmdData{}
+	var dryRun bool
+	var gitBranch string
 
 	publishCmd := &cobra.Command{
 		Use:   "publish <project-name>",
@@ -27,13 +29,18 @@ func createPublishCommand() *cobra.Command {
 		Args:  cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			projectName := args[0]
-			if err := publish(cmdData, projectName); err != nil {
+			if gitBranch != "" && !dryRun {
+				log.Fatal("Publish failed: --branch can be set only with --dry-run")
+			}
+			if err := publish(cmdData, projectName, dryRun, gitBranch); err != nil {
 				log.Fatalf("Publish failed: %s", err.Error())
 			}
 		},
 	}
 
 	common.SetupCmdData(cmdData, publishCmd)
+	publishCmd.Flags().BoolVarP(&dryRun, "dry-run", "", false, "Validate the channels config and show the channels changes without publishing them")
+	publishCmd.Flags().StringVarP(&gitBranch, "branch", "", "", "Git branch to dry run instead of the configured trdl channels branch (only with --dry-run)")
 	return publishCmd
 }
 
@@ -56,7 +63,7 @@ func createReleaseCommand() *cobra.Command {
 	return releaseCmd
 }
 
-func publish(c *common.CmdData, projectName string) error {
+func publish(c *common.CmdData, projectName string, dryRun bool, gitBranch string) error {
 	log := initLogger(c)
 	trdlClient, err := client.NewTrdlVaultClient(client.NewTrdlVaultClientOpts{
 		Address:     *c.Address,
@@ -69,6 +76,19 @@ func publish(c *common.CmdData, projectName string) error {
 	if err != nil {
 		return fmt.Errorf("unable to create client: %w", err)
 	}
+	if dryRun {
+		changes, err := trdlClient.PublishDryRun(projectName, gitBranch)
+		if err != nil {
+			return fmt.Errorf("unable to dry run publish: %w", err)
+		}
+		if len(changes) == 0 {
+			log.Info("No channels changes")
+		}
+		for _, change := range changes {
+			log.Info(fmt.Sprintf("Channel %q of group %q: %q -> %q", change.Channel, change.Group, change.PublishedVersion, change.Version))
+		}
+		return nil
+	}
 	if err := trdlClient.Publish(projectName); err != nil {
 		return fmt.Errorf("unable to publish project: %w", err)
 	}
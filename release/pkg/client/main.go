@@ -10,6 +10,7 @@ import (
 
 type Interface interface {
 	Publish(projectName string) error
+	PublishDryRun(projectName, gitBranch string) ([]vault.ChannelChange, error)
 	Release(projectName, gitTag string) error
 }
 
@@ -25,6 +26,10 @@ func (c *Client) Publish(projectName string) error {
 	return c.client.Publish(projectName)
 }
 
+func (c *Client) PublishDryRun(projectName, gitBranch string) ([]vault.ChannelChange, error) {
+	return c.client.PublishDryRun(projectName, gitBranch)
+}
+
 func (c *Client) Release(projectName, gitTag string) error {
 	return c.client.Release(projectName, gitTag)
 }
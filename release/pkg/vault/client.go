@@ -34,6 +34,14 @@ type Task struct {
 	Log    string `json:"result"`
 }
 
+// ChannelChange is the channel pointer change the publish would make.
+type ChannelChange struct {
+	Group            string `json:"group"`
+	Channel          string `json:"channel"`
+	PublishedVersion string `json:"published_version"`
+	Version          string `json:"version"`
+}
+
 type NewTrdlClientOpts struct {
 	Address     string
 	Token       string
@@ -141,6 +149,35 @@ func (c *TrdlClient) Publish(projectName string) error {
 	return nil
 }
 
+// PublishDryRun validates the channels config without publishing it and returns the channels changes.
+// The channels config of the git branch is validated instead of the configured one if the branch is set.
+func (c *TrdlClient) PublishDryRun(projectName, gitBranch string) ([]ChannelChange, error) {
+	data := map[string]interface{}{"dry_run": true}
+	if gitBranch != "" {
+		data["git_branch"] = gitBranch
+	}
+
+	resp, err := c.longRunningWrite(fmt.Sprintf("%s/publish", projectName), data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dry run publish of project %s: %w", projectName, err)
+	}
+	if resp == nil || resp.Data == nil {
+		return nil, fmt.Errorf("no response data")
+	}
+
+	dataBytes, err := json.Marshal(resp.Data["changes"])
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal changes: %w", err)
+	}
+
+	var changes []ChannelChange
+	if err := json.Unmarshal(dataBytes, &changes); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal changes: %w", err)
+	}
+
+	return changes, nil
+}
+
 func (c *TrdlClient) Release(projectName, gitTag string) error {
 	err := c.withBackoffRequest(
 		fmt.Sprintf("%s/release", projectName),
@@ -6,6 +6,7 @@ import (
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
 	"github.com/hashicorp/vault/sdk/logical"
 
 	"github.com/werf/trdl/server/pkg/publisher"
@@ -26,6 +27,8 @@ type Backend struct {
 	TasksManager    tasks_manager.ActionsInterface
 	Publisher       publisher.Interface
 	BackendPeriodic BackendPeriodicInterface
+
+	pendingReleaseLocks []*locksutil.LockEntry
 }
 
 var _ logical.Factory = Factory
@@ -52,8 +55,9 @@ func NewBackend(logger hclog.Logger) (*Backend, error) {
 	publisher := publisher.NewPublisher(logger)
 
 	b := &Backend{
-		TasksManager: tasksManager,
-		Publisher:    publisher,
+		TasksManager:        tasksManager,
+		Publisher:           publisher,
+		pendingReleaseLocks: locksutil.CreateLocks(),
 	}
 	b.BackendPeriodic = b
 
@@ -64,6 +68,12 @@ func NewBackend(logger hclog.Logger) (*Backend, error) {
 
 	b.InitPaths(tasksManager, publisher)
 	b.InitPeriodicFunc(tasksManager, publisher)
+
+	b.InitializeFunc = tasksManager.InitializeFunc
+	b.Clean = tasksManager.Clean
+	tasksManager.RegisterTaskResumeFunc(string(AuditOperationRelease), b.resumeReleaseTask, tasks_manager.RegisterTaskResumeFuncOptions{IsRetryableError: isRetryableTaskError})
+	tasksManager.RegisterTaskResumeFunc(string(AuditOperationPublish), b.resumePublishTask, tasks_manager.RegisterTaskResumeFuncOptions{IsRetryableError: isRetryableTaskError})
+
 	return b, nil
 }
 
@@ -72,8 +82,15 @@ func (b *Backend) InitPaths(modules ...BackendModuleInterface) {
 		configurePaths(b),
 		[]*framework.Path{
 			releasePath(b),
+			releaseYankPath(b),
+			releaseApprovePath(b),
 			publishPath(b),
+			rotateKeysPath(b),
+			statusPath(b),
+			channelsPath(b),
+			auditPath(b),
 		},
+		releasesPaths(b),
 	)
 
 	for _, module := range modules {
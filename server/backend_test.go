@@ -5,11 +5,14 @@ import (
 	"testing"
 
 	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"github.com/theupdateframework/go-tuf/data"
 
+	"github.com/werf/logboek"
 	"github.com/werf/trdl/server/pkg/publisher"
 	"github.com/werf/trdl/server/pkg/tasks_manager"
 )
@@ -35,7 +38,7 @@ func (m *MockedTasksManager) PeriodicFunc(_ context.Context, _ *logical.Request)
 	return nil
 }
 
-func (m *MockedTasksManager) RunTask(_ context.Context, _ logical.Storage, _ func(ctx context.Context, storage logical.Storage) error) (string, error) {
+func (m *MockedTasksManager) RunTask(_ context.Context, _ logical.Storage, _ func(ctx context.Context, storage logical.Storage) error, _ tasks_manager.TaskOptions) (string, error) {
 	m.Called()
 
 	if !m.IsBusy {
@@ -61,8 +64,59 @@ func (m *MockedPublisher) PeriodicFunc(_ context.Context, _ *logical.Request) er
 }
 
 func (m *MockedPublisher) GetRepository(_ context.Context, _ logical.Storage, _ publisher.RepositoryOptions) (publisher.RepositoryInterface, error) {
-	m.Called()
-	return nil, nil
+	args := m.Called()
+	repository, _ := args.Get(0).(publisher.RepositoryInterface)
+	return repository, nil
+}
+
+func (m *MockedPublisher) GetExistingReleases(_ context.Context, _ publisher.RepositoryInterface) ([]string, error) {
+	args := m.Called()
+	return args.Get(0).([]string), nil
+}
+
+func (m *MockedPublisher) GetReleaseTargets(_ context.Context, _ publisher.RepositoryInterface, releaseName string) ([]publisher.ReleaseTarget, error) {
+	args := m.Called(releaseName)
+	return args.Get(0).([]publisher.ReleaseTarget), nil
+}
+
+func (m *MockedPublisher) YankRelease(_ context.Context, _ logical.Storage, _ publisher.RepositoryInterface, releaseName string, opts publisher.YankReleaseOptions) error {
+	args := m.Called(releaseName, opts.Reason)
+	return args.Error(0)
+}
+
+func (m *MockedPublisher) GetYankedReleases(_ context.Context, _ publisher.RepositoryInterface) ([]publisher.YankedRelease, error) {
+	args := m.Called()
+	return args.Get(0).([]publisher.YankedRelease), nil
+}
+
+func (m *MockedPublisher) ImportRoot(_ context.Context, _ logical.Storage, _ publisher.RepositoryInterface, _ *data.Signed) error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockedPublisher) UpdateRoleKeys(_ context.Context, _ logical.Storage, _ publisher.RepositoryInterface, role string, opts publisher.UpdateRoleKeysOptions) error {
+	args := m.Called(role, opts)
+	return args.Error(0)
+}
+
+type MockedRepository struct {
+	mock.Mock
+	publisher.RepositoryInterface
+}
+
+func (m *MockedRepository) GetChannels(_ context.Context) ([]publisher.PublishedChannel, error) {
+	args := m.Called()
+	return args.Get(0).([]publisher.PublishedChannel), nil
+}
+
+func (m *MockedRepository) DiscardStaged(_ context.Context) error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockedRepository) RoleStatuses() ([]publisher.RoleStatus, error) {
+	args := m.Called()
+	return args.Get(0).([]publisher.RoleStatus), nil
 }
 
 type MockedBackendPeriodic struct {
@@ -95,6 +149,8 @@ func (suite *CommonSuite) SetupTest() {
 		TasksManager:    mockedTasksManager,
 		Publisher:       mockedPublisher,
 		BackendPeriodic: mockedBackendPeriodic,
+
+		pendingReleaseLocks: locksutil.CreateLocks(),
 	}
 	b.InitPaths()
 
@@ -114,6 +170,11 @@ func (suite *CommonSuite) SetupTest() {
 	suite.mockedBackendPeriodic = mockedBackendPeriodic
 }
 
+// taskContext returns the context with the task log, which the task funcs expect.
+func (suite *CommonSuite) taskContext() context.Context {
+	return logboek.NewContext(suite.ctx, logboek.DefaultLogger())
+}
+
 type BackendSuite struct {
 	CommonSuite
 }
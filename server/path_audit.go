@@ -0,0 +1,239 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/samber/lo"
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/werf/trdl/server/pkg/publisher"
+	"github.com/werf/trdl/server/pkg/tasks_manager"
+)
+
+const (
+	fieldNameAuditAfter = "after"
+	fieldNameAuditLimit = "limit"
+
+	storageKeyPrefixAudit = "audit/"
+
+	defaultAuditListLimit = 100
+)
+
+type AuditOperation string
+
+const (
+	AuditOperationRelease      AuditOperation = "release"
+	AuditOperationForceRelease AuditOperation = "force_release"
+	AuditOperationPublish      AuditOperation = "publish"
+	AuditOperationYank         AuditOperation = "yank"
+	AuditOperationRotateKeys   AuditOperation = "rotate_keys"
+	AuditOperationConfigure    AuditOperation = "configure"
+)
+
+// auditEntry is the record of the audit log, the log is append-only and is not pruned along with the tasks history.
+type auditEntry struct {
+	Time        time.Time         `json:"time"`
+	Operation   AuditOperation    `json:"operation"`
+	DisplayName string            `json:"display_name"`
+	EntityID    string            `json:"entity_id"`
+	GitTag      string            `json:"git_tag,omitempty"`
+	GitCommit   string            `json:"git_commit,omitempty"`
+	TaskUUID    string            `json:"task_uuid,omitempty"`
+	TufVersions map[string]int64  `json:"tuf_versions,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+}
+
+func newAuditEntry(req *logical.Request, operation AuditOperation) *auditEntry {
+	return &auditEntry{
+		Operation:   operation,
+		DisplayName: req.DisplayName,
+		EntityID:    req.EntityID,
+	}
+}
+
+func auditPath(b *Backend) *framework.Path {
+	return &framework.Path{
+		Pattern:         `audit/?$`,
+		HelpSynopsis:    "List the audit log",
+		HelpDescription: "List the audit log of the release, forced rebuild of the published release, publish, yank, key rotation and configuration operations in chronological order. The entries are listed by pages, the next page starts after the last listed entry",
+		Fields: map[string]*framework.FieldSchema{
+			fieldNameAuditAfter: {
+				Type:        framework.TypeString,
+				Description: "The entry to list the entries after",
+			},
+			fieldNameAuditLimit: {
+				Type:        framework.TypeInt,
+				Description: "The maximum number of the entries to list (100 is used by default)",
+				Default:     defaultAuditListLimit,
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ListOperation: &framework.PathOperation{
+				Description: "List the audit log",
+				Callback:    b.pathAuditList,
+			},
+		},
+	}
+}
+
+// auditConfigurePaths records the successful configuration changes to the audit log.
+// The field values are not recorded, since they might be secret.
+func auditConfigurePaths(paths []*framework.Path) []*framework.Path {
+	for _, p := range paths {
+		for operation, handler := range p.Operations {
+			pathOperation, ok := handler.(*framework.PathOperation)
+			if !ok || operation == logical.ReadOperation || operation == logical.ListOperation {
+				continue
+			}
+
+			callback := pathOperation.Callback
+			pathOperation.Callback = func(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+				resp, err := callback(ctx, req, fields)
+				if err != nil || resp.IsError() {
+					return resp, err
+				}
+
+				fieldNames := lo.Keys(req.Data)
+				sort.Strings(fieldNames)
+
+				audit := newAuditEntry(req, AuditOperationConfigure)
+				audit.Details = map[string]string{
+					"path":      req.Path,
+					"operation": string(req.Operation),
+					"fields":    strings.Join(fieldNames, ","),
+				}
+
+				if err := putAuditEntry(ctx, req.Storage, audit); err != nil {
+					return nil, fmt.Errorf("unable to record audit entry: %w", err)
+				}
+
+				return resp, nil
+			}
+		}
+	}
+
+	return paths
+}
+
+func (b *Backend) pathAuditList(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	limit := fields.Get(fieldNameAuditLimit).(int)
+	if limit <= 0 {
+		return logical.ErrorResponse("%s validation failed: expected positive number", fieldNameAuditLimit), nil
+	}
+
+	keys, err := req.Storage.List(ctx, storageKeyPrefixAudit)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list %q in storage: %w", storageKeyPrefixAudit, err)
+	}
+
+	sort.Strings(keys)
+
+	after := fields.Get(fieldNameAuditAfter).(string)
+	keys = keys[sort.Search(len(keys), func(i int) bool { return keys[i] > after }):]
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+
+	keyInfo := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		entry, err := getAuditEntry(ctx, req.Storage, key)
+		if err != nil {
+			return nil, err
+		}
+
+		if entry != nil {
+			keyInfo[key] = auditEntryData(entry)
+		}
+	}
+
+	return logical.ListResponseWithInfo(keys, keyInfo), nil
+}
+
+func auditEntryData(entry *auditEntry) map[string]interface{} {
+	return map[string]interface{}{
+		"time":         entry.Time.Format(time.RFC3339Nano),
+		"operation":    string(entry.Operation),
+		"display_name": entry.DisplayName,
+		"entity_id":    entry.EntityID,
+		"git_tag":      entry.GitTag,
+		"git_commit":   entry.GitCommit,
+		"task_uuid":    entry.TaskUUID,
+		"tuf_versions": entry.TufVersions,
+		"details":      entry.Details,
+	}
+}
+
+// putAuditEntry appends the entry to the audit log, the task UUID is taken from the context of the task.
+func putAuditEntry(ctx context.Context, storage logical.Storage, entry *auditEntry) error {
+	entry.Time = SystemClock.Now().UTC()
+	if entry.TaskUUID == "" {
+		entry.TaskUUID = tasks_manager.TaskUUIDFromContext(ctx)
+	}
+
+	// The keys are ordered chronologically, the suffix prevents the collisions.
+	key := path.Join(storageKeyPrefixAudit, fmt.Sprintf("%020d-%s", entry.Time.UnixNano(), uuid.NewV4().String()[:8]))
+
+	storageEntry, err := logical.StorageEntryJSON(key, entry)
+	if err != nil {
+		return fmt.Errorf("unable to encode %q: %w", key, err)
+	}
+
+	if err := storage.Put(ctx, storageEntry); err != nil {
+		return fmt.Errorf("unable to put %q into storage: %w", key, err)
+	}
+
+	return nil
+}
+
+func getAuditEntry(ctx context.Context, storage logical.Storage, id string) (*auditEntry, error) {
+	key := path.Join(storageKeyPrefixAudit, id)
+
+	storageEntry, err := storage.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get %q from storage: %w", key, err)
+	}
+
+	if storageEntry == nil {
+		return nil, nil
+	}
+
+	entry := new(auditEntry)
+	if err := storageEntry.DecodeJSON(entry); err != nil {
+		return nil, fmt.Errorf("unable to decode %q: %w", key, err)
+	}
+
+	return entry, nil
+}
+
+// putRepositoryAuditEntry appends the entry along with the TUF versions the operation resulted in to the audit log.
+func putRepositoryAuditEntry(ctx context.Context, storage logical.Storage, repository publisher.RepositoryInterface, entry *auditEntry) error {
+	versions, err := tufVersions(repository)
+	if err != nil {
+		return err
+	}
+	entry.TufVersions = versions
+
+	return putAuditEntry(ctx, storage, entry)
+}
+
+// tufVersions returns the versions of the TUF roles metadata.
+func tufVersions(repository publisher.RepositoryInterface) (map[string]int64, error) {
+	statuses, err := repository.RoleStatuses()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get TUF roles statuses: %w", err)
+	}
+
+	versions := make(map[string]int64, len(statuses))
+	for _, status := range statuses {
+		versions[status.Role] = status.Version
+	}
+
+	return versions, nil
+}
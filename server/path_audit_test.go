@@ -0,0 +1,91 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type PathAuditCallbacksSuite struct {
+	CommonSuite
+}
+
+func (suite *PathAuditCallbacksSuite) SetupTest() {
+	suite.CommonSuite.SetupTest()
+	suite.req.Path = "audit/"
+	suite.req.Operation = logical.ListOperation
+}
+
+func (suite *PathAuditCallbacksSuite) TestList_Pages() {
+	for i := 0; i < 3; i++ {
+		err := putAuditEntry(suite.ctx, suite.storage, &auditEntry{Operation: AuditOperationPublish, GitCommit: fmt.Sprint(i)})
+		assert.Nil(suite.T(), err)
+	}
+
+	suite.req.Data = map[string]interface{}{fieldNameAuditLimit: 2}
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	if !assert.NotNil(suite.T(), resp) {
+		return
+	}
+
+	keys := resp.Data["keys"].([]string)
+	if !assert.Len(suite.T(), keys, 2) {
+		return
+	}
+
+	keyInfo := resp.Data["key_info"].(map[string]interface{})
+	assert.Equal(suite.T(), "0", keyInfo[keys[0]].(map[string]interface{})["git_commit"])
+	assert.Equal(suite.T(), "1", keyInfo[keys[1]].(map[string]interface{})["git_commit"])
+
+	suite.req.Data = map[string]interface{}{fieldNameAuditLimit: 2, fieldNameAuditAfter: keys[1]}
+
+	resp, err = suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	if assert.NotNil(suite.T(), resp) && assert.Len(suite.T(), resp.Data["keys"], 1) {
+		key := resp.Data["keys"].([]string)[0]
+		assert.Equal(suite.T(), "2", resp.Data["key_info"].(map[string]interface{})[key].(map[string]interface{})["git_commit"])
+	}
+}
+
+func (suite *PathAuditCallbacksSuite) TestConfigureRecorded() {
+	suite.req.Path = "configure"
+	suite.req.Operation = logical.CreateOperation
+	suite.req.Data = dataCompleteConfiguration()
+	suite.req.DisplayName = "token-admin"
+	suite.req.EntityID = "admin"
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), resp)
+
+	// The rejected configuration is not recorded.
+	suite.req.Data = map[string]interface{}{fieldNameGitRepoUrl: "https://example.com/repo.git"}
+
+	resp, err = suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), resp.IsError())
+
+	ids, err := suite.storage.List(suite.ctx, storageKeyPrefixAudit)
+	assert.Nil(suite.T(), err)
+	if assert.Len(suite.T(), ids, 1) {
+		entry, err := getAuditEntry(suite.ctx, suite.storage, ids[0])
+		assert.Nil(suite.T(), err)
+		if assert.NotNil(suite.T(), entry) {
+			assert.Equal(suite.T(), AuditOperationConfigure, entry.Operation)
+			assert.Equal(suite.T(), "token-admin", entry.DisplayName)
+			assert.Equal(suite.T(), "admin", entry.EntityID)
+			assert.Equal(suite.T(), "configure", entry.Details["path"])
+			assert.Contains(suite.T(), entry.Details["fields"], fieldNameS3SecretAccessKey)
+			assert.NotContains(suite.T(), entry.Details["fields"], completeConfiguration().S3SecretAccessKey)
+		}
+	}
+}
+
+func TestBackendPathAuditCallbacks(t *testing.T) {
+	suite.Run(t, new(PathAuditCallbacksSuite))
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+
+	"github.com/werf/trdl/server/pkg/publisher"
+)
+
+func channelsPath(b *Backend) *framework.Path {
+	return &framework.Path{
+		Pattern:         `channels$`,
+		HelpSynopsis:    "Get the published channels",
+		HelpDescription: "Get the release channels groups reconstructed from the TUF repository: the release version each channel points to and the trdl channels branch commit it has been published from or the release tag it has been promoted by",
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Description: "Get the published channels",
+				Callback:    b.pathChannelsRead,
+			},
+		},
+	}
+}
+
+func (b *Backend) pathChannelsRead(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	publisherRepository, errResp, err := b.getConfiguredRepository(ctx, req)
+	if errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	channels, err := publisherRepository.GetChannels(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get channels: %w", err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"groups": channelsGroupsData(channels),
+		},
+	}, nil
+}
+
+// channelsGroupsData follows the trdl_channels.yaml structure, the channels are expected to be sorted by group.
+func channelsGroupsData(channels []publisher.PublishedChannel) []map[string]interface{} {
+	var groupsData []map[string]interface{}
+	var groupChannelsData []map[string]interface{}
+	for i, channel := range channels {
+		groupChannelsData = append(groupChannelsData, map[string]interface{}{
+			"name":              channel.Name,
+			"version":           channel.Version,
+			"git_commit":        channel.GitCommit,
+			"promoted_from_tag": channel.PromotedFromTag,
+		})
+
+		if i == len(channels)-1 || channels[i+1].Group != channel.Group {
+			groupsData = append(groupsData, map[string]interface{}{
+				"name":     channel.Group,
+				"channels": groupChannelsData,
+			})
+			groupChannelsData = nil
+		}
+	}
+
+	return groupsData
+}
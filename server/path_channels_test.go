@@ -0,0 +1,53 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/werf/trdl/server/pkg/publisher"
+)
+
+type PathChannelsCallbacksSuite struct {
+	CommonSuite
+}
+
+func (suite *PathChannelsCallbacksSuite) TestRead_ConfigurationNotFound() {
+	suite.req.Path = "channels"
+	suite.req.Operation = logical.ReadOperation
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), errorResponseConfigurationNotFound, resp)
+}
+
+func TestBackendPathChannelsCallbacks(t *testing.T) {
+	suite.Run(t, new(PathChannelsCallbacksSuite))
+}
+
+func TestChannelsGroupsData(t *testing.T) {
+	groupsData := channelsGroupsData([]publisher.PublishedChannel{
+		{Group: "1", Name: "alpha", Version: "1.1.0", GitCommit: "commit-2"},
+		{Group: "1", Name: "stable", Version: "1.0.0", GitCommit: "commit-1"},
+		{Group: "2", Name: "stable", Version: "2.0.0", PromotedFromTag: "v2.0.0"},
+	})
+
+	assert.Equal(t, []map[string]interface{}{
+		{
+			"name": "1",
+			"channels": []map[string]interface{}{
+				{"name": "alpha", "version": "1.1.0", "git_commit": "commit-2", "promoted_from_tag": ""},
+				{"name": "stable", "version": "1.0.0", "git_commit": "commit-1", "promoted_from_tag": ""},
+			},
+		},
+		{
+			"name": "2",
+			"channels": []map[string]interface{}{
+				{"name": "stable", "version": "2.0.0", "git_commit": "", "promoted_from_tag": "v2.0.0"},
+			},
+		},
+	}, groupsData)
+	assert.Nil(t, channelsGroupsData(nil))
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/fatih/structs"
 	"github.com/hashicorp/vault/sdk/framework"
@@ -18,6 +20,7 @@ import (
 	"github.com/werf/trdl/server/pkg/pgp"
 	"github.com/werf/trdl/server/pkg/publisher"
 	"github.com/werf/trdl/server/pkg/secrets"
+	"github.com/werf/trdl/server/pkg/transit"
 	"github.com/werf/trdl/server/pkg/util"
 )
 
@@ -28,32 +31,61 @@ const (
 	fieldNameGitTrdlChannelsBranch                      = "git_trdl_channels_branch"
 	fieldNameInitialLastPublishedGitCommit              = "initial_last_published_git_commit"
 	fieldNameRequiredNumberOfVerifiedSignaturesOnCommit = "required_number_of_verified_signatures_on_commit"
+	fieldNameStorageBackend                             = "storage_backend"
 	fieldNameS3Endpoint                                 = "s3_endpoint"
 	fieldNameS3Region                                   = "s3_region"
 	fieldNameS3AccessKeyID                              = "s3_access_key_id"
 	fieldNameS3SecretAccessKey                          = "s3_secret_access_key"
 	fieldNameS3BucketName                               = "s3_bucket_name"
+	fieldNameLocalDirectory                             = "local_directory"
+	fieldNameConsistentSnapshot                         = "consistent_snapshot"
+	fieldNameTufDelegatedRoles                          = "tuf_delegated_roles"
 	fieldNameBuildkitdAddress                           = "buildkitd_address"
 	fieldNameBuildxDriver                               = "buildx_driver"
 	fieldNameBuildxDriverOpts                           = "buildx_driver_opts"
+	fieldNameTufRootExpires                             = "tuf_root_expires"
+	fieldNameTufRootRefreshLead                         = "tuf_root_refresh_lead"
+	fieldNameTufTargetsExpires                          = "tuf_targets_expires"
+	fieldNameTufTargetsRefreshLead                      = "tuf_targets_refresh_lead"
+	fieldNameTufSnapshotExpires                         = "tuf_snapshot_expires"
+	fieldNameTufSnapshotRefreshLead                     = "tuf_snapshot_refresh_lead"
+	fieldNameTufTimestampExpires                        = "tuf_timestamp_expires"
+	fieldNameTufTimestampRefreshLead                    = "tuf_timestamp_refresh_lead"
+	fieldNameAutoPromotableChannels                     = "auto_promotable_channels"
+	fieldNamePromotionRules                             = "promotion_rules"
+	fieldNameReleaseApprovalsRequired                   = "release_approvals_required"
+	fieldNameReleaseApprovalTTL                         = "release_approval_ttl"
 
 	storageKeyConfiguration = "configuration"
 )
 
-var errorResponseConfigurationNotFound = logical.ErrorResponse("Configuration not found")
+var (
+	errorResponseConfigurationNotFound = logical.ErrorResponse("Configuration not found")
+
+	s3StorageBackendFieldNames = []string{
+		fieldNameS3BucketName,
+		fieldNameS3Endpoint,
+		fieldNameS3Region,
+		fieldNameS3AccessKeyID,
+		fieldNameS3SecretAccessKey,
+	}
+)
 
 func configurePaths(b *Backend) []*framework.Path {
-	return framework.PathAppend(
+	return auditConfigurePaths(framework.PathAppend(
 		[]*framework.Path{
 			configurePath(b),
 			configureLastPublishedGitCommitPath(b),
 		},
+		configureTufRootPaths(b),
+		[]*framework.Path{configureTufKeysPath(b)},
 		git.CredentialsPaths(),
 		pgp.Paths(),
 		secrets.Paths(),
 		mac_signing.Paths(),
 		elf_signing.Paths(),
-	)
+		transit.Paths(),
+	))
 }
 
 func configurePath(b *Backend) *framework.Path {
@@ -91,30 +123,53 @@ func configurePath(b *Backend) *framework.Path {
 				Description: "The required number of verified signatures for a commit",
 				Required:    true,
 			},
+			fieldNameStorageBackend: {
+				Type:        framework.TypeString,
+				Description: "The storage backend to publish the TUF repository into: s3 (used by default) or local. The files of the commit in progress are uploaded under the .staging/<version> prefix of the repository, which must not be mirrored, and are deleted once the commit is finished or failed",
+				Default:     string(publisher.StorageBackendS3),
+				Required:    false,
+			},
 			fieldNameS3BucketName: {
 				Type:        framework.TypeString,
-				Description: "The S3 storage bucket name",
-				Required:    true,
+				Description: "The S3 storage bucket name (required for the s3 storage backend)",
+				Required:    false,
 			},
 			fieldNameS3Endpoint: {
 				Type:        framework.TypeString,
-				Description: "The S3 storage endpoint",
-				Required:    true,
+				Description: "The S3 storage endpoint (required for the s3 storage backend)",
+				Required:    false,
 			},
 			fieldNameS3Region: {
 				Type:        framework.TypeString,
-				Description: "The S3 storage region",
-				Required:    true,
+				Description: "The S3 storage region (required for the s3 storage backend)",
+				Required:    false,
 			},
 			fieldNameS3AccessKeyID: {
 				Type:        framework.TypeString,
-				Description: "The S3 storage access key id",
-				Required:    true,
+				Description: "The S3 storage access key id (required for the s3 storage backend)",
+				Required:    false,
 			},
 			fieldNameS3SecretAccessKey: {
 				Type:        framework.TypeString,
-				Description: "The S3 storage secret access key",
-				Required:    true,
+				Description: "The S3 storage secret access key (required for the s3 storage backend)",
+				Required:    false,
+			},
+			fieldNameLocalDirectory: {
+				Type:        framework.TypeString,
+				Description: "An absolute path to the directory to publish the TUF repository into (required for the local storage backend). The directory is expected to be served to the clients as is, e.g. by nginx",
+				Required:    false,
+			},
+			fieldNameConsistentSnapshot: {
+				Type:        framework.TypeBool,
+				Description: "Publish hash-prefixed targets and version-prefixed metadata, so that published files are never overwritten and can be cached forever, and the clients see either the previous repository state or the new one. Without it the metadata is overwritten in place and the clients updating during a commit may fail and have to retry. Takes effect only when the TUF repository is initialized",
+				Default:     true,
+				Required:    false,
+			},
+			fieldNameTufDelegatedRoles: {
+				Type:        framework.TypeBool,
+				Description: "Sign the releases and the channels of each group with the delegated TUF targets roles, each with its own key. The published releases and channels are moved out of the top-level targets metadata and the trdl clients not following the delegations stop finding them, so enable it only once all the clients are upgraded. The delegations are kept once committed",
+				Default:     false,
+				Required:    false,
 			},
 			fieldNameBuildkitdAddress: {
 				Type:        framework.TypeString,
@@ -131,6 +186,67 @@ func configurePath(b *Backend) *framework.Path {
 				Description: "The buildx driver options, one --driver-opt per element (e.g. namespace=trdl-build), passed through as is. Take precedence over the TRDL_BUILDX_DRIVER_OPTS_* environment variables, and cannot be combined with buildkitd_address",
 				Required:    false,
 			},
+			fieldNameTufRootExpires: {
+				Type:        framework.TypeDurationSecond,
+				Description: "The period the TUF root metadata is signed for (1 year is used by default)",
+				Required:    false,
+			},
+			fieldNameTufRootRefreshLead: {
+				Type:        framework.TypeDurationSecond,
+				Description: "How long before the expiration the TUF root metadata is re-signed (9 months is used by default)",
+				Required:    false,
+			},
+			fieldNameTufTargetsExpires: {
+				Type:        framework.TypeDurationSecond,
+				Description: "The period the TUF targets metadata is signed for (3 months is used by default)",
+				Required:    false,
+			},
+			fieldNameTufTargetsRefreshLead: {
+				Type:        framework.TypeDurationSecond,
+				Description: "How long before the expiration the TUF targets metadata is re-signed (69 days is used by default)",
+				Required:    false,
+			},
+			fieldNameTufSnapshotExpires: {
+				Type:        framework.TypeDurationSecond,
+				Description: "The period the TUF snapshot metadata is signed for (7 days is used by default)",
+				Required:    false,
+			},
+			fieldNameTufSnapshotRefreshLead: {
+				Type:        framework.TypeDurationSecond,
+				Description: "How long before the expiration the TUF snapshot metadata is re-signed (5 days is used by default)",
+				Required:    false,
+			},
+			fieldNameTufTimestampExpires: {
+				Type:        framework.TypeDurationSecond,
+				Description: "The period the TUF timestamp metadata is signed for (1 day is used by default)",
+				Required:    false,
+			},
+			fieldNameTufTimestampRefreshLead: {
+				Type:        framework.TypeDurationSecond,
+				Description: "How long before the expiration the TUF timestamp metadata is re-signed (20 hours is used by default)",
+				Required:    false,
+			},
+			fieldNameAutoPromotableChannels: {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "The channels the promotion rules may move, as <group>/<channel> (e.g. 1/alpha). Such channels should be left out of the trdl channels configuration file: the promoted channel is moved back to the version in the file on the next publication",
+				Required:    false,
+			},
+			fieldNamePromotionRules: {
+				Type:        framework.TypeStringSlice,
+				Description: "The rules to point auto-promotable channels at a successfully released version, one <prerelease pattern>=<group>/<channel> per element (e.g. alpha.*=1/alpha). The pattern is matched against the prerelease part of the version without the leading dash, the empty one matches final versions only. A channel is promoted only if the version belongs to the group and is greater than the published one",
+				Required:    false,
+			},
+			fieldNameReleaseApprovalsRequired: {
+				Type:        framework.TypeInt,
+				Description: "The number of approvals from distinct Vault entities, other than the initiator, required to start the release build. The release requests are pending approval via release/<git tag>/approve if set, the build is started immediately otherwise",
+				Default:     0,
+				Required:    false,
+			},
+			fieldNameReleaseApprovalTTL: {
+				Type:        framework.TypeDurationSecond,
+				Description: "The period the release request is pending approval for (24 hours is used by default)",
+				Required:    false,
+			},
 		},
 		Operations: map[logical.Operation]framework.OperationHandler{
 			logical.CreateOperation: &framework.PathOperation{
@@ -183,6 +299,22 @@ func (b *Backend) pathConfigureCreateOrUpdate(ctx context.Context, req *logical.
 		}
 	}
 
+	if errResp := validateStorageBackendFields(req, fields); errResp != nil {
+		return errResp, nil
+	}
+
+	if fields.Get(fieldNameReleaseApprovalsRequired).(int) < 0 {
+		return logical.ErrorResponse("%s validation failed: expected non-negative number", fieldNameReleaseApprovalsRequired), nil
+	}
+
+	if fields.Get(fieldNameReleaseApprovalTTL).(int) < 0 {
+		return logical.ErrorResponse("%s validation failed: expected non-negative duration", fieldNameReleaseApprovalTTL), nil
+	}
+
+	if _, err := parsePromotionRules(fields.Get(fieldNamePromotionRules).([]string), fields.Get(fieldNameAutoPromotableChannels).([]string)); err != nil {
+		return logical.ErrorResponse("%s validation failed: %s", fieldNamePromotionRules, err), nil
+	}
+
 	cfg := &configuration{
 		GitRepoUrl:                    fields.Get(fieldNameGitRepoUrl).(string),
 		GitTrdlPath:                   fields.Get(fieldNameGitTrdlPath).(string),
@@ -190,14 +322,34 @@ func (b *Backend) pathConfigureCreateOrUpdate(ctx context.Context, req *logical.
 		GitTrdlChannelsBranch:         fields.Get(fieldNameGitTrdlChannelsBranch).(string),
 		InitialLastPublishedGitCommit: fields.Get(fieldNameInitialLastPublishedGitCommit).(string),
 		RequiredNumberOfVerifiedSignaturesOnCommit: fields.Get(fieldNameRequiredNumberOfVerifiedSignaturesOnCommit).(int),
-		S3Endpoint:        fields.Get(fieldNameS3Endpoint).(string),
-		S3Region:          fields.Get(fieldNameS3Region).(string),
-		S3AccessKeyID:     fields.Get(fieldNameS3AccessKeyID).(string),
-		S3SecretAccessKey: fields.Get(fieldNameS3SecretAccessKey).(string),
-		S3BucketName:      fields.Get(fieldNameS3BucketName).(string),
-		BuildkitdAddress:  fields.Get(fieldNameBuildkitdAddress).(string),
-		BuildxDriver:      fields.Get(fieldNameBuildxDriver).(string),
-		BuildxDriverOpts:  fields.Get(fieldNameBuildxDriverOpts).([]string),
+		StorageBackend:           fields.Get(fieldNameStorageBackend).(string),
+		S3Endpoint:               fields.Get(fieldNameS3Endpoint).(string),
+		S3Region:                 fields.Get(fieldNameS3Region).(string),
+		S3AccessKeyID:            fields.Get(fieldNameS3AccessKeyID).(string),
+		S3SecretAccessKey:        fields.Get(fieldNameS3SecretAccessKey).(string),
+		S3BucketName:             fields.Get(fieldNameS3BucketName).(string),
+		LocalDirectory:           fields.Get(fieldNameLocalDirectory).(string),
+		ConsistentSnapshot:       fields.Get(fieldNameConsistentSnapshot).(bool),
+		TufDelegatedRoles:        fields.Get(fieldNameTufDelegatedRoles).(bool),
+		BuildkitdAddress:         fields.Get(fieldNameBuildkitdAddress).(string),
+		BuildxDriver:             fields.Get(fieldNameBuildxDriver).(string),
+		BuildxDriverOpts:         fields.Get(fieldNameBuildxDriverOpts).([]string),
+		TufRootExpires:           fields.Get(fieldNameTufRootExpires).(int),
+		TufRootRefreshLead:       fields.Get(fieldNameTufRootRefreshLead).(int),
+		TufTargetsExpires:        fields.Get(fieldNameTufTargetsExpires).(int),
+		TufTargetsRefreshLead:    fields.Get(fieldNameTufTargetsRefreshLead).(int),
+		TufSnapshotExpires:       fields.Get(fieldNameTufSnapshotExpires).(int),
+		TufSnapshotRefreshLead:   fields.Get(fieldNameTufSnapshotRefreshLead).(int),
+		TufTimestampExpires:      fields.Get(fieldNameTufTimestampExpires).(int),
+		TufTimestampRefreshLead:  fields.Get(fieldNameTufTimestampRefreshLead).(int),
+		AutoPromotableChannels:   fields.Get(fieldNameAutoPromotableChannels).([]string),
+		PromotionRules:           fields.Get(fieldNamePromotionRules).([]string),
+		ReleaseApprovalsRequired: fields.Get(fieldNameReleaseApprovalsRequired).(int),
+		ReleaseApprovalTTL:       fields.Get(fieldNameReleaseApprovalTTL).(int),
+	}
+
+	if err := cfg.RepositoryOptions().Expirations.Validate(); err != nil {
+		return logical.ErrorResponse("TUF expirations validation failed: %s", err), nil
 	}
 
 	if err := putConfiguration(ctx, req.Storage, cfg); err != nil {
@@ -207,6 +359,41 @@ func (b *Backend) pathConfigureCreateOrUpdate(ctx context.Context, req *logical.
 	return nil, nil
 }
 
+// validateStorageBackendFields requires the settings of the selected storage backend
+// and rejects the settings of the other one, which would silently do nothing.
+func validateStorageBackendFields(req *logical.Request, fields *framework.FieldData) *logical.Response {
+	var requiredFields, unusedFields []string
+
+	switch storageBackend := publisher.StorageBackend(fields.Get(fieldNameStorageBackend).(string)); storageBackend {
+	case publisher.StorageBackendS3:
+		requiredFields = s3StorageBackendFieldNames
+		unusedFields = []string{fieldNameLocalDirectory}
+	case publisher.StorageBackendLocal:
+		requiredFields = []string{fieldNameLocalDirectory}
+		unusedFields = s3StorageBackendFieldNames
+
+		if localDirectory := fields.Get(fieldNameLocalDirectory).(string); localDirectory != "" && !filepath.IsAbs(localDirectory) {
+			return logical.ErrorResponse("%s validation failed: expected absolute path, got %q", fieldNameLocalDirectory, localDirectory)
+		}
+	default:
+		return logical.ErrorResponse("%s validation failed: expected %q or %q, got %q", fieldNameStorageBackend, publisher.StorageBackendS3, publisher.StorageBackendLocal, storageBackend)
+	}
+
+	for _, fieldName := range requiredFields {
+		if req.Get(fieldName) == nil {
+			return logical.ErrorResponse("Required field %q must be set", fieldName)
+		}
+	}
+
+	for _, fieldName := range unusedFields {
+		if fields.Get(fieldName).(string) != "" {
+			return logical.ErrorResponse("%s cannot be used with the %s storage backend", fieldName, fields.Get(fieldNameStorageBackend).(string))
+		}
+	}
+
+	return nil
+}
+
 func (b *Backend) pathConfigureRead(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
 	cfg, err := getConfiguration(ctx, req.Storage)
 	if err != nil {
@@ -235,23 +422,71 @@ type configuration struct {
 	GitTrdlChannelsBranch                      string   `structs:"git_trdl_channels_branch" json:"git_trdl_channels_branch"`
 	InitialLastPublishedGitCommit              string   `structs:"initial_last_published_git_commit" json:"initial_last_published_git_commit"`
 	RequiredNumberOfVerifiedSignaturesOnCommit int      `structs:"required_number_of_verified_signatures_on_commit" json:"required_number_of_verified_signatures_on_commit"`
+	StorageBackend                             string   `structs:"storage_backend" json:"storage_backend"`
 	S3Endpoint                                 string   `structs:"s3_endpoint" json:"s3_endpoint"`
 	S3Region                                   string   `structs:"s3_region" json:"s3_region"`
 	S3AccessKeyID                              string   `structs:"s3_access_key_id" json:"s3_access_key_id"`
 	S3SecretAccessKey                          string   `structs:"s3_secret_access_key" json:"s3_secret_access_key"`
 	S3BucketName                               string   `structs:"s3_bucket_name" json:"s3_bucket_name"`
+	LocalDirectory                             string   `structs:"local_directory" json:"local_directory"`
+	ConsistentSnapshot                         bool     `structs:"consistent_snapshot" json:"consistent_snapshot"`
+	TufDelegatedRoles                          bool     `structs:"tuf_delegated_roles" json:"tuf_delegated_roles"`
 	BuildkitdAddress                           string   `structs:"buildkitd_address" json:"buildkitd_address"`
 	BuildxDriver                               string   `structs:"buildx_driver" json:"buildx_driver"`
 	BuildxDriverOpts                           []string `structs:"buildx_driver_opts" json:"buildx_driver_opts"`
+	TufRootExpires                             int      `structs:"tuf_root_expires" json:"tuf_root_expires"`
+	TufRootRefreshLead                         int      `structs:"tuf_root_refresh_lead" json:"tuf_root_refresh_lead"`
+	TufTargetsExpires                          int      `structs:"tuf_targets_expires" json:"tuf_targets_expires"`
+	TufTargetsRefreshLead                      int      `structs:"tuf_targets_refresh_lead" json:"tuf_targets_refresh_lead"`
+	TufSnapshotExpires                         int      `structs:"tuf_snapshot_expires" json:"tuf_snapshot_expires"`
+	TufSnapshotRefreshLead                     int      `structs:"tuf_snapshot_refresh_lead" json:"tuf_snapshot_refresh_lead"`
+	TufTimestampExpires                        int      `structs:"tuf_timestamp_expires" json:"tuf_timestamp_expires"`
+	TufTimestampRefreshLead                    int      `structs:"tuf_timestamp_refresh_lead" json:"tuf_timestamp_refresh_lead"`
+	AutoPromotableChannels                     []string `structs:"auto_promotable_channels" json:"auto_promotable_channels"`
+	PromotionRules                             []string `structs:"promotion_rules" json:"promotion_rules"`
+	ReleaseApprovalsRequired                   int      `structs:"release_approvals_required" json:"release_approvals_required"`
+	ReleaseApprovalTTL                         int      `structs:"release_approval_ttl" json:"release_approval_ttl"`
 }
 
 func (cfg *configuration) RepositoryOptions() publisher.RepositoryOptions {
+	// A configuration stored before the storage backend setting existed is an S3 one.
+	storageBackend := publisher.StorageBackend(cfg.StorageBackend)
+	if storageBackend == "" {
+		storageBackend = publisher.StorageBackendS3
+	}
+
 	return publisher.RepositoryOptions{
+		StorageBackend:    storageBackend,
 		S3Endpoint:        cfg.S3Endpoint,
 		S3Region:          cfg.S3Region,
 		S3AccessKeyID:     cfg.S3AccessKeyID,
 		S3SecretAccessKey: cfg.S3SecretAccessKey,
 		S3BucketName:      cfg.S3BucketName,
+		LocalDirectory:    cfg.LocalDirectory,
+
+		ConsistentSnapshot: cfg.ConsistentSnapshot,
+		DelegatedRoles:     cfg.TufDelegatedRoles,
+		Expirations: publisher.TufExpirations{
+			Root:      metadataExpiration(cfg.TufRootExpires, cfg.TufRootRefreshLead),
+			Targets:   metadataExpiration(cfg.TufTargetsExpires, cfg.TufTargetsRefreshLead),
+			Snapshot:  metadataExpiration(cfg.TufSnapshotExpires, cfg.TufSnapshotRefreshLead),
+			Timestamp: metadataExpiration(cfg.TufTimestampExpires, cfg.TufTimestampRefreshLead),
+		},
+	}
+}
+
+func (cfg *configuration) releaseApprovalTTL() time.Duration {
+	if cfg.ReleaseApprovalTTL == 0 {
+		return defaultReleaseApprovalTTL
+	}
+
+	return time.Duration(cfg.ReleaseApprovalTTL) * time.Second
+}
+
+func metadataExpiration(expiresSeconds, refreshLeadSeconds int) publisher.MetadataExpiration {
+	return publisher.MetadataExpiration{
+		Expires:     time.Duration(expiresSeconds) * time.Second,
+		RefreshLead: time.Duration(refreshLeadSeconds) * time.Second,
 	}
 }
 
@@ -272,6 +507,25 @@ func getConfiguration(ctx context.Context, storage logical.Storage) (*configurat
 	return config, nil
 }
 
+// getConfiguredRepository returns the repository with the initialized keys.
+func (b *Backend) getConfiguredRepository(ctx context.Context, req *logical.Request) (publisher.RepositoryInterface, *logical.Response, error) {
+	cfg, err := getConfiguration(ctx, req.Storage)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to get configuration from storage: %w", err)
+	}
+
+	if cfg == nil {
+		return nil, errorResponseConfigurationNotFound, nil
+	}
+
+	publisherRepository, err := b.Publisher.GetRepository(ctx, req.Storage, cfg.RepositoryOptions())
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting publisher repository: %w", err)
+	}
+
+	return publisherRepository, nil, nil
+}
+
 func putConfiguration(ctx context.Context, storage logical.Storage, config *configuration) error {
 	entry, err := logical.StorageEntryJSON(storageKeyConfiguration, config)
 	if err != nil {
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+
+	"github.com/werf/logboek"
+	"github.com/werf/trdl/server/pkg/publisher"
+	"github.com/werf/trdl/server/pkg/tasks_manager"
+)
+
+const (
+	fieldNameTufKeysRole         = "role"
+	fieldNameTufKeysAddKeys      = "add_keys"
+	fieldNameTufKeysRemoveKeyIDs = "remove_key_ids"
+	fieldNameTufKeysThreshold    = "threshold"
+
+	taskOperationUpdateTufKeys = "update_tuf_keys"
+)
+
+func configureTufKeysPath(b *Backend) *framework.Path {
+	return &framework.Path{
+		Pattern:         "configure/tuf_keys/(?P<role>root|targets|snapshot|timestamp)$",
+		HelpSynopsis:    "Manage the keys of the TUF role",
+		HelpDescription: "Add and revoke the keys of the top-level TUF role and change the number of the keys required to sign the role metadata. The changes are published with the new root version signed both by the current and the new root keys in a task. The resulting keys and threshold are in the task result",
+		Fields: map[string]*framework.FieldSchema{
+			fieldNameTufKeysRole: {
+				Type:        framework.TypeString,
+				Description: "The TUF role: root, targets, snapshot or timestamp",
+				Required:    true,
+			},
+			fieldNameTufKeysAddKeys: {
+				Type:        framework.TypeInt,
+				Description: "The number of the new keys to generate and store in the plugin",
+				Default:     0,
+			},
+			fieldNameTufKeysRemoveKeyIDs: {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "The IDs of the keys to revoke",
+			},
+			fieldNameTufKeysThreshold: {
+				Type:        framework.TypeInt,
+				Description: "The number of the keys required to sign the role metadata. The current threshold is kept if not set",
+				Default:     0,
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Description: "Get the keys and the threshold of the TUF role",
+				Callback:    b.pathConfigureTufKeysRead,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Description: "Add and revoke the keys of the TUF role and change the threshold",
+				Callback:    b.pathConfigureTufKeysUpdate,
+			},
+		},
+	}
+}
+
+func (b *Backend) pathConfigureTufKeysRead(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	role := fields.Get(fieldNameTufKeysRole).(string)
+
+	publisherRepository, errResp, err := b.getConfiguredRepository(ctx, req)
+	if errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	return tufKeysResponse(publisherRepository, role)
+}
+
+func (b *Backend) pathConfigureTufKeysUpdate(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	role := fields.Get(fieldNameTufKeysRole).(string)
+	opts := publisher.UpdateRoleKeysOptions{
+		AddKeys:      fields.Get(fieldNameTufKeysAddKeys).(int),
+		RemoveKeyIDs: fields.Get(fieldNameTufKeysRemoveKeyIDs).([]string),
+		Threshold:    fields.Get(fieldNameTufKeysThreshold).(int),
+	}
+
+	if opts.AddKeys < 0 {
+		return logical.ErrorResponse("Field %q must not be negative", fieldNameTufKeysAddKeys), nil
+	}
+
+	if opts.Threshold < 0 {
+		return logical.ErrorResponse("Field %q must not be negative", fieldNameTufKeysThreshold), nil
+	}
+
+	cfg, err := getConfiguration(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get configuration from storage: %w", err)
+	}
+
+	if cfg == nil {
+		return errorResponseConfigurationNotFound, nil
+	}
+
+	// The update commits the new root, so it holds the TUF repository commit for the whole task.
+	taskUUID, err := b.TasksManager.RunTask(ctx, req.Storage, b.updateTufKeysTask(cfg, role, opts), tasks_manager.TaskOptions{
+		Operation:         taskOperationUpdateTufKeys,
+		Params:            map[string]string{"role": role},
+		Initiator:         req.DisplayName,
+		InitiatorEntityID: req.EntityID,
+		Resources:         []string{taskResourceTufCommit},
+	})
+	if err != nil {
+		if errors.Is(err, tasks_manager.ErrBusy) {
+			return logical.ErrorResponse("busy"), nil
+		}
+
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"task_uuid": taskUUID,
+		},
+	}, nil
+}
+
+func (b *Backend) updateTufKeysTask(cfg *configuration, role string, opts publisher.UpdateRoleKeysOptions) func(context.Context, logical.Storage) error {
+	return func(ctx context.Context, storage logical.Storage) error {
+		logboek.Context(ctx).Default().LogF("Updating the keys of the TUF role %q\n", role)
+		b.Logger().Debug(fmt.Sprintf("Updating the keys of the TUF role %q", role))
+
+		publisherRepository, err := b.Publisher.GetRepository(ctx, storage, cfg.RepositoryOptions())
+		if err != nil {
+			return fmt.Errorf("error getting publisher repository: %w", err)
+		}
+
+		if err := b.Publisher.UpdateRoleKeys(ctx, storage, publisherRepository, role, opts); err != nil {
+			return fmt.Errorf("unable to update %s keys: %w", role, err)
+		}
+
+		keysData, err := tufKeysData(publisherRepository, role)
+		if err != nil {
+			return err
+		}
+
+		if err := tasks_manager.PutTaskResult(ctx, storage, keysData); err != nil {
+			return fmt.Errorf("unable to put task result: %w", err)
+		}
+
+		logboek.Context(ctx).Default().LogF("Task finished\n")
+		b.Logger().Debug("Task finished")
+
+		return nil
+	}
+}
+
+func tufKeysResponse(publisherRepository publisher.RepositoryInterface, role string) (*logical.Response, error) {
+	keysData, err := tufKeysData(publisherRepository, role)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{Data: keysData}, nil
+}
+
+func tufKeysData(publisherRepository publisher.RepositoryInterface, role string) (map[string]interface{}, error) {
+	roleKeys, threshold, err := publisherRepository.RoleKeys(role)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get %s keys: %w", role, err)
+	}
+
+	var keysData []map[string]interface{}
+	for _, roleKey := range roleKeys {
+		keysData = append(keysData, map[string]interface{}{
+			"key_id":     roleKey.KeyID,
+			"public_key": roleKey.PublicKey,
+			"location":   string(roleKey.Location),
+		})
+	}
+
+	return map[string]interface{}{
+		"keys":      keysData,
+		"threshold": threshold,
+	}, nil
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/werf/trdl/server/pkg/publisher"
+	"github.com/werf/trdl/server/pkg/tasks_manager"
+)
+
+type PathConfigureTufKeysCallbacksSuite struct {
+	CommonSuite
+}
+
+func (suite *PathConfigureTufKeysCallbacksSuite) TestUpdate_NegativeAddKeys() {
+	suite.req.Path = "configure/tuf_keys/targets"
+	suite.req.Operation = logical.UpdateOperation
+	suite.req.Data = map[string]interface{}{
+		fieldNameTufKeysAddKeys: -1,
+	}
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), logical.ErrorResponse("Field %q must not be negative", fieldNameTufKeysAddKeys), resp)
+}
+
+func (suite *PathConfigureTufKeysCallbacksSuite) TestUpdate_ConfigurationNotFound() {
+	suite.req.Path = "configure/tuf_keys/targets"
+	suite.req.Operation = logical.UpdateOperation
+	suite.req.Data = map[string]interface{}{
+		fieldNameTufKeysAddKeys: 1,
+	}
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), errorResponseConfigurationNotFound, resp)
+}
+
+func (suite *PathConfigureTufKeysCallbacksSuite) TestUpdate_Basic() {
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+
+	suite.req.Path = "configure/tuf_keys/targets"
+	suite.req.Operation = logical.UpdateOperation
+	suite.req.Data = map[string]interface{}{
+		fieldNameTufKeysAddKeys: 1,
+	}
+
+	suite.mockedTasksManager.On("RunTask").Return("UUID", nil)
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	if assert.NotNil(suite.T(), resp) {
+		assert.Equal(suite.T(), map[string]interface{}{"task_uuid": "UUID"}, resp.Data)
+	}
+
+	suite.mockedTasksManager.AssertExpectations(suite.T())
+}
+
+func (suite *PathConfigureTufKeysCallbacksSuite) TestUpdate_Busy() {
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+
+	suite.req.Path = "configure/tuf_keys/targets"
+	suite.req.Operation = logical.UpdateOperation
+	suite.req.Data = map[string]interface{}{
+		fieldNameTufKeysAddKeys: 1,
+	}
+
+	suite.mockedTasksManager.IsBusy = true
+	suite.mockedTasksManager.On("RunTask").Return("", tasks_manager.ErrBusy)
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), logical.ErrorResponse("busy"), resp)
+}
+
+func (suite *PathConfigureTufKeysCallbacksSuite) TestUpdateTufKeysTask() {
+	opts := publisher.UpdateRoleKeysOptions{AddKeys: 1}
+
+	repository := &MockedRepository{}
+	suite.mockedPublisher.On("GetRepository").Return(repository)
+	suite.mockedPublisher.On("UpdateRoleKeys", "targets", opts).Return(errors.New("threshold is not met"))
+
+	err := suite.backend.updateTufKeysTask(completeConfiguration(), "targets", opts)(suite.taskContext(), suite.storage)
+	assert.EqualError(suite.T(), err, "unable to update targets keys: threshold is not met")
+
+	suite.mockedPublisher.AssertExpectations(suite.T())
+}
+
+func (suite *PathConfigureTufKeysCallbacksSuite) TestRead_ConfigurationNotFound() {
+	suite.req.Path = "configure/tuf_keys/root"
+	suite.req.Operation = logical.ReadOperation
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), errorResponseConfigurationNotFound, resp)
+}
+
+func TestBackendPathConfigureTufKeysCallbacks(t *testing.T) {
+	suite.Run(t, new(PathConfigureTufKeysCallbacksSuite))
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/theupdateframework/go-tuf/data"
+
+	"github.com/werf/logboek"
+	"github.com/werf/trdl/server/pkg/tasks_manager"
+	"github.com/werf/trdl/server/pkg/util"
+)
+
+const (
+	fieldNameRootKeys      = "root_keys"
+	fieldNameRootThreshold = "root_threshold"
+	fieldNameRootExpires   = "expires"
+	fieldNameRootJSON      = "root_json"
+
+	taskOperationImportTufRoot = "import_tuf_root"
+)
+
+func configureTufRootPaths(b *Backend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern:         "configure/tuf_root/export$",
+			HelpSynopsis:    "Export the unsigned TUF root candidate",
+			HelpDescription: "Export the next version of the TUF root metadata to be signed with the offline root keys and imported. The online roles keys are managed by the plugin and kept as is",
+			Fields: map[string]*framework.FieldSchema{
+				fieldNameRootKeys: {
+					Type:        framework.TypeString,
+					Description: "The JSON list of the offline root public keys in the TUF format. The current root keys are kept if not set",
+				},
+				fieldNameRootThreshold: {
+					Type:        framework.TypeInt,
+					Description: "The number of the root keys required to sign the root",
+					Default:     1,
+				},
+				fieldNameRootExpires: {
+					Type:        framework.TypeDurationSecond,
+					Description: "The root expiration period",
+					Default:     "8760h",
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Description: "Export the unsigned TUF root candidate",
+					Callback:    b.pathConfigureTufRootExport,
+				},
+			},
+		},
+		{
+			Pattern:         "configure/tuf_root/import$",
+			HelpSynopsis:    "Import the TUF root signed with the offline root keys",
+			HelpDescription: "Import the TUF root candidate signed with the offline root keys. The root is cross-signed with the root key stored in the plugin, which is deleted after the import, so the plugin keeps only the online roles keys. The root is imported in a task",
+			Fields: map[string]*framework.FieldSchema{
+				fieldNameRootJSON: {
+					Type:        framework.TypeString,
+					Description: "The signed root.json",
+					Required:    true,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Description: "Import the TUF root signed with the offline root keys",
+					Callback:    b.pathConfigureTufRootImport,
+				},
+			},
+		},
+	}
+}
+
+func (b *Backend) pathConfigureTufRootExport(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	var rootKeys []*data.PublicKey
+	if rootKeysJSON := fields.Get(fieldNameRootKeys).(string); rootKeysJSON != "" {
+		if err := json.Unmarshal([]byte(rootKeysJSON), &rootKeys); err != nil {
+			return logical.ErrorResponse("Unable to parse %s: %s", fieldNameRootKeys, err), nil
+		}
+	}
+
+	publisherRepository, errResp, err := b.getConfiguredRepository(ctx, req)
+	if errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	expires := time.Now().Add(time.Duration(fields.Get(fieldNameRootExpires).(int)) * time.Second)
+	signed, err := publisherRepository.RootCandidate(rootKeys, fields.Get(fieldNameRootThreshold).(int), expires)
+	if err != nil {
+		return logical.ErrorResponse("Unable to export root candidate: %s", err), nil
+	}
+
+	rootJSON, err := json.MarshalIndent(signed, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal root candidate: %w", err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			fieldNameRootJSON: string(rootJSON),
+		},
+	}, nil
+}
+
+func (b *Backend) pathConfigureTufRootImport(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	if errResp := util.CheckRequiredFields(req, fields); errResp != nil {
+		return errResp, nil
+	}
+
+	var signed data.Signed
+	if err := json.Unmarshal([]byte(fields.Get(fieldNameRootJSON).(string)), &signed); err != nil {
+		return logical.ErrorResponse("Unable to parse %s: %s", fieldNameRootJSON, err), nil
+	}
+
+	cfg, err := getConfiguration(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get configuration from storage: %w", err)
+	}
+
+	if cfg == nil {
+		return errorResponseConfigurationNotFound, nil
+	}
+
+	// The import commits the new root, so it holds the TUF repository commit for the whole task.
+	taskUUID, err := b.TasksManager.RunTask(ctx, req.Storage, b.importTufRootTask(cfg, &signed), tasks_manager.TaskOptions{
+		Operation:         taskOperationImportTufRoot,
+		Initiator:         req.DisplayName,
+		InitiatorEntityID: req.EntityID,
+		Resources:         []string{taskResourceTufCommit},
+	})
+	if err != nil {
+		if errors.Is(err, tasks_manager.ErrBusy) {
+			return logical.ErrorResponse("busy"), nil
+		}
+
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"task_uuid": taskUUID,
+		},
+	}, nil
+}
+
+func (b *Backend) importTufRootTask(cfg *configuration, signed *data.Signed) func(context.Context, logical.Storage) error {
+	return func(ctx context.Context, storage logical.Storage) error {
+		logboek.Context(ctx).Default().LogF("Importing the TUF root\n")
+		b.Logger().Debug("Importing the TUF root")
+
+		publisherRepository, err := b.Publisher.GetRepository(ctx, storage, cfg.RepositoryOptions())
+		if err != nil {
+			return fmt.Errorf("error getting publisher repository: %w", err)
+		}
+
+		if err := b.Publisher.ImportRoot(ctx, storage, publisherRepository, signed); err != nil {
+			return fmt.Errorf("unable to import root: %w", err)
+		}
+
+		logboek.Context(ctx).Default().LogF("Task finished\n")
+		b.Logger().Debug("Task finished")
+
+		return nil
+	}
+}
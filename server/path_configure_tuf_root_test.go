@@ -0,0 +1,114 @@
+package server
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/theupdateframework/go-tuf/data"
+
+	"github.com/werf/trdl/server/pkg/tasks_manager"
+)
+
+type PathConfigureTufRootCallbacksSuite struct {
+	CommonSuite
+}
+
+func (suite *PathConfigureTufRootCallbacksSuite) TestExport_InvalidRootKeys() {
+	suite.req.Path = "configure/tuf_root/export"
+	suite.req.Operation = logical.UpdateOperation
+	suite.req.Data = map[string]interface{}{
+		fieldNameRootKeys: "not a json",
+	}
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), resp.IsError())
+	assert.Contains(suite.T(), resp.Error().Error(), "Unable to parse root_keys")
+}
+
+func (suite *PathConfigureTufRootCallbacksSuite) TestExport_ConfigurationNotFound() {
+	suite.req.Path = "configure/tuf_root/export"
+	suite.req.Operation = logical.UpdateOperation
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), errorResponseConfigurationNotFound, resp)
+}
+
+func (suite *PathConfigureTufRootCallbacksSuite) TestImport_RequiredFields() {
+	suite.req.Path = "configure/tuf_root/import"
+	suite.req.Operation = logical.UpdateOperation
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), logical.ErrorResponse("Required field %q must be set", fieldNameRootJSON), resp)
+}
+
+func (suite *PathConfigureTufRootCallbacksSuite) TestImport_ConfigurationNotFound() {
+	suite.req.Path = "configure/tuf_root/import"
+	suite.req.Operation = logical.UpdateOperation
+	suite.req.Data = map[string]interface{}{
+		fieldNameRootJSON: `{"signed": {}, "signatures": []}`,
+	}
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), errorResponseConfigurationNotFound, resp)
+}
+
+func (suite *PathConfigureTufRootCallbacksSuite) TestImport_Basic() {
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+
+	suite.req.Path = "configure/tuf_root/import"
+	suite.req.Operation = logical.UpdateOperation
+	suite.req.Data = map[string]interface{}{
+		fieldNameRootJSON: `{"signed": {}, "signatures": []}`,
+	}
+
+	suite.mockedTasksManager.On("RunTask").Return("UUID", nil)
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	if assert.NotNil(suite.T(), resp) {
+		assert.Equal(suite.T(), map[string]interface{}{"task_uuid": "UUID"}, resp.Data)
+	}
+
+	suite.mockedTasksManager.AssertExpectations(suite.T())
+}
+
+func (suite *PathConfigureTufRootCallbacksSuite) TestImport_Busy() {
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+
+	suite.req.Path = "configure/tuf_root/import"
+	suite.req.Operation = logical.UpdateOperation
+	suite.req.Data = map[string]interface{}{
+		fieldNameRootJSON: `{"signed": {}, "signatures": []}`,
+	}
+
+	suite.mockedTasksManager.IsBusy = true
+	suite.mockedTasksManager.On("RunTask").Return("", tasks_manager.ErrBusy)
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), logical.ErrorResponse("busy"), resp)
+}
+
+func (suite *PathConfigureTufRootCallbacksSuite) TestImportTufRootTask() {
+	repository := &MockedRepository{}
+	suite.mockedPublisher.On("GetRepository").Return(repository)
+	suite.mockedPublisher.On("ImportRoot").Return(errors.New("root is not signed"))
+
+	err := suite.backend.importTufRootTask(completeConfiguration(), &data.Signed{})(suite.taskContext(), suite.storage)
+	assert.EqualError(suite.T(), err, "unable to import root: root is not signed")
+
+	suite.mockedPublisher.AssertExpectations(suite.T())
+}
+
+func TestBackendPathConfigureTufRootCallbacks(t *testing.T) {
+	suite.Run(t, new(PathConfigureTufRootCallbacksSuite))
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"path"
 	"strings"
+	"time"
 
 	"github.com/Masterminds/semver"
 	"github.com/go-git/go-git/v5"
@@ -12,6 +15,7 @@ import (
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/samber/lo"
 	"gopkg.in/yaml.v2"
 
 	"github.com/werf/logboek"
@@ -25,12 +29,21 @@ import (
 
 const (
 	storageKeyLastPublishedGitCommit = "last_published_git_commit"
+
+	fieldNameDryRun    = "dry_run"
+	fieldNameGitBranch = "git_branch"
+
+	publishDryRunTimeout = 5 * time.Minute
 )
 
 func NewErrPublishingNonExistingReleases(releases []string) error {
 	return util.NewLogicalError("publishing non existing releases: %v", releases)
 }
 
+func NewErrPublishingYankedRelease(group, channel, release, reason string) error {
+	return util.NewLogicalError("channel %q of group %q points at yanked release %q (reason: %q)", channel, group, release, reason)
+}
+
 func publishPath(b *Backend) *framework.Path {
 	return &framework.Path{
 		Pattern: `publish$`,
@@ -43,6 +56,15 @@ func publishPath(b *Backend) *framework.Path {
 				Type:        framework.TypeString,
 				Description: "Git password",
 			},
+			fieldNameDryRun: {
+				Type:        framework.TypeBool,
+				Description: "Verify and validate the trdl channels config and return the channels changes without publishing them",
+				Default:     false,
+			},
+			fieldNameGitBranch: {
+				Type:        framework.TypeString,
+				Description: "Git branch to dry run instead of the configured trdl channels branch (allowed only with dry_run)",
+			},
 		},
 
 		Operations: map[logical.Operation]framework.OperationHandler{
@@ -87,142 +109,316 @@ func (b *Backend) pathPublish(ctx context.Context, req *logical.Request, fields
 		gitPassword = gitCredentialFromStorage.Password
 	}
 
-	lastPublishedGitCommit := cfg.InitialLastPublishedGitCommit
-	{
-		entry, err := req.Storage.Get(ctx, storageKeyLastPublishedGitCommit)
-		if err != nil {
-			return nil, fmt.Errorf("unable to get %q from storage: %w", storageKeyLastPublishedGitCommit, err)
-		}
+	lastPublishedGitCommit, err := getLastPublishedGitCommit(ctx, req.Storage, cfg)
+	if err != nil {
+		return nil, err
+	}
 
-		if entry != nil {
-			lastPublishedGitCommit = string(entry.Value)
+	dryRun := fields.Get(fieldNameDryRun).(bool)
+	gitBranch := fields.Get(fieldNameGitBranch).(string)
+	if gitBranch != "" && !dryRun {
+		return logical.ErrorResponse("Field %q can be set only with %q", fieldNameGitBranch, fieldNameDryRun), nil
+	}
+
+	if dryRun {
+		if gitBranch == "" {
+			gitBranch = cfg.GitTrdlChannelsBranch
 		}
+
+		return b.pathPublishDryRun(ctx, req, cfg, gitBranch, gitUsername, gitPassword, lastPublishedGitCommit)
 	}
 
-	opts := cfg.RepositoryOptions()
-	opts.InitializeTUFKeys = true
-	opts.InitializePGPSigningKey = true
-	publisherRepository, err := b.Publisher.GetRepository(ctx, req.Storage, opts)
+	publisherRepository, err := b.getTaskPublisherRepository(ctx, req.Storage, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("error getting publisher repository: %w", err)
 	}
 
-	taskUUID, err := b.TasksManager.RunTask(ctx, req.Storage, func(ctx context.Context, storage logical.Storage) error {
-		logboek.Context(ctx).Default().LogF("Started task\n")
-		b.Logger().Debug("Started task")
-
-		logboek.Context(ctx).Default().LogF("Cloning git repo\n")
-		b.Logger().Debug("Cloning git repo")
+	audit := newAuditEntry(req, AuditOperationPublish)
 
-		gitBranch := cfg.GitTrdlChannelsBranch
-		gitRepo, err := cloneGitRepositoryBranch(ctx, cfg.GitRepoUrl, gitBranch, gitUsername, gitPassword)
-		if err != nil {
-			return fmt.Errorf("unable to clone git repository: %w", err)
+	taskUUID, err := b.TasksManager.RunTask(ctx, req.Storage, b.publishTask(cfg, publisherRepository, gitUsername, gitPassword, lastPublishedGitCommit, audit), tasks_manager.TaskOptions{
+		Operation:         string(AuditOperationPublish),
+		Initiator:         audit.DisplayName,
+		InitiatorEntityID: audit.EntityID,
+		Resources:         []string{taskResourceChannels},
+		IsRetryableError:  isRetryableTaskError,
+	})
+	if err != nil {
+		if errors.Is(err, tasks_manager.ErrBusy) {
+			return logical.ErrorResponse("busy"), nil
 		}
 
-		headRef, err := gitRepo.Head()
-		if err != nil {
-			return fmt.Errorf("error getting git repo branch %q head reference: %w", gitBranch, err)
+		if _, match := err.(util.LogicalError); match {
+			return logical.ErrorResponse(err.Error()), nil
 		}
-		headCommit := headRef.Hash().String()
 
-		if lastPublishedGitCommit == headCommit {
-			logboek.Context(ctx).Default().LogF("Head commit %q not changed: skipping publish task\n", headCommit)
-			b.Logger().Debug(fmt.Sprintf("Head commit %q not changed: skipping publish task", headCommit))
+		return nil, err
+	}
 
-			return nil
-		}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"task_uuid": taskUUID,
+		},
+	}, nil
+}
 
-		if lastPublishedGitCommit != "" {
-			logboek.Context(ctx).Default().LogF("Checking previously published commit %q is ancestor to the current head commit %q\n", lastPublishedGitCommit, headCommit)
-			b.Logger().Debug(fmt.Sprintf("Checking previously published commit %q is ancestor to the current head commit %q", lastPublishedGitCommit, headCommit))
+func (b *Backend) publishTask(cfg *configuration, publisherRepository publisher.RepositoryInterface, gitUsername, gitPassword, lastPublishedGitCommit string, audit *auditEntry) func(context.Context, logical.Storage) error {
+	// The state of the previous attempts of the retried task.
+	var attempt int
+	var committedGitCommit string
 
-			isAncestor, err := trdlGit.IsAncestor(gitRepo, lastPublishedGitCommit, headRef.Hash().String())
-			if err != nil {
-				return err
-			}
+	return func(ctx context.Context, storage logical.Storage) (err error) {
+		attempt++
+		if committedGitCommit != "" {
+			return b.finishPublishTask(ctx, storage, publisherRepository, committedGitCommit, audit)
+		}
 
-			if !isAncestor {
-				return fmt.Errorf("cannot publish git commit %q which is not desdendant of previously published git commit %q", headRef.Hash().String(), lastPublishedGitCommit)
+		defer func() {
+			if err != nil && committedGitCommit == "" {
+				b.discardStagedChanges(ctx, publisherRepository)
 			}
-		}
+		}()
 
-		logboek.Context(ctx).Default().LogF("Verifying tag PGP signatures of the commit %q\n", headCommit)
-		b.Logger().Debug(fmt.Sprintf("Verifying tag PGP signatures of the commit %q", headCommit))
+		logboek.Context(ctx).Default().LogF("Started task\n")
+		b.Logger().Debug("Started task")
 
-		trustedPGPPublicKeys, err := pgp.GetTrustedPGPPublicKeys(ctx, req.Storage)
-		if err != nil {
-			return fmt.Errorf("unable to get trusted PGP public keys: %w", err)
+		if attempt > 1 {
+			var err error
+			publisherRepository, err = b.getTaskPublisherRepository(ctx, storage, cfg)
+			if err != nil {
+				return fmt.Errorf("error getting publisher repository: %w", err)
+			}
 		}
 
-		if err := trdlGit.VerifyCommitSignatures(gitRepo, headRef.Hash().String(), trustedPGPPublicKeys, cfg.RequiredNumberOfVerifiedSignaturesOnCommit, b.Logger()); err != nil {
-			return fmt.Errorf("signature verification failed: %w", err)
+		gitRepo, headCommit, err := b.cloneTrdlChannelsBranch(ctx, cfg, cfg.GitTrdlChannelsBranch, gitUsername, gitPassword)
+		if err != nil {
+			return err
 		}
 
-		logboek.Context(ctx).Default().LogF("Verified commit signatures\n")
-		b.Logger().Debug("Verified commit signatures")
-
-		logboek.Context(ctx).Default().LogF("Getting trdl_channels.yaml configuration from the commit %q\n", headCommit)
-		b.Logger().Debug(fmt.Sprintf("Getting trdl_channels.yaml configuration from the commit %q\n", headCommit))
+		if lastPublishedGitCommit == headCommit {
+			logboek.Context(ctx).Default().LogF("Head commit %q not changed: skipping publish task\n", headCommit)
+			b.Logger().Debug(fmt.Sprintf("Head commit %q not changed: skipping publish task", headCommit))
 
-		cfg, err := GetTrdlChannelsConfig(gitRepo, cfg.GitTrdlChannelsPath)
-		if err != nil {
-			return fmt.Errorf("error getting trdl channels config: %w", err)
+			return nil
 		}
 
-		cfgDump, _ := yaml.Marshal(cfg)
-		logboek.Context(ctx).Default().LogF("Got trdl channels config:\n%s\n---\n", cfgDump)
-		b.Logger().Debug(fmt.Sprintf("Got trdl channels config:\n%s\n---", cfgDump))
-
-		if err := ValidatePublishConfig(ctx, b.Publisher, publisherRepository, cfg, b.Logger()); err != nil {
-			return fmt.Errorf("unable to publish bad config: %w", err)
+		trdlChannelsCfg, err := b.getVerifiedTrdlChannelsConfig(ctx, storage, cfg, publisherRepository, gitRepo, headCommit, lastPublishedGitCommit)
+		if err != nil {
+			return err
 		}
 
 		logboek.Context(ctx).Default().LogF("Publishing trdl channels config into the TUF repository\n")
 		b.Logger().Debug("Publishing trdl channels config into the TUF repository")
-		if err := b.Publisher.StageChannelsConfig(ctx, publisherRepository, cfg); err != nil {
+		if err := b.Publisher.StageChannelsConfig(ctx, storage, publisherRepository, trdlChannelsCfg, publisher.StageChannelsConfigOptions{GitCommit: headCommit}); err != nil {
 			return fmt.Errorf("error publishing trdl channels into the repository: %w", err)
 		}
 
+		unlockTufCommit, err := lockTufCommit(ctx)
+		if err != nil {
+			return err
+		}
+		defer unlockTufCommit()
+
+		if err := tasks_manager.PutTaskCheckpoint(ctx, storage, taskCheckpointCommitting, nil); err != nil {
+			return fmt.Errorf("unable to put task checkpoint: %w", err)
+		}
+
 		logboek.Context(ctx).Default().LogF("Committing TUF repository state\n")
 		b.Logger().Debug("Committing TUF repository state")
 
 		if err := publisherRepository.CommitStaged(ctx); err != nil {
 			return fmt.Errorf("unable to commit new tuf repository state: %w", err)
 		}
+		committedGitCommit = headCommit
 
-		logboek.Context(ctx).Default().LogF("Storing published commit record %q into the storage\n", headCommit)
-		b.Logger().Debug(fmt.Sprintf("Storing published commit record %q into the storage", headCommit))
-
-		if err := storage.Put(ctx, &logical.StorageEntry{Key: storageKeyLastPublishedGitCommit, Value: []byte(headCommit)}); err != nil {
-			return fmt.Errorf("unable to put %q into storage: %w", storageKeyLastPublishedGitCommit, err)
+		if err := tasks_manager.PutTaskCheckpoint(ctx, storage, taskCheckpointCommitted, map[string]string{"git_commit": headCommit}); err != nil {
+			return fmt.Errorf("unable to put task checkpoint: %w", err)
 		}
 
-		logboek.Context(ctx).Default().LogF("Task finished\n")
-		b.Logger().Debug("Task finished")
+		return b.finishPublishTask(ctx, storage, publisherRepository, headCommit, audit)
+	}
+}
+
+// finishPublishTask records the channels config committed into the TUF repository.
+func (b *Backend) finishPublishTask(ctx context.Context, storage logical.Storage, publisherRepository publisher.RepositoryInterface, headCommit string, audit *auditEntry) error {
+	logboek.Context(ctx).Default().LogF("Storing published commit record %q into the storage\n", headCommit)
+	b.Logger().Debug(fmt.Sprintf("Storing published commit record %q into the storage", headCommit))
 
-		return nil
-	})
+	if err := storage.Put(ctx, &logical.StorageEntry{Key: storageKeyLastPublishedGitCommit, Value: []byte(headCommit)}); err != nil {
+		return fmt.Errorf("unable to put %q into storage: %w", storageKeyLastPublishedGitCommit, err)
+	}
+
+	audit.GitCommit = headCommit
+	if err := putRepositoryAuditEntry(ctx, storage, publisherRepository, audit); err != nil {
+		return fmt.Errorf("unable to record audit entry: %w", err)
+	}
+
+	if err := tasks_manager.PutTaskResult(ctx, storage, map[string]interface{}{
+		"git_commit":   headCommit,
+		"tuf_versions": audit.TufVersions,
+	}); err != nil {
+		return fmt.Errorf("unable to put task result: %w", err)
+	}
+
+	logboek.Context(ctx).Default().LogF("Task finished\n")
+	b.Logger().Debug("Task finished")
+
+	return nil
+}
+
+func getLastPublishedGitCommit(ctx context.Context, storage logical.Storage, cfg *configuration) (string, error) {
+	entry, err := storage.Get(ctx, storageKeyLastPublishedGitCommit)
 	if err != nil {
-		if errors.Is(err, tasks_manager.ErrBusy) {
-			return logical.ErrorResponse("busy"), nil
-		}
+		return "", fmt.Errorf("unable to get %q from storage: %w", storageKeyLastPublishedGitCommit, err)
+	}
 
-		if _, match := err.(util.LogicalError); match {
-			return logical.ErrorResponse(err.Error()), nil
-		}
+	if entry == nil {
+		return cfg.InitialLastPublishedGitCommit, nil
+	}
 
-		return nil, err
+	return string(entry.Value), nil
+}
+
+// pathPublishDryRun runs the publish checks of the git branch right away without the task and returns the channels changes.
+// Nothing is staged, the repository keys are not initialized.
+// The git repository is cloned and the PGP signatures are verified within the request, so the dry run is limited by publishDryRunTimeout.
+func (b *Backend) pathPublishDryRun(ctx context.Context, req *logical.Request, cfg *configuration, gitBranch, gitUsername, gitPassword, lastPublishedGitCommit string) (*logical.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, publishDryRunTimeout)
+	defer cancel()
+
+	publisherRepository, err := b.Publisher.GetRepository(ctx, req.Storage, cfg.RepositoryOptions())
+	if errors.Is(err, publisher.ErrUninitializedRepositoryKeys) {
+		return logical.ErrorResponse("Repository is not initialized"), nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error getting publisher repository: %w", err)
+	}
+
+	ctx = logboek.NewContext(ctx, logboek.DefaultLogger().NewSubLogger(io.Discard, io.Discard))
+
+	gitRepo, headCommit, err := b.cloneTrdlChannelsBranch(ctx, cfg, gitBranch, gitUsername, gitPassword)
+	if err != nil {
+		return logical.ErrorResponse("Dry run failed: %s", err), nil
+	}
+
+	trdlChannelsCfg, err := b.getVerifiedTrdlChannelsConfig(ctx, req.Storage, cfg, publisherRepository, gitRepo, headCommit, lastPublishedGitCommit)
+	if err != nil {
+		return logical.ErrorResponse("Dry run failed: %s", err), nil
+	}
+
+	publishedChannels, err := publisherRepository.GetChannels(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get channels: %w", err)
 	}
 
 	return &logical.Response{
 		Data: map[string]interface{}{
-			"task_uuid": taskUUID,
+			"git_branch": gitBranch,
+			"git_commit": headCommit,
+			"changes":    channelsChanges(publishedChannels, trdlChannelsCfg),
 		},
 	}, nil
 }
 
-func ValidatePublishConfig(ctx context.Context, publisher publisher.Interface, publisherRepository publisher.RepositoryInterface, config *config.TrdlChannels, logger hclog.Logger) error {
+// channelsChanges returns the channels the config points at the other versions than the published ones.
+// The channels missing in the config are not changed by the publish, so they are not reported.
+func channelsChanges(publishedChannels []publisher.PublishedChannel, trdlChannelsCfg *config.TrdlChannels) []map[string]interface{} {
+	publishedVersions := make(map[string]string)
+	for _, channel := range publishedChannels {
+		publishedVersions[path.Join(channel.Group, channel.Name)] = channel.Version
+	}
+
+	changes := []map[string]interface{}{}
+	for _, group := range trdlChannelsCfg.Groups {
+		for _, channel := range group.Channels {
+			publishedVersion := publishedVersions[path.Join(group.Name, channel.Name)]
+			if publishedVersion == channel.Version {
+				continue
+			}
+
+			changes = append(changes, map[string]interface{}{
+				"group":             group.Name,
+				"channel":           channel.Name,
+				"published_version": publishedVersion,
+				"version":           channel.Version,
+			})
+		}
+	}
+
+	return changes
+}
+
+func (b *Backend) cloneTrdlChannelsBranch(ctx context.Context, cfg *configuration, gitBranch, gitUsername, gitPassword string) (*git.Repository, string, error) {
+	logboek.Context(ctx).Default().LogF("Cloning git repo\n")
+	b.Logger().Debug("Cloning git repo")
+
+	gitRepo, err := cloneGitRepositoryBranch(ctx, cfg.GitRepoUrl, gitBranch, gitUsername, gitPassword)
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to clone git repository: %w", err)
+	}
+
+	headRef, err := gitRepo.Head()
+	if err != nil {
+		return nil, "", fmt.Errorf("error getting git repo branch %q head reference: %w", gitBranch, err)
+	}
+
+	return gitRepo, headRef.Hash().String(), nil
+}
+
+// getVerifiedTrdlChannelsConfig returns the trdl channels config of the head commit ready to be published.
+func (b *Backend) getVerifiedTrdlChannelsConfig(ctx context.Context, storage logical.Storage, cfg *configuration, publisherRepository publisher.RepositoryInterface, gitRepo *git.Repository, headCommit, lastPublishedGitCommit string) (*config.TrdlChannels, error) {
+	if lastPublishedGitCommit != "" && lastPublishedGitCommit != headCommit {
+		logboek.Context(ctx).Default().LogF("Checking previously published commit %q is ancestor to the current head commit %q\n", lastPublishedGitCommit, headCommit)
+		b.Logger().Debug(fmt.Sprintf("Checking previously published commit %q is ancestor to the current head commit %q", lastPublishedGitCommit, headCommit))
+
+		isAncestor, err := trdlGit.IsAncestor(gitRepo, lastPublishedGitCommit, headCommit)
+		if err != nil {
+			return nil, err
+		}
+
+		if !isAncestor {
+			return nil, fmt.Errorf("cannot publish git commit %q which is not desdendant of previously published git commit %q", headCommit, lastPublishedGitCommit)
+		}
+	}
+
+	logboek.Context(ctx).Default().LogF("Verifying tag PGP signatures of the commit %q\n", headCommit)
+	b.Logger().Debug(fmt.Sprintf("Verifying tag PGP signatures of the commit %q", headCommit))
+
+	trustedPGPPublicKeys, err := pgp.GetTrustedPGPPublicKeys(ctx, storage)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get trusted PGP public keys: %w", err)
+	}
+
+	if err := trdlGit.VerifyCommitSignatures(gitRepo, headCommit, trustedPGPPublicKeys, cfg.RequiredNumberOfVerifiedSignaturesOnCommit, b.Logger()); err != nil {
+		return nil, fmt.Errorf("signature verification failed: %w", err)
+	}
+
+	logboek.Context(ctx).Default().LogF("Verified commit signatures\n")
+	b.Logger().Debug("Verified commit signatures")
+
+	logboek.Context(ctx).Default().LogF("Getting trdl_channels.yaml configuration from the commit %q\n", headCommit)
+	b.Logger().Debug(fmt.Sprintf("Getting trdl_channels.yaml configuration from the commit %q\n", headCommit))
+
+	trdlChannelsCfg, err := GetTrdlChannelsConfig(gitRepo, cfg.GitTrdlChannelsPath)
+	if err != nil {
+		return nil, fmt.Errorf("error getting trdl channels config: %w", err)
+	}
+
+	cfgDump, _ := yaml.Marshal(trdlChannelsCfg)
+	logboek.Context(ctx).Default().LogF("Got trdl channels config:\n%s\n---\n", cfgDump)
+	b.Logger().Debug(fmt.Sprintf("Got trdl channels config:\n%s\n---", cfgDump))
+
+	if err := ValidatePublishConfig(ctx, b.Publisher, publisherRepository, trdlChannelsCfg, b.Logger(), ValidatePublishConfigOptions{AutoPromotableChannels: cfg.AutoPromotableChannels}); err != nil {
+		return nil, fmt.Errorf("unable to publish bad config: %w", err)
+	}
+
+	return trdlChannelsCfg, nil
+}
+
+type ValidatePublishConfigOptions struct {
+	// AutoPromotableChannels are the channels the promotion rules may move ahead of the trdl channels configuration, as <group>/<channel>.
+	AutoPromotableChannels []string
+}
+
+func ValidatePublishConfig(ctx context.Context, publisher publisher.Interface, publisherRepository publisher.RepositoryInterface, config *config.TrdlChannels, logger hclog.Logger, opts ValidatePublishConfigOptions) error {
 	existingReleases, err := publisher.GetExistingReleases(ctx, publisherRepository)
 	if err != nil {
 		return fmt.Errorf("error getting existing targets: %w", err)
@@ -231,17 +427,30 @@ func ValidatePublishConfig(ctx context.Context, publisher publisher.Interface, p
 	logboek.Context(ctx).Default().LogF("Got existing releases list: %v\n", existingReleases)
 	logger.Debug(fmt.Sprintf("Got existing releases list: %v\n", existingReleases))
 
+	yankedReleases, err := publisher.GetYankedReleases(ctx, publisherRepository)
+	if err != nil {
+		return fmt.Errorf("error getting yanked releases: %w", err)
+	}
+
+	yankReasons := make(map[string]string)
+	for _, yankedRelease := range yankedReleases {
+		yankReasons[yankedRelease.Name] = yankedRelease.Reason
+	}
+
+	publishedChannels, err := publisherRepository.GetChannels(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting published channels: %w", err)
+	}
+
+	publishedChannelsByName := publishedChannelsByGroupChannel(publishedChannels)
+
 	var nonExistingReleases []string
 
 	processedGroups := map[string]bool{}
 
 	for _, group := range config.Groups {
-		if strings.HasPrefix(group.Name, "v") {
-			return fmt.Errorf("bad group name %q, expected semver without \"v\" prefix", group.Name)
-		}
-
-		if _, err := semver.NewVersion(group.Name); err != nil {
-			return fmt.Errorf("expected semver group got %q: %w", group.Name, err)
+		if err := validateChannelsGroupName(group.Name); err != nil {
+			return err
 		}
 
 		if _, hasKey := processedGroups[group.Name]; hasKey {
@@ -257,10 +466,8 @@ func ValidatePublishConfig(ctx context.Context, publisher publisher.Interface, p
 				return fmt.Errorf("duplicate channel %q found within group %q", channel.Name, group.Name)
 			}
 
-			switch channel.Name {
-			case "dev", "alpha", "beta", "ea", "stable", "rock-solid":
-			default:
-				return NewErrIncorrectChannelName(channel.Name)
+			if err := validateChannelName(channel.Name); err != nil {
+				return err
 			}
 
 			if err := ValidateReleaseVersion(channel.Version); err != nil {
@@ -271,6 +478,15 @@ func ValidatePublishConfig(ctx context.Context, publisher publisher.Interface, p
 				return fmt.Errorf("bad version %q, expected semver without \"v\" prefix", channel.Version)
 			}
 
+			if reason, yanked := yankReasons[channel.Version]; yanked {
+				return NewErrPublishingYankedRelease(group.Name, channel.Name, channel.Version, reason)
+			}
+
+			groupChannel := path.Join(group.Name, channel.Name)
+			if err := checkChannelDowngrade(ctx, group.Name, channel, publishedChannelsByName[groupChannel], lo.Contains(opts.AutoPromotableChannels, groupChannel), logger); err != nil {
+				return err
+			}
+
 			releaseExists := false
 			for _, release := range existingReleases {
 				if channel.Version == release {
@@ -307,6 +523,75 @@ func ValidatePublishConfig(ctx context.Context, publisher publisher.Interface, p
 	return nil
 }
 
+// publishedChannelsByGroupChannel returns the published channels by <group>/<channel>.
+func publishedChannelsByGroupChannel(channels []publisher.PublishedChannel) map[string]publisher.PublishedChannel {
+	return lo.KeyBy(channels, func(channel publisher.PublishedChannel) string {
+		return path.Join(channel.Group, channel.Name)
+	})
+}
+
+func validateChannelsGroupName(group string) error {
+	if strings.HasPrefix(group, "v") {
+		return fmt.Errorf("bad group name %q, expected semver without \"v\" prefix", group)
+	}
+
+	if _, err := semver.NewVersion(group); err != nil {
+		return fmt.Errorf("expected semver group got %q: %w", group, err)
+	}
+
+	return nil
+}
+
+func validateChannelName(channel string) error {
+	switch channel {
+	case "dev", "alpha", "beta", "ea", "stable", "rock-solid":
+		return nil
+	default:
+		return NewErrIncorrectChannelName(channel)
+	}
+}
+
+// checkChannelDowngrade refuses to move the channel to the version lower than the published one unless the downgrade is allowed explicitly.
+// The auto-promotable channel promoted ahead of the trdl channels configuration is moved back to the configured version.
+func checkChannelDowngrade(ctx context.Context, group string, channel config.TrdlGroupChannel, published publisher.PublishedChannel, autoPromotable bool, logger hclog.Logger) error {
+	publishedVersion := published.Version
+	if publishedVersion == "" {
+		return nil
+	}
+
+	// The published version is not validated by the earlier trdl versions.
+	publishedSemver, err := semver.NewVersion(publishedVersion)
+	if err != nil {
+		logboek.Context(ctx).Warn().LogF("Channel %q of group %q is not checked for downgrade: the published version %q is not a semver: %s\n", channel.Name, group, publishedVersion, err)
+		logger.Warn(fmt.Sprintf("Channel %q of group %q is not checked for downgrade: the published version %q is not a semver: %s", channel.Name, group, publishedVersion, err))
+		return nil
+	}
+
+	if !semver.MustParse(channel.Version).LessThan(publishedSemver) {
+		return nil
+	}
+
+	if autoPromotable && published.PromotedFromTag != "" {
+		logboek.Context(ctx).Warn().LogF("Channel %q of group %q promoted to %q by the tag %q is moved back to %q by the trdl channels configuration\n", channel.Name, group, publishedVersion, published.PromotedFromTag, channel.Version)
+		logger.Warn(fmt.Sprintf("Channel %q of group %q promoted to %q by the tag %q is moved back to %q by the trdl channels configuration", channel.Name, group, publishedVersion, published.PromotedFromTag, channel.Version))
+		return nil
+	}
+
+	if channel.AllowDowngrade {
+		logboek.Context(ctx).Warn().LogF("Channel %q of group %q is rolled back from %q to %q\n", channel.Name, group, publishedVersion, channel.Version)
+		logger.Warn(fmt.Sprintf("Channel %q of group %q is rolled back from %q to %q", channel.Name, group, publishedVersion, channel.Version))
+		return nil
+	}
+
+	logboek.Context(ctx).Error().LogF("Channel %q of group %q would go backwards from %q to %q\n", channel.Name, group, publishedVersion, channel.Version)
+
+	return NewErrChannelDowngrade(group, channel.Name, publishedVersion, channel.Version)
+}
+
+func NewErrChannelDowngrade(group, channel, publishedVersion, version string) error {
+	return util.NewLogicalError("channel %q of group %q would go backwards from %q to %q: set allowDowngrade for the channel to roll it back", channel, group, publishedVersion, version)
+}
+
 func NewErrIncorrectChannelName(chnl string) error {
 	return fmt.Errorf(`got incorrect channel name %q: expected "dev", "alpha", "beta", "ea", "stable" or "rock-solid"`, chnl)
 }
@@ -352,5 +637,5 @@ func GetTrdlChannelsConfig(gitRepo *git.Repository, trdlChannelsPath string) (*c
 
 const (
 	pathPublishHelpSyn  = "Publish release channels"
-	pathPublishHelpDesc = "Publish release channels based on trdl_channels.yaml configuration in the git repository"
+	pathPublishHelpDesc = "Publish release channels based on trdl_channels.yaml configuration in the git repository. The dry run clones the git repository and verifies the PGP signatures within the request and fails if it takes longer than 5 minutes"
 )
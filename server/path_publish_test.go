@@ -1,12 +1,16 @@
 package server
 
 import (
+	"bytes"
 	"testing"
 
+	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/werf/trdl/server/pkg/config"
+	"github.com/werf/trdl/server/pkg/publisher"
 	"github.com/werf/trdl/server/pkg/tasks_manager"
 )
 
@@ -67,6 +71,125 @@ func (suite *PathPublishCallbackSuite) TestBusy() {
 	suite.mockedTasksManager.AssertExpectations(suite.T())
 }
 
+func (suite *PathPublishCallbackSuite) TestGitBranchWithoutDryRun() {
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+
+	suite.req.Data = map[string]interface{}{fieldNameGitBranch: "feature"}
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), logical.ErrorResponse("Field %q can be set only with %q", fieldNameGitBranch, fieldNameDryRun), resp)
+
+	suite.mockedTasksManager.AssertNotCalled(suite.T(), "RunTask")
+}
+
+func (suite *PathPublishCallbackSuite) TestValidatePublishConfig_YankedRelease() {
+	suite.mockedPublisher.On("GetExistingReleases").Return([]string{"1.0.0"})
+	suite.mockedPublisher.On("GetYankedReleases").Return([]publisher.YankedRelease{{Name: "1.1.0", Reason: "broken"}})
+
+	repository := &MockedRepository{}
+	repository.On("GetChannels").Return([]publisher.PublishedChannel(nil))
+
+	err := ValidatePublishConfig(suite.ctx, suite.mockedPublisher, repository, &config.TrdlChannels{
+		Groups: []config.TrdlGroup{
+			{Name: "1", Channels: []config.TrdlGroupChannel{{Name: "alpha", Version: "1.1.0"}, {Name: "stable", Version: "1.0.0"}}},
+		},
+	}, hclog.NewNullLogger(), ValidatePublishConfigOptions{})
+	assert.Equal(suite.T(), NewErrPublishingYankedRelease("1", "alpha", "1.1.0", "broken"), err)
+
+	suite.mockedPublisher.AssertExpectations(suite.T())
+}
+
+func (suite *PathPublishCallbackSuite) TestValidatePublishConfig_Downgrade() {
+	suite.mockedPublisher.On("GetExistingReleases").Return([]string{"1.9.0", "2.3.0"})
+	suite.mockedPublisher.On("GetYankedReleases").Return([]publisher.YankedRelease(nil))
+
+	repository := &MockedRepository{}
+	repository.On("GetChannels").Return([]publisher.PublishedChannel{
+		{Group: "1", Name: "alpha", Version: "1.9.0"},
+		{Group: "1", Name: "stable", Version: "2.3.0"},
+	})
+
+	channelsConfig := func(allowDowngrade bool) *config.TrdlChannels {
+		return &config.TrdlChannels{
+			Groups: []config.TrdlGroup{
+				{Name: "1", Channels: []config.TrdlGroupChannel{
+					{Name: "alpha", Version: "2.3.0"},
+					{Name: "stable", Version: "1.9.0", AllowDowngrade: allowDowngrade},
+				}},
+			},
+		}
+	}
+
+	err := ValidatePublishConfig(suite.ctx, suite.mockedPublisher, repository, channelsConfig(false), hclog.NewNullLogger(), ValidatePublishConfigOptions{})
+	assert.Equal(suite.T(), NewErrChannelDowngrade("1", "stable", "2.3.0", "1.9.0"), err)
+
+	err = ValidatePublishConfig(suite.ctx, suite.mockedPublisher, repository, channelsConfig(true), hclog.NewNullLogger(), ValidatePublishConfigOptions{})
+	assert.Nil(suite.T(), err)
+}
+
+func (suite *PathPublishCallbackSuite) TestValidatePublishConfig_DowngradeOfPromotedChannel() {
+	suite.mockedPublisher.On("GetExistingReleases").Return([]string{"1.8.0", "1.9.0"})
+	suite.mockedPublisher.On("GetYankedReleases").Return([]publisher.YankedRelease(nil))
+
+	repository := &MockedRepository{}
+	repository.On("GetChannels").Return([]publisher.PublishedChannel{
+		{Group: "1", Name: "alpha", Version: "1.9.0", PromotedFromTag: "v1.9.0"},
+		{Group: "1", Name: "stable", Version: "1.9.0"},
+	})
+
+	channelsConfig := &config.TrdlChannels{
+		Groups: []config.TrdlGroup{
+			{Name: "1", Channels: []config.TrdlGroupChannel{{Name: "alpha", Version: "1.8.0"}}},
+		},
+	}
+
+	err := ValidatePublishConfig(suite.ctx, suite.mockedPublisher, repository, channelsConfig, hclog.NewNullLogger(), ValidatePublishConfigOptions{})
+	assert.Equal(suite.T(), NewErrChannelDowngrade("1", "alpha", "1.9.0", "1.8.0"), err)
+
+	err = ValidatePublishConfig(suite.ctx, suite.mockedPublisher, repository, channelsConfig, hclog.NewNullLogger(), ValidatePublishConfigOptions{AutoPromotableChannels: []string{"1/alpha", "1/stable"}})
+	assert.Nil(suite.T(), err)
+
+	// The channel published from the trdl channels configuration is checked even if it is auto-promotable.
+	channelsConfig.Groups[0].Channels = []config.TrdlGroupChannel{{Name: "stable", Version: "1.8.0"}}
+	err = ValidatePublishConfig(suite.ctx, suite.mockedPublisher, repository, channelsConfig, hclog.NewNullLogger(), ValidatePublishConfigOptions{AutoPromotableChannels: []string{"1/alpha", "1/stable"}})
+	assert.Equal(suite.T(), NewErrChannelDowngrade("1", "stable", "1.9.0", "1.8.0"), err)
+}
+
+func (suite *PathPublishCallbackSuite) TestValidatePublishConfig_DowngradeOfNotSemverPublishedVersion() {
+	suite.mockedPublisher.On("GetExistingReleases").Return([]string{"1.9.0"})
+	suite.mockedPublisher.On("GetYankedReleases").Return([]publisher.YankedRelease(nil))
+
+	repository := &MockedRepository{}
+	repository.On("GetChannels").Return([]publisher.PublishedChannel{{Group: "1", Name: "stable", Version: "latest"}})
+
+	var logBuf bytes.Buffer
+	err := ValidatePublishConfig(suite.ctx, suite.mockedPublisher, repository, &config.TrdlChannels{
+		Groups: []config.TrdlGroup{{Name: "1", Channels: []config.TrdlGroupChannel{{Name: "stable", Version: "1.9.0"}}}},
+	}, hclog.New(&hclog.LoggerOptions{Output: &logBuf}), ValidatePublishConfigOptions{})
+	assert.Nil(suite.T(), err)
+	assert.Contains(suite.T(), logBuf.String(), `Channel "stable" of group "1" is not checked for downgrade: the published version "latest" is not a semver`)
+}
+
 func TestBackendPathPublishCallback(t *testing.T) {
 	suite.Run(t, new(PathPublishCallbackSuite))
 }
+
+func TestChannelsChanges(t *testing.T) {
+	changes := channelsChanges([]publisher.PublishedChannel{
+		{Group: "1", Name: "alpha", Version: "1.1.0"},
+		{Group: "1", Name: "stable", Version: "1.0.0"},
+		{Group: "1", Name: "rock-solid", Version: "1.0.0"},
+	}, &config.TrdlChannels{
+		Groups: []config.TrdlGroup{
+			{Name: "1", Channels: []config.TrdlGroupChannel{{Name: "alpha", Version: "1.2.0"}, {Name: "stable", Version: "1.0.0"}}},
+			{Name: "2", Channels: []config.TrdlGroupChannel{{Name: "alpha", Version: "2.0.0"}}},
+		},
+	})
+
+	assert.Equal(t, []map[string]interface{}{
+		{"group": "1", "channel": "alpha", "published_version": "1.1.0", "version": "1.2.0"},
+		{"group": "2", "channel": "alpha", "published_version": "", "version": "2.0.0"},
+	}, changes)
+}
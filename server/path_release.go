@@ -15,6 +15,7 @@ import (
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/samber/lo"
 
 	"github.com/werf/logboek"
 	"github.com/werf/trdl/server/pkg/config"
@@ -22,6 +23,7 @@ import (
 	"github.com/werf/trdl/server/pkg/elf_signing"
 	trdlGit "github.com/werf/trdl/server/pkg/git"
 	"github.com/werf/trdl/server/pkg/pgp"
+	"github.com/werf/trdl/server/pkg/publisher"
 	"github.com/werf/trdl/server/pkg/tasks_manager"
 	"github.com/werf/trdl/server/pkg/util"
 )
@@ -30,6 +32,7 @@ const (
 	fieldNameGitTag      = "git_tag"
 	fieldNameGitUsername = "git_username"
 	fieldNameGitPassword = "git_password"
+	fieldNameForce       = "force"
 )
 
 func releasePath(b *Backend) *framework.Path {
@@ -49,6 +52,11 @@ func releasePath(b *Backend) *framework.Path {
 				Type:        framework.TypeString,
 				Description: "Git password",
 			},
+			fieldNameForce: {
+				Type:        framework.TypeBool,
+				Description: "Rebuild the already published release replacing its artifacts. The field is meant for the administrators only and should be denied for the others by the denied_parameters of the Vault policy",
+				Default:     false,
+			},
 		},
 
 		Operations: map[logical.Operation]framework.OperationHandler{
@@ -107,30 +115,136 @@ func (b *Backend) pathRelease(ctx context.Context, req *logical.Request, fields
 		gitPassword = gitCredentialFromStorage.Password
 	}
 
-	opts := cfg.RepositoryOptions()
-	opts.InitializeTUFKeys = true
-	opts.InitializePGPSigningKey = true
-	publisherRepository, err := b.Publisher.GetRepository(ctx, req.Storage, opts)
+	publisherRepository, err := b.getTaskPublisherRepository(ctx, req.Storage, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("error getting publisher repository: %w", err)
 	}
 
-	taskUUID, err := b.TasksManager.RunTask(ctx, req.Storage, func(ctx context.Context, storage logical.Storage) error {
+	force := fields.Get(fieldNameForce).(bool)
+	releaseExists, errResp, err := b.checkReleaseNotPublished(ctx, publisherRepository, releaseName, force)
+	if errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	if releaseExists {
+		b.Logger().Warn(fmt.Sprintf("Forced rebuild of the published release %q requested by %q (entity id %q)", releaseName, req.DisplayName, req.EntityID))
+	}
+
+	audit := newAuditEntry(req, AuditOperationRelease)
+
+	if cfg.ReleaseApprovalsRequired > 0 {
+		resp, err := b.createPendingRelease(ctx, req, cfg, &pendingRelease{
+			GitTag:            gitTag,
+			Force:             force,
+			Initiator:         req.DisplayName,
+			InitiatorEntityID: req.EntityID,
+		})
+		if err == nil && !resp.IsError() && (fields.Get(fieldNameGitUsername).(string) != "" || fields.Get(fieldNameGitPassword).(string) != "") {
+			resp.AddWarning("The git credentials are not stored with the pending release: the final approver has to pass them, otherwise the stored git credential is used")
+		}
+
+		return resp, err
+	}
+
+	return b.runReleaseTask(ctx, req.Storage, cfg, publisherRepository, gitTag, gitUsername, gitPassword, releaseExists, audit)
+}
+
+// runReleaseTask starts the task building the release artifacts and publishing them into the TUF repository.
+// The audit entry is recorded once the release is published.
+func (b *Backend) runReleaseTask(ctx context.Context, storage logical.Storage, cfg *configuration, publisherRepository publisher.RepositoryInterface, gitTag, gitUsername, gitPassword string, releaseExists bool, audit *auditEntry) (*logical.Response, error) {
+	params := map[string]string{"git_tag": gitTag}
+	if releaseExists {
+		params["force"] = "true"
+	}
+
+	taskUUID, err := b.TasksManager.RunTask(ctx, storage, b.releaseTask(cfg, publisherRepository, gitTag, gitUsername, gitPassword, releaseExists, audit), tasks_manager.TaskOptions{
+		Operation:         string(AuditOperationRelease),
+		Params:            params,
+		Initiator:         audit.DisplayName,
+		InitiatorEntityID: audit.EntityID,
+		Resources:         []string{taskResourceBuild},
+		IsRetryableError:  isRetryableTaskError,
+	})
+	if err != nil {
+		if errors.Is(err, tasks_manager.ErrBusy) {
+			return logical.ErrorResponse("busy"), nil
+		}
+
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"task_uuid": taskUUID,
+		},
+	}, nil
+}
+
+// releaseTask returns the task building and publishing the release, the published release is rebuilt only if forced.
+// The forced rebuild is recorded as the separate audit operation.
+func (b *Backend) releaseTask(cfg *configuration, publisherRepository publisher.RepositoryInterface, gitTag, gitUsername, gitPassword string, force bool, audit *auditEntry) func(context.Context, logical.Storage) error {
+	releaseName := strings.TrimPrefix(gitTag, "v")
+
+	// The state of the previous attempts of the retried task.
+	var attempt int
+	var gitRepo *git.Repository
+	var committed bool
+	var gitCommit string
+	var stagedTargets []string
+
+	return func(ctx context.Context, storage logical.Storage) (err error) {
+		attempt++
+		if committed {
+			return b.finishReleaseTask(ctx, storage, publisherRepository, gitTag, gitCommit, stagedTargets, audit)
+		}
+
+		defer func() {
+			if err != nil && !committed {
+				b.discardStagedChanges(ctx, publisherRepository)
+			}
+		}()
+
 		logboek.Context(ctx).Default().LogF("Started task\n")
 		b.Logger().Debug("Started task")
 
-		logboek.Context(ctx).Default().LogF("Cloning git repo\n")
-		b.Logger().Debug("Cloning git repo")
+		if attempt > 1 {
+			var err error
+			publisherRepository, err = b.getTaskPublisherRepository(ctx, storage, cfg)
+			if err != nil {
+				return fmt.Errorf("error getting publisher repository: %w", err)
+			}
+		}
 
-		gitRepo, err := cloneGitRepositoryTag(ctx, cfg.GitRepoUrl, gitTag, gitUsername, gitPassword)
+		// The release might be published or yanked by another task since it has been requested.
+		releaseExists, err := b.checkCommittedReleaseNotPublished(ctx, storage, cfg, releaseName, force)
 		if err != nil {
-			return fmt.Errorf("unable to clone git repository: %w", err)
+			return err
+		}
+
+		audit.Operation = AuditOperationRelease
+		if releaseExists {
+			audit.Operation = AuditOperationForceRelease
+		}
+
+		// The build only reads the worktree, so the clone of the failed attempt is reused.
+		if gitRepo == nil {
+			logboek.Context(ctx).Default().LogF("Cloning git repo\n")
+			b.Logger().Debug("Cloning git repo")
+
+			var err error
+			gitRepo, err = cloneGitRepositoryTag(ctx, cfg.GitRepoUrl, gitTag, gitUsername, gitPassword)
+			if err != nil {
+				return fmt.Errorf("unable to clone git repository: %w", err)
+			}
+		} else {
+			logboek.Context(ctx).Default().LogF("Reusing the cloned git repo\n")
+			b.Logger().Debug("Reusing the cloned git repo")
 		}
 
 		logboek.Context(ctx).Default().LogF("Verifying tag PGP signatures of the git tag %q\n", gitTag)
 		b.Logger().Debug(fmt.Sprintf("Verifying tag PGP signatures of the git tag %q", gitTag))
 
-		trustedPGPPublicKeys, err := pgp.GetTrustedPGPPublicKeys(ctx, req.Storage)
+		trustedPGPPublicKeys, err := pgp.GetTrustedPGPPublicKeys(ctx, storage)
 		if err != nil {
 			return fmt.Errorf("unable to get trusted PGP public keys: %w", err)
 		}
@@ -152,7 +266,7 @@ func (b *Backend) pathRelease(ctx context.Context, req *logical.Request, fields
 		b.Logger().Debug("Starting release artifacts tar archive build")
 
 		var elfSigner *elf_signing.ELFSigner
-		if elfSettings, err := elf_signing.GetSettings(ctx, req.Storage); err != nil {
+		if elfSettings, err := elf_signing.GetSettings(ctx, storage); err != nil {
 			return fmt.Errorf("get elf signing settings: %w", err)
 		} else if elfSettings != nil {
 			elfSigner = elf_signing.NewELFSigner(b.Logger(), elfSettings)
@@ -160,6 +274,7 @@ func (b *Backend) pathRelease(ctx context.Context, req *logical.Request, fields
 
 		tarBuf := buffer.New(64 * 1024 * 1024)
 		tarReader, tarWriter := nio.Pipe(tarBuf)
+		defer tarReader.Close()
 
 		errCh := make(chan error, 1)
 		go func() {
@@ -169,7 +284,7 @@ func (b *Backend) pathRelease(ctx context.Context, req *logical.Request, fields
 					GitRepo:          gitRepo,
 					FromImage:        trdlCfg.GetDockerImage(),
 					RunCommands:      trdlCfg.Commands,
-					Storage:          req.Storage,
+					Storage:          storage,
 					BuildkitdAddress: cfg.BuildkitdAddress,
 					BuildxDriver:     cfg.BuildxDriver,
 					BuildxDriverOpts: cfg.BuildxDriverOpts,
@@ -182,6 +297,16 @@ func (b *Backend) pathRelease(ctx context.Context, req *logical.Request, fields
 			errCh <- nil
 		}()
 
+		if releaseExists {
+			logboek.Context(ctx).Default().LogF("Removing the artifacts of the published release %q to rebuild it\n", releaseName)
+			b.Logger().Debug(fmt.Sprintf("Removing the artifacts of the published release %q to rebuild it", releaseName))
+
+			if err := b.Publisher.RemoveReleaseTargets(ctx, publisherRepository, releaseName); err != nil {
+				return fmt.Errorf("unable to remove published release targets: %w", err)
+			}
+		}
+
+		stagedTargets = nil
 		{
 			logboek.Context(ctx).Default().LogF("Starting to read tar artifacts...\n")
 			b.Logger().Debug("Starting to read tar artifacts...")
@@ -205,6 +330,8 @@ func (b *Backend) pathRelease(ctx context.Context, req *logical.Request, fields
 					if err := b.Publisher.StageReleaseTarget(ctx, publisherRepository, releaseName, name, twArtifacts, elfSigner); err != nil {
 						return fmt.Errorf("unable to publish release target %q: %w", name, err)
 					}
+
+					stagedTargets = append(stagedTargets, name)
 				}
 			}
 
@@ -213,31 +340,172 @@ func (b *Backend) pathRelease(ctx context.Context, req *logical.Request, fields
 			}
 		}
 
+		// The channels are locked only from the promotion until the commit, not for the whole build.
+		if len(cfg.PromotionRules) > 0 {
+			unlockChannels, err := lockChannels(ctx)
+			if err != nil {
+				return err
+			}
+			defer unlockChannels()
+		}
+
+		if err := b.stagePromotedChannels(ctx, storage, cfg, publisherRepository, gitTag, releaseName); err != nil {
+			return fmt.Errorf("unable to promote release channels: %w", err)
+		}
+
+		headRef, err := gitRepo.Head()
+		if err != nil {
+			return fmt.Errorf("error getting git repository head: %w", err)
+		}
+		gitCommit = headRef.Hash().String()
+
+		unlockTufCommit, err := lockTufCommit(ctx)
+		if err != nil {
+			return err
+		}
+		defer unlockTufCommit()
+
+		// The same release might be committed by another task during the build.
+		if _, err := b.checkCommittedReleaseNotPublished(ctx, storage, cfg, releaseName, releaseExists); err != nil {
+			return err
+		}
+
+		if err := tasks_manager.PutTaskCheckpoint(ctx, storage, taskCheckpointCommitting, nil); err != nil {
+			return fmt.Errorf("unable to put task checkpoint: %w", err)
+		}
+
 		logboek.Context(ctx).Default().LogF("Committing TUF repository state\n")
 		b.Logger().Debug("Committing TUF repository state")
 
 		if err := publisherRepository.CommitStaged(ctx); err != nil {
 			return fmt.Errorf("unable to commit new tuf repository state: %w", err)
 		}
+		committed = true
+
+		if err := tasks_manager.PutTaskCheckpoint(ctx, storage, taskCheckpointCommitted, map[string]string{
+			"git_commit":     gitCommit,
+			"staged_targets": strings.Join(stagedTargets, ","),
+		}); err != nil {
+			return fmt.Errorf("unable to put task checkpoint: %w", err)
+		}
+
+		return b.finishReleaseTask(ctx, storage, publisherRepository, gitTag, gitCommit, stagedTargets, audit)
+	}
+}
+
+// finishReleaseTask records the release committed into the TUF repository.
+func (b *Backend) finishReleaseTask(ctx context.Context, storage logical.Storage, publisherRepository publisher.RepositoryInterface, gitTag, gitCommit string, stagedTargets []string, audit *auditEntry) error {
+	if err := storage.Put(ctx, &logical.StorageEntry{Key: storageKeyLastReleaseGitTag, Value: []byte(gitTag)}); err != nil {
+		return fmt.Errorf("unable to put %q into storage: %w", storageKeyLastReleaseGitTag, err)
+	}
+
+	audit.GitTag = gitTag
+	audit.GitCommit = gitCommit
+	if err := putRepositoryAuditEntry(ctx, storage, publisherRepository, audit); err != nil {
+		return fmt.Errorf("unable to record audit entry: %w", err)
+	}
+
+	if err := tasks_manager.PutTaskResult(ctx, storage, map[string]interface{}{
+		"git_commit":     gitCommit,
+		"staged_targets": stagedTargets,
+		"tuf_versions":   audit.TufVersions,
+	}); err != nil {
+		return fmt.Errorf("unable to put task result: %w", err)
+	}
+
+	logboek.Context(ctx).Default().LogF("Task finished\n")
+	b.Logger().Debug("Task finished")
+
+	return nil
+}
 
-		logboek.Context(ctx).Default().LogF("Task finished\n")
-		b.Logger().Debug("Task finished")
+// stagePromotedChannels points the auto-promotable channels at the release by the configured promotion rules,
+// so that the channels are updated in the same TUF commit as the release artifacts.
+// The channels are compared with the committed ones, the channels might be published during the build.
+func (b *Backend) stagePromotedChannels(ctx context.Context, storage logical.Storage, cfg *configuration, publisherRepository publisher.RepositoryInterface, gitTag, releaseName string) error {
+	rules, err := parsePromotionRules(cfg.PromotionRules, cfg.AutoPromotableChannels)
+	if err != nil {
+		return fmt.Errorf("bad promotion rules: %w", err)
+	}
 
+	if len(rules) == 0 {
 		return nil
-	})
+	}
+
+	committedRepository, err := b.Publisher.GetRepository(ctx, storage, cfg.RepositoryOptions())
 	if err != nil {
-		if errors.Is(err, tasks_manager.ErrBusy) {
-			return logical.ErrorResponse("busy"), nil
+		return fmt.Errorf("error getting publisher repository: %w", err)
+	}
+
+	publishedChannels, err := committedRepository.GetChannels(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting published channels: %w", err)
+	}
+
+	promotedChannels := promotedChannelsConfig(rules, releaseName, publishedChannels)
+	if len(promotedChannels.Groups) == 0 {
+		return nil
+	}
+
+	for _, group := range promotedChannels.Groups {
+		for _, channel := range group.Channels {
+			logboek.Context(ctx).Default().LogF("Promoting channel %q of group %q to %q\n", channel.Name, group.Name, channel.Version)
+			b.Logger().Info(fmt.Sprintf("Promoting channel %q of group %q to %q", channel.Name, group.Name, channel.Version))
 		}
+	}
 
-		return nil, err
+	if err := b.Publisher.StageChannelsConfig(ctx, storage, publisherRepository, promotedChannels, publisher.StageChannelsConfigOptions{PromotedFromTag: gitTag}); err != nil {
+		return fmt.Errorf("unable to stage promoted channels: %w", err)
 	}
 
-	return &logical.Response{
-		Data: map[string]interface{}{
-			"task_uuid": taskUUID,
-		},
-	}, nil
+	return nil
+}
+
+// checkReleaseNotPublished refuses to overwrite the published release unless forced, the yanked release is never republished.
+// Returns whether the release is published.
+func (b *Backend) checkReleaseNotPublished(ctx context.Context, publisherRepository publisher.RepositoryInterface, releaseName string, force bool) (bool, *logical.Response, error) {
+	yankedReleases, err := b.Publisher.GetYankedReleases(ctx, publisherRepository)
+	if err != nil {
+		return false, nil, fmt.Errorf("unable to get yanked releases: %w", err)
+	}
+
+	if lo.ContainsBy(yankedReleases, func(yankedRelease publisher.YankedRelease) bool { return yankedRelease.Name == releaseName }) {
+		return false, logical.ErrorResponse("Release %q is yanked and cannot be published again", releaseName), nil
+	}
+
+	existingReleases, err := b.Publisher.GetExistingReleases(ctx, publisherRepository)
+	if err != nil {
+		return false, nil, fmt.Errorf("unable to get existing releases: %w", err)
+	}
+
+	if !lo.Contains(existingReleases, releaseName) {
+		return false, nil, nil
+	}
+
+	if !force {
+		return false, logical.ErrorResponse("Release %q is already published, set %q to rebuild it", releaseName, fieldNameForce), nil
+	}
+
+	return true, nil, nil
+}
+
+// checkCommittedReleaseNotPublished checks the release against the committed repository state, the task repository handle might be stale.
+func (b *Backend) checkCommittedReleaseNotPublished(ctx context.Context, storage logical.Storage, cfg *configuration, releaseName string, force bool) (bool, error) {
+	committedRepository, err := b.Publisher.GetRepository(ctx, storage, cfg.RepositoryOptions())
+	if err != nil {
+		return false, fmt.Errorf("error getting publisher repository: %w", err)
+	}
+
+	releaseExists, errResp, err := b.checkReleaseNotPublished(ctx, committedRepository, releaseName, force)
+	if err != nil {
+		return false, err
+	}
+
+	if errResp != nil {
+		return false, errResp.Error()
+	}
+
+	return releaseExists, nil
 }
 
 func cloneGitRepositoryTag(ctx context.Context, url, gitTag, username, password string) (*git.Repository, error) {
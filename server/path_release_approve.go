@@ -0,0 +1,292 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/samber/lo"
+
+	trdlGit "github.com/werf/trdl/server/pkg/git"
+)
+
+const (
+	storageKeyPrefixPendingRelease = "pending_release/"
+
+	defaultReleaseApprovalTTL = 24 * time.Hour
+)
+
+// pendingRelease is the release request waiting for the approvals quorum to start the build.
+// The git credentials are not stored, the final approver passes them or the stored git credential is used.
+type pendingRelease struct {
+	GitTag            string    `json:"git_tag"`
+	Force             bool      `json:"force"`
+	Initiator         string    `json:"initiator"`
+	InitiatorEntityID string    `json:"initiator_entity_id"`
+	Approvals         []string  `json:"approvals"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+func releaseApprovePath(b *Backend) *framework.Path {
+	return &framework.Path{
+		Pattern:         `release/(?P<git_tag>[^/]+)/approve$`,
+		HelpSynopsis:    "Approve the pending release",
+		HelpDescription: "Approve the release pending approval. The release build is started once the release is approved by the configured number of distinct Vault entities other than the initiator",
+		Fields: map[string]*framework.FieldSchema{
+			fieldNameGitTag: {
+				Type:        framework.TypeString,
+				Description: "Git tag",
+				Required:    true,
+			},
+			fieldNameGitUsername: {
+				Type:        framework.TypeString,
+				Description: "Git username to start the release build with, the stored git credential is used if not set",
+			},
+			fieldNameGitPassword: {
+				Type:        framework.TypeString,
+				Description: "Git password to start the release build with, the stored git credential is used if not set",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Description: "Approve the pending release",
+				Callback:    b.pathReleaseApprove,
+			},
+			logical.ReadOperation: &framework.PathOperation{
+				Description: "Get the pending release, whether it is forced and the entity IDs of its initiator and approvers",
+				Callback:    b.pathReleaseApproveRead,
+			},
+		},
+	}
+}
+
+func (b *Backend) createPendingRelease(ctx context.Context, req *logical.Request, cfg *configuration, release *pendingRelease) (*logical.Response, error) {
+	releaseName := strings.TrimPrefix(release.GitTag, "v")
+
+	lock := locksutil.LockForKey(b.pendingReleaseLocks, releaseName)
+	lock.Lock()
+	defer lock.Unlock()
+
+	existingRelease, err := getPendingRelease(ctx, req.Storage, releaseName)
+	if err != nil {
+		return nil, err
+	}
+
+	if existingRelease != nil && SystemClock.Now().Before(existingRelease.ExpiresAt) {
+		return logical.ErrorResponse("Release %q is already pending approval", releaseName), nil
+	}
+
+	release.ExpiresAt = SystemClock.Now().Add(cfg.releaseApprovalTTL())
+	if err := putPendingRelease(ctx, req.Storage, releaseName, release); err != nil {
+		return nil, err
+	}
+
+	b.Logger().Info(fmt.Sprintf("Release %q requested by %q (entity id %q) is pending approval", releaseName, req.DisplayName, req.EntityID))
+
+	return &logical.Response{Data: pendingReleaseData(release, cfg)}, nil
+}
+
+func (b *Backend) pathReleaseApprove(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	releaseName := strings.TrimPrefix(fields.Get(fieldNameGitTag).(string), "v")
+
+	cfg, err := getConfiguration(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get configuration from storage: %w", err)
+	}
+
+	if cfg == nil {
+		return errorResponseConfigurationNotFound, nil
+	}
+
+	// The concurrent approvals are serialized, so that no approval is lost and the build is started once.
+	lock := locksutil.LockForKey(b.pendingReleaseLocks, releaseName)
+	lock.Lock()
+	defer lock.Unlock()
+
+	release, errResp, err := getActualPendingRelease(ctx, req.Storage, releaseName)
+	if errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	// The approvals are counted by the entities, so the tokens without an entity cannot approve.
+	if req.EntityID == "" {
+		return logical.ErrorResponse("Release %q can only be approved by a Vault entity", releaseName), nil
+	}
+
+	if req.EntityID == release.InitiatorEntityID {
+		return logical.ErrorResponse("Release %q cannot be approved by its initiator", releaseName), nil
+	}
+
+	// The entity may repeat the approval to retry the release start once the quorum is reached, e.g. after the busy response.
+	if !lo.Contains(release.Approvals, req.EntityID) {
+		release.Approvals = append(release.Approvals, req.EntityID)
+		if err := putPendingRelease(ctx, req.Storage, releaseName, release); err != nil {
+			return nil, err
+		}
+
+		b.Logger().Info(fmt.Sprintf("Release %q approved by %q (entity id %q): %d of %d approvals", releaseName, req.DisplayName, req.EntityID, len(release.Approvals), cfg.ReleaseApprovalsRequired))
+	} else if len(release.Approvals) < cfg.ReleaseApprovalsRequired {
+		return logical.ErrorResponse("Release %q is already approved by the entity %q", releaseName, req.EntityID), nil
+	}
+
+	if len(release.Approvals) < cfg.ReleaseApprovalsRequired {
+		return &logical.Response{Data: pendingReleaseData(release, cfg)}, nil
+	}
+
+	gitUsername := fields.Get(fieldNameGitUsername).(string)
+	gitPassword := fields.Get(fieldNameGitPassword).(string)
+	if gitUsername == "" && gitPassword == "" {
+		gitCredentialFromStorage, err := trdlGit.GetGitCredential(ctx, req.Storage)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get git credential from storage: %w", err)
+		}
+
+		if gitCredentialFromStorage != nil {
+			gitUsername = gitCredentialFromStorage.Username
+			gitPassword = gitCredentialFromStorage.Password
+		}
+	}
+
+	publisherRepository, err := b.getTaskPublisherRepository(ctx, req.Storage, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error getting publisher repository: %w", err)
+	}
+
+	// The release might be published or yanked while it was pending approval.
+	releaseExists, errResp, err := b.checkReleaseNotPublished(ctx, publisherRepository, releaseName, release.Force)
+	if err != nil {
+		return nil, err
+	}
+
+	if errResp != nil {
+		if err := deletePendingRelease(ctx, req.Storage, releaseName); err != nil {
+			return nil, err
+		}
+
+		return errResp, nil
+	}
+
+	audit := &auditEntry{
+		Operation:   AuditOperationRelease,
+		DisplayName: release.Initiator,
+		EntityID:    release.InitiatorEntityID,
+		Details:     map[string]string{"approved_by": strings.Join(release.Approvals, ",")},
+	}
+	resp, err := b.runReleaseTask(ctx, req.Storage, cfg, publisherRepository, release.GitTag, gitUsername, gitPassword, releaseExists, audit)
+	if err != nil || resp.IsError() {
+		return resp, err
+	}
+
+	if err := deletePendingRelease(ctx, req.Storage, releaseName); err != nil {
+		return nil, err
+	}
+
+	b.Logger().Info(fmt.Sprintf("Release %q requested by %q is approved by the entities %v", releaseName, release.Initiator, release.Approvals))
+
+	return resp, nil
+}
+
+func (b *Backend) pathReleaseApproveRead(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	releaseName := strings.TrimPrefix(fields.Get(fieldNameGitTag).(string), "v")
+
+	cfg, err := getConfiguration(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get configuration from storage: %w", err)
+	}
+
+	if cfg == nil {
+		return errorResponseConfigurationNotFound, nil
+	}
+
+	release, errResp, err := getActualPendingRelease(ctx, req.Storage, releaseName)
+	if errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	return &logical.Response{Data: pendingReleaseData(release, cfg)}, nil
+}
+
+func pendingReleaseData(release *pendingRelease, cfg *configuration) map[string]interface{} {
+	return map[string]interface{}{
+		"git_tag":             release.GitTag,
+		"force":               release.Force,
+		"initiator":           release.Initiator,
+		"initiator_entity_id": release.InitiatorEntityID,
+		"approvals":           len(release.Approvals),
+		"approved_by":         release.Approvals,
+		"approvals_required":  cfg.ReleaseApprovalsRequired,
+		"expires_at":          release.ExpiresAt.Format(time.RFC3339),
+	}
+}
+
+// getActualPendingRelease returns the pending release, the expired one is removed.
+func getActualPendingRelease(ctx context.Context, storage logical.Storage, releaseName string) (*pendingRelease, *logical.Response, error) {
+	release, err := getPendingRelease(ctx, storage, releaseName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if release == nil {
+		return nil, logical.ErrorResponse("Release %q is not pending approval", releaseName), nil
+	}
+
+	if !SystemClock.Now().Before(release.ExpiresAt) {
+		if err := deletePendingRelease(ctx, storage, releaseName); err != nil {
+			return nil, nil, err
+		}
+
+		return nil, logical.ErrorResponse("Release %q approval has expired", releaseName), nil
+	}
+
+	return release, nil, nil
+}
+
+func getPendingRelease(ctx context.Context, storage logical.Storage, releaseName string) (*pendingRelease, error) {
+	key := path.Join(storageKeyPrefixPendingRelease, releaseName)
+
+	entry, err := storage.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get %q from storage: %w", key, err)
+	}
+
+	if entry == nil {
+		return nil, nil
+	}
+
+	release := new(pendingRelease)
+	if err := entry.DecodeJSON(release); err != nil {
+		return nil, fmt.Errorf("unable to decode %q: %w", key, err)
+	}
+
+	return release, nil
+}
+
+func putPendingRelease(ctx context.Context, storage logical.Storage, releaseName string, release *pendingRelease) error {
+	key := path.Join(storageKeyPrefixPendingRelease, releaseName)
+
+	entry, err := logical.StorageEntryJSON(key, release)
+	if err != nil {
+		return fmt.Errorf("unable to encode %q: %w", key, err)
+	}
+
+	if err := storage.Put(ctx, entry); err != nil {
+		return fmt.Errorf("unable to put %q into storage: %w", key, err)
+	}
+
+	return nil
+}
+
+func deletePendingRelease(ctx context.Context, storage logical.Storage, releaseName string) error {
+	key := path.Join(storageKeyPrefixPendingRelease, releaseName)
+
+	if err := storage.Delete(ctx, key); err != nil {
+		return fmt.Errorf("unable to delete %q from storage: %w", key, err)
+	}
+
+	return nil
+}
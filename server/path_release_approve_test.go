@@ -0,0 +1,188 @@
+package server
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/werf/trdl/server/pkg/publisher"
+)
+
+type PathReleaseApproveCallbackSuite struct {
+	CommonSuite
+}
+
+func (suite *PathReleaseApproveCallbackSuite) SetupTest() {
+	suite.CommonSuite.SetupTest()
+
+	cfg := completeConfiguration()
+	cfg.ReleaseApprovalsRequired = 2
+	err := putConfiguration(suite.ctx, suite.storage, cfg)
+	assert.Nil(suite.T(), err)
+}
+
+func (suite *PathReleaseApproveCallbackSuite) requestRelease(entityID string) *logical.Response {
+	suite.req.Path = "release"
+	suite.req.Operation = logical.CreateOperation
+	suite.req.Data = map[string]interface{}{fieldNameGitTag: fieldGitTagValidValue}
+	suite.req.EntityID = entityID
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+
+	return resp
+}
+
+func (suite *PathReleaseApproveCallbackSuite) approveRelease(entityID string) *logical.Response {
+	suite.req.Path = "release/" + fieldGitTagValidValue + "/approve"
+	suite.req.Operation = logical.UpdateOperation
+	suite.req.Data = nil
+	suite.req.EntityID = entityID
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+
+	return resp
+}
+
+func (suite *PathReleaseApproveCallbackSuite) TestQuorum() {
+	suite.mockedPublisher.On("GetRepository").Return(nil)
+	suite.mockedPublisher.On("GetYankedReleases").Return([]publisher.YankedRelease(nil))
+	suite.mockedPublisher.On("GetExistingReleases").Return([]string{"1.0.0"})
+	suite.mockedTasksManager.On("RunTask").Return("UUID", nil).Once()
+
+	resp := suite.requestRelease("initiator")
+	if assert.NotNil(suite.T(), resp) {
+		assert.Equal(suite.T(), 0, resp.Data["approvals"])
+		assert.Equal(suite.T(), 2, resp.Data["approvals_required"])
+	}
+	suite.mockedTasksManager.AssertNotCalled(suite.T(), "RunTask")
+
+	assert.Equal(suite.T(), logical.ErrorResponse("Release %q is already pending approval", "1.0.1"), suite.requestRelease("other"))
+	assert.Equal(suite.T(), logical.ErrorResponse("Release %q cannot be approved by its initiator", "1.0.1"), suite.approveRelease("initiator"))
+	assert.Equal(suite.T(), logical.ErrorResponse("Release %q can only be approved by a Vault entity", "1.0.1"), suite.approveRelease(""))
+
+	resp = suite.approveRelease("first")
+	if assert.NotNil(suite.T(), resp) {
+		assert.Equal(suite.T(), 1, resp.Data["approvals"])
+		assert.Equal(suite.T(), []string{"first"}, resp.Data["approved_by"])
+		assert.Equal(suite.T(), "initiator", resp.Data["initiator_entity_id"])
+		assert.Equal(suite.T(), false, resp.Data["force"])
+	}
+	suite.mockedTasksManager.AssertNotCalled(suite.T(), "RunTask")
+
+	assert.Equal(suite.T(), logical.ErrorResponse("Release %q is already approved by the entity %q", "1.0.1", "first"), suite.approveRelease("first"))
+
+	resp = suite.approveRelease("second")
+	if assert.NotNil(suite.T(), resp) {
+		assert.Equal(suite.T(), map[string]interface{}{"task_uuid": "UUID"}, resp.Data)
+	}
+
+	assert.Equal(suite.T(), logical.ErrorResponse("Release %q is not pending approval", "1.0.1"), suite.approveRelease("third"))
+
+	suite.mockedPublisher.AssertExpectations(suite.T())
+	suite.mockedTasksManager.AssertExpectations(suite.T())
+}
+
+func (suite *PathReleaseApproveCallbackSuite) TestExpired() {
+	err := putPendingRelease(suite.ctx, suite.storage, "1.0.1", &pendingRelease{
+		GitTag:            fieldGitTagValidValue,
+		InitiatorEntityID: "initiator",
+		ExpiresAt:         time.Now().Add(-time.Minute),
+	})
+	assert.Nil(suite.T(), err)
+
+	assert.Equal(suite.T(), logical.ErrorResponse("Release %q approval has expired", "1.0.1"), suite.approveRelease("first"))
+
+	release, err := getPendingRelease(suite.ctx, suite.storage, "1.0.1")
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), release)
+
+	suite.mockedTasksManager.AssertNotCalled(suite.T(), "RunTask")
+}
+
+func (suite *PathReleaseApproveCallbackSuite) TestPublishedWhilePending() {
+	suite.mockedPublisher.On("GetRepository").Return(nil)
+	suite.mockedPublisher.On("GetYankedReleases").Return([]publisher.YankedRelease(nil))
+	suite.mockedPublisher.On("GetExistingReleases").Return([]string{"1.0.0"}).Once()
+
+	suite.requestRelease("initiator")
+	suite.approveRelease("first")
+
+	suite.mockedPublisher.On("GetExistingReleases").Return([]string{"1.0.0", "1.0.1"}).Once()
+
+	assert.Equal(suite.T(), logical.ErrorResponse("Release %q is already published, set %q to rebuild it", "1.0.1", fieldNameForce), suite.approveRelease("second"))
+
+	release, err := getPendingRelease(suite.ctx, suite.storage, "1.0.1")
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), release)
+
+	suite.mockedTasksManager.AssertNotCalled(suite.T(), "RunTask")
+}
+
+func (suite *PathReleaseApproveCallbackSuite) TestConcurrentApprovals() {
+	suite.mockedPublisher.On("GetRepository").Return(nil)
+	suite.mockedPublisher.On("GetYankedReleases").Return([]publisher.YankedRelease(nil))
+	suite.mockedPublisher.On("GetExistingReleases").Return([]string{"1.0.0"})
+	suite.mockedTasksManager.On("RunTask").Return("UUID", nil).Once()
+
+	suite.requestRelease("initiator")
+
+	var wg sync.WaitGroup
+	responses := make([]*logical.Response, 2)
+	for i, entityID := range []string{"first", "second"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			resp, err := suite.backend.HandleRequest(suite.ctx, &logical.Request{
+				Path:      "release/" + fieldGitTagValidValue + "/approve",
+				Operation: logical.UpdateOperation,
+				Storage:   suite.storage,
+				EntityID:  entityID,
+			})
+			assert.Nil(suite.T(), err)
+			responses[i] = resp
+		}()
+	}
+	wg.Wait()
+
+	for _, resp := range responses {
+		if assert.NotNil(suite.T(), resp) {
+			assert.False(suite.T(), resp.IsError())
+		}
+	}
+
+	suite.mockedTasksManager.AssertNumberOfCalls(suite.T(), "RunTask", 1)
+}
+
+func (suite *PathReleaseApproveCallbackSuite) TestGitCredentialsNotStored() {
+	suite.mockedPublisher.On("GetRepository").Return(nil)
+	suite.mockedPublisher.On("GetYankedReleases").Return([]publisher.YankedRelease(nil))
+	suite.mockedPublisher.On("GetExistingReleases").Return([]string{"1.0.0"})
+
+	suite.req.Path = "release"
+	suite.req.Operation = logical.CreateOperation
+	suite.req.Data = map[string]interface{}{fieldNameGitTag: fieldGitTagValidValue, fieldNameGitUsername: "user", fieldNameGitPassword: "secret"}
+	suite.req.EntityID = "initiator"
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	if assert.NotNil(suite.T(), resp) {
+		assert.Len(suite.T(), resp.Warnings, 1)
+	}
+
+	entry, err := suite.storage.Get(suite.ctx, storageKeyPrefixPendingRelease+"1.0.1")
+	assert.Nil(suite.T(), err)
+	if assert.NotNil(suite.T(), entry) {
+		assert.NotContains(suite.T(), string(entry.Value), "secret")
+	}
+}
+
+func TestPathReleaseApproveCallbackSuite(t *testing.T) {
+	suite.Run(t, new(PathReleaseApproveCallbackSuite))
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/werf/trdl/server/pkg/publisher"
 	"github.com/werf/trdl/server/pkg/tasks_manager"
 )
 
@@ -43,6 +44,8 @@ func (suite *PathReleaseCallbackSuite) TestBasic() {
 	suite.req.Data = map[string]interface{}{fieldNameGitTag: fieldGitTagValidValue}
 
 	suite.mockedPublisher.On("GetRepository").Return(nil)
+	suite.mockedPublisher.On("GetYankedReleases").Return([]publisher.YankedRelease(nil))
+	suite.mockedPublisher.On("GetExistingReleases").Return([]string{"1.0.0"})
 	suite.mockedTasksManager.On("RunTask").Return("UUID", nil)
 
 	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
@@ -71,6 +74,8 @@ func (suite *PathReleaseCallbackSuite) TestBusy() {
 	suite.req.Data = map[string]interface{}{fieldNameGitTag: fieldGitTagValidValue}
 
 	suite.mockedPublisher.On("GetRepository").Return(nil)
+	suite.mockedPublisher.On("GetYankedReleases").Return([]publisher.YankedRelease(nil))
+	suite.mockedPublisher.On("GetExistingReleases").Return([]string{"1.0.0"})
 	suite.mockedTasksManager.On("RunTask").Return("", tasks_manager.ErrBusy)
 
 	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
@@ -81,6 +86,94 @@ func (suite *PathReleaseCallbackSuite) TestBusy() {
 	suite.mockedTasksManager.AssertExpectations(suite.T())
 }
 
+func (suite *PathReleaseCallbackSuite) TestReleaseExists() {
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+
+	suite.req.Data = map[string]interface{}{fieldNameGitTag: fieldGitTagValidValue}
+
+	suite.mockedPublisher.On("GetRepository").Return(nil)
+	suite.mockedPublisher.On("GetYankedReleases").Return([]publisher.YankedRelease(nil))
+	suite.mockedPublisher.On("GetExistingReleases").Return([]string{"1.0.1"})
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), logical.ErrorResponse("Release %q is already published, set %q to rebuild it", "1.0.1", fieldNameForce), resp)
+
+	suite.mockedPublisher.AssertExpectations(suite.T())
+	suite.mockedTasksManager.AssertNotCalled(suite.T(), "RunTask")
+}
+
+func (suite *PathReleaseCallbackSuite) TestReleaseExists_Force() {
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+
+	suite.req.Data = map[string]interface{}{fieldNameGitTag: fieldGitTagValidValue, fieldNameForce: true}
+
+	suite.mockedPublisher.On("GetRepository").Return(nil)
+	suite.mockedPublisher.On("GetYankedReleases").Return([]publisher.YankedRelease(nil))
+	suite.mockedPublisher.On("GetExistingReleases").Return([]string{"1.0.1"})
+	suite.mockedTasksManager.On("RunTask").Return("UUID", nil)
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	if assert.NotNil(suite.T(), resp) {
+		assert.Equal(suite.T(), map[string]interface{}{"task_uuid": "UUID"}, resp.Data)
+	}
+
+	suite.mockedPublisher.AssertExpectations(suite.T())
+	suite.mockedTasksManager.AssertExpectations(suite.T())
+}
+
+func (suite *PathReleaseCallbackSuite) TestReleaseYanked() {
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+
+	suite.req.Data = map[string]interface{}{fieldNameGitTag: fieldGitTagValidValue, fieldNameForce: true}
+
+	suite.mockedPublisher.On("GetRepository").Return(nil)
+	suite.mockedPublisher.On("GetYankedReleases").Return([]publisher.YankedRelease{{Name: "1.0.1", Reason: "broken"}})
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), logical.ErrorResponse("Release %q is yanked and cannot be published again", "1.0.1"), resp)
+
+	suite.mockedPublisher.AssertExpectations(suite.T())
+	suite.mockedTasksManager.AssertNotCalled(suite.T(), "RunTask")
+}
+
+func (suite *PathReleaseCallbackSuite) TestReleaseTask_PublishedSinceRequest() {
+	repository := &MockedRepository{}
+	repository.On("DiscardStaged").Return(nil)
+
+	suite.mockedPublisher.On("GetRepository").Return(nil)
+	suite.mockedPublisher.On("GetYankedReleases").Return([]publisher.YankedRelease(nil))
+	suite.mockedPublisher.On("GetExistingReleases").Return([]string{"1.0.1"})
+
+	audit := &auditEntry{Operation: AuditOperationRelease}
+	err := suite.backend.releaseTask(completeConfiguration(), repository, fieldGitTagValidValue, "", "", false, audit)(suite.taskContext(), suite.storage)
+	assert.EqualError(suite.T(), err, `Release "1.0.1" is already published, set "force" to rebuild it`)
+
+	repository.AssertExpectations(suite.T())
+}
+
+func (suite *PathReleaseCallbackSuite) TestCheckCommittedReleaseNotPublished() {
+	suite.mockedPublisher.On("GetRepository").Return(nil)
+	suite.mockedPublisher.On("GetYankedReleases").Return([]publisher.YankedRelease{{Name: "1.0.2", Reason: "broken"}})
+	suite.mockedPublisher.On("GetExistingReleases").Return([]string{"1.0.1", "1.0.2"})
+
+	releaseExists, err := suite.backend.checkCommittedReleaseNotPublished(suite.ctx, suite.storage, completeConfiguration(), "1.0.1", true)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), releaseExists)
+
+	releaseExists, err = suite.backend.checkCommittedReleaseNotPublished(suite.ctx, suite.storage, completeConfiguration(), "1.0.3", false)
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), releaseExists)
+
+	_, err = suite.backend.checkCommittedReleaseNotPublished(suite.ctx, suite.storage, completeConfiguration(), "1.0.2", true)
+	assert.EqualError(suite.T(), err, `Release "1.0.2" is yanked and cannot be published again`)
+}
+
 func TestBackendPathReleaseCallback(t *testing.T) {
 	suite.Run(t, new(PathReleaseCallbackSuite))
 }
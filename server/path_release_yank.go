@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+
+	"github.com/werf/logboek"
+	"github.com/werf/trdl/server/pkg/publisher"
+	"github.com/werf/trdl/server/pkg/tasks_manager"
+)
+
+const fieldNameYankReason = "reason"
+
+func releaseYankPath(b *Backend) *framework.Path {
+	return &framework.Path{
+		Pattern:         `release/(?P<version>[^/]+)/yank$`,
+		HelpSynopsis:    "Yank the published release",
+		HelpDescription: "Withdraw the broken release: the release targets and signatures are removed from the TUF repository and the release is published as yanked with the reason. The release used by any channel cannot be yanked, the channels cannot be pointed at the yanked release later. The release is yanked in a task",
+		Fields: map[string]*framework.FieldSchema{
+			fieldNameReleaseVersion: {
+				Type:        framework.TypeString,
+				Description: "The release version",
+				Required:    true,
+			},
+			fieldNameYankReason: {
+				Type:        framework.TypeString,
+				Description: "The reason the release is yanked, it is shown to the clients",
+				Required:    true,
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Description: "Yank the published release",
+				Callback:    b.pathReleaseYank,
+			},
+		},
+	}
+}
+
+func (b *Backend) pathReleaseYank(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	// The version is a part of the path, so only the reason is checked.
+	reason := fields.Get(fieldNameYankReason).(string)
+	if reason == "" {
+		return logical.ErrorResponse("Required field %q must be set", fieldNameYankReason), nil
+	}
+
+	releaseName := strings.TrimPrefix(fields.Get(fieldNameReleaseVersion).(string), "v")
+
+	cfg, err := getConfiguration(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get configuration from storage: %w", err)
+	}
+
+	if cfg == nil {
+		return errorResponseConfigurationNotFound, nil
+	}
+
+	audit := newAuditEntry(req, AuditOperationYank)
+	audit.Details = map[string]string{"release": releaseName, "reason": reason}
+
+	// The yank holds the channels, so that no channel is pointed at the release by the publication staged concurrently,
+	// and the TUF repository commit.
+	taskUUID, err := b.TasksManager.RunTask(ctx, req.Storage, b.yankReleaseTask(cfg, releaseName, reason, audit), tasks_manager.TaskOptions{
+		Operation:         string(AuditOperationYank),
+		Params:            map[string]string{"release": releaseName},
+		Initiator:         audit.DisplayName,
+		InitiatorEntityID: audit.EntityID,
+		Resources:         []string{taskResourceChannels, taskResourceTufCommit},
+	})
+	if err != nil {
+		if errors.Is(err, tasks_manager.ErrBusy) {
+			return logical.ErrorResponse("busy"), nil
+		}
+
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"task_uuid": taskUUID,
+		},
+	}, nil
+}
+
+func (b *Backend) yankReleaseTask(cfg *configuration, releaseName, reason string, audit *auditEntry) func(context.Context, logical.Storage) error {
+	return func(ctx context.Context, storage logical.Storage) error {
+		logboek.Context(ctx).Default().LogF("Yanking release %q\n", releaseName)
+		b.Logger().Debug(fmt.Sprintf("Yanking release %q", releaseName))
+
+		publisherRepository, err := b.Publisher.GetRepository(ctx, storage, cfg.RepositoryOptions())
+		if err != nil {
+			return fmt.Errorf("error getting publisher repository: %w", err)
+		}
+
+		if err := b.Publisher.YankRelease(ctx, storage, publisherRepository, releaseName, publisher.YankReleaseOptions{Reason: reason}); err != nil {
+			return fmt.Errorf("unable to yank release %q: %w", releaseName, err)
+		}
+
+		if err := putRepositoryAuditEntry(ctx, storage, publisherRepository, audit); err != nil {
+			return fmt.Errorf("unable to record audit entry: %w", err)
+		}
+
+		logboek.Context(ctx).Default().LogF("Task finished\n")
+		b.Logger().Debug("Task finished")
+
+		return nil
+	}
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/werf/trdl/server/pkg/publisher"
+	"github.com/werf/trdl/server/pkg/tasks_manager"
+)
+
+type PathReleaseYankCallbacksSuite struct {
+	CommonSuite
+}
+
+func (suite *PathReleaseYankCallbacksSuite) SetupTest() {
+	suite.CommonSuite.SetupTest()
+	suite.req.Path = "release/v1.0.0/yank"
+	suite.req.Operation = logical.UpdateOperation
+}
+
+func (suite *PathReleaseYankCallbacksSuite) TestRequiredFields() {
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), logical.ErrorResponse("Required field %q must be set", fieldNameYankReason), resp)
+}
+
+func (suite *PathReleaseYankCallbacksSuite) TestConfigurationNotFound() {
+	suite.req.Data = map[string]interface{}{fieldNameYankReason: "broken"}
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), errorResponseConfigurationNotFound, resp)
+}
+
+func (suite *PathReleaseYankCallbacksSuite) TestYank() {
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+
+	suite.mockedTasksManager.On("RunTask").Return("UUID", nil)
+	suite.req.Data = map[string]interface{}{fieldNameYankReason: "broken"}
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	if assert.NotNil(suite.T(), resp) {
+		assert.Equal(suite.T(), map[string]interface{}{"task_uuid": "UUID"}, resp.Data)
+	}
+
+	suite.mockedTasksManager.AssertExpectations(suite.T())
+}
+
+func (suite *PathReleaseYankCallbacksSuite) TestYank_Busy() {
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+
+	suite.mockedTasksManager.IsBusy = true
+	suite.mockedTasksManager.On("RunTask").Return("", tasks_manager.ErrBusy)
+	suite.req.Data = map[string]interface{}{fieldNameYankReason: "broken"}
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), logical.ErrorResponse("busy"), resp)
+}
+
+func (suite *PathReleaseYankCallbacksSuite) TestYankTask() {
+	repository := &MockedRepository{}
+	repository.On("RoleStatuses").Return([]publisher.RoleStatus{{Role: "targets", Version: 3}, {Role: "yanked", Version: 1}})
+
+	suite.mockedPublisher.On("GetRepository").Return(repository)
+	suite.mockedPublisher.On("YankRelease", "1.0.0", "broken").Return(nil)
+	suite.req.DisplayName = "token-admin"
+	suite.req.EntityID = "admin"
+
+	audit := newAuditEntry(suite.req, AuditOperationYank)
+	audit.Details = map[string]string{"release": "1.0.0", "reason": "broken"}
+	err := suite.backend.yankReleaseTask(completeConfiguration(), "1.0.0", "broken", audit)(suite.taskContext(), suite.storage)
+	assert.Nil(suite.T(), err)
+
+	ids, err := suite.storage.List(suite.ctx, storageKeyPrefixAudit)
+	assert.Nil(suite.T(), err)
+	if assert.Len(suite.T(), ids, 1) {
+		entry, err := getAuditEntry(suite.ctx, suite.storage, ids[0])
+		assert.Nil(suite.T(), err)
+		if assert.NotNil(suite.T(), entry) {
+			assert.Equal(suite.T(), AuditOperationYank, entry.Operation)
+			assert.Equal(suite.T(), "token-admin", entry.DisplayName)
+			assert.Equal(suite.T(), "admin", entry.EntityID)
+			assert.Equal(suite.T(), map[string]int64{"targets": 3, "yanked": 1}, entry.TufVersions)
+			assert.Equal(suite.T(), map[string]string{"release": "1.0.0", "reason": "broken"}, entry.Details)
+		}
+	}
+
+	suite.mockedPublisher.AssertExpectations(suite.T())
+}
+
+func (suite *PathReleaseYankCallbacksSuite) TestYankTask_UsedByChannels() {
+	suite.mockedPublisher.On("GetRepository").Return(nil)
+	suite.mockedPublisher.On("YankRelease", "1.0.0", "broken").Return(errors.New(`release "1.0.0" is used by the channels [1/stable]`))
+
+	err := suite.backend.yankReleaseTask(completeConfiguration(), "1.0.0", "broken", newAuditEntry(suite.req, AuditOperationYank))(suite.taskContext(), suite.storage)
+	assert.EqualError(suite.T(), err, `unable to yank release "1.0.0": release "1.0.0" is used by the channels [1/stable]`)
+
+	suite.mockedPublisher.AssertExpectations(suite.T())
+}
+
+func TestBackendPathReleaseYankCallbacks(t *testing.T) {
+	suite.Run(t, new(PathReleaseYankCallbacksSuite))
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const fieldNameReleaseVersion = "version"
+
+func releasesPaths(b *Backend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern:         `releases/?$`,
+			HelpSynopsis:    "List the published releases",
+			HelpDescription: "List the versions of the releases published into the TUF repository",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Description: "List the published releases",
+					Callback:    b.pathReleasesList,
+				},
+			},
+		},
+		{
+			Pattern:         `releases/(?P<version>[^/]+)$`,
+			HelpSynopsis:    "Get the published release",
+			HelpDescription: "Get the targets of the published release: the os and the arch, the size, the hashes and the PGP signature target",
+			Fields: map[string]*framework.FieldSchema{
+				fieldNameReleaseVersion: {
+					Type:        framework.TypeString,
+					Description: "The release version",
+					Required:    true,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Description: "Get the published release",
+					Callback:    b.pathReleasesRead,
+				},
+			},
+		},
+	}
+}
+
+func (b *Backend) pathReleasesList(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	publisherRepository, errResp, err := b.getConfiguredRepository(ctx, req)
+	if errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	releases, err := b.Publisher.GetExistingReleases(ctx, publisherRepository)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get existing releases: %w", err)
+	}
+
+	sortReleases(releases)
+
+	return logical.ListResponse(releases), nil
+}
+
+func (b *Backend) pathReleasesRead(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	releaseName := strings.TrimPrefix(fields.Get(fieldNameReleaseVersion).(string), "v")
+
+	publisherRepository, errResp, err := b.getConfiguredRepository(ctx, req)
+	if errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	releaseTargets, err := b.Publisher.GetReleaseTargets(ctx, publisherRepository, releaseName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get release %q targets: %w", releaseName, err)
+	}
+
+	if len(releaseTargets) == 0 {
+		return logical.ErrorResponse("Release %q not found", releaseName), nil
+	}
+
+	var targetsData []map[string]interface{}
+	for _, releaseTarget := range releaseTargets {
+		targetsData = append(targetsData, map[string]interface{}{
+			"name":             releaseTarget.Name,
+			"os":               releaseTarget.OS,
+			"arch":             releaseTarget.Arch,
+			"length":           releaseTarget.Length,
+			"hashes":           releaseTarget.Hashes,
+			"signature_target": releaseTarget.SignatureTarget,
+		})
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			fieldNameReleaseVersion: releaseName,
+			"targets":               targetsData,
+		},
+	}, nil
+}
+
+// sortReleases sorts the semver releases in ascending order, the other ones go first in lexical order.
+func sortReleases(releases []string) {
+	sort.SliceStable(releases, func(i, j int) bool {
+		iVersion, iErr := semver.NewVersion(releases[i])
+		jVersion, jErr := semver.NewVersion(releases[j])
+
+		switch {
+		case iErr != nil && jErr != nil:
+			return releases[i] < releases[j]
+		case iErr != nil:
+			return true
+		case jErr != nil:
+			return false
+		default:
+			return iVersion.LessThan(jVersion)
+		}
+	})
+}
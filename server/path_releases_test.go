@@ -0,0 +1,100 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/werf/trdl/server/pkg/publisher"
+)
+
+type PathReleasesCallbacksSuite struct {
+	CommonSuite
+}
+
+func (suite *PathReleasesCallbacksSuite) TestList() {
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+
+	suite.mockedPublisher.On("GetRepository").Return(nil)
+	suite.mockedPublisher.On("GetExistingReleases").Return([]string{"1.10.0", "1.2.0", "0.1.0"})
+
+	suite.req.Path = "releases/"
+	suite.req.Operation = logical.ListOperation
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), logical.ListResponse([]string{"0.1.0", "1.2.0", "1.10.0"}), resp)
+
+	suite.mockedPublisher.AssertExpectations(suite.T())
+}
+
+func (suite *PathReleasesCallbacksSuite) TestList_ConfigurationNotFound() {
+	suite.req.Path = "releases/"
+	suite.req.Operation = logical.ListOperation
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), errorResponseConfigurationNotFound, resp)
+}
+
+func (suite *PathReleasesCallbacksSuite) TestRead() {
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+
+	suite.mockedPublisher.On("GetRepository").Return(nil)
+	suite.mockedPublisher.On("GetReleaseTargets", "1.0.0").Return([]publisher.ReleaseTarget{
+		{
+			Name:            "linux-amd64/bin/app",
+			OS:              "linux",
+			Arch:            "amd64",
+			Length:          3,
+			Hashes:          map[string]string{"sha512": "abc"},
+			SignatureTarget: "signatures/1.0.0/linux-amd64/bin/app.sig",
+		},
+	})
+
+	suite.req.Path = "releases/v1.0.0"
+	suite.req.Operation = logical.ReadOperation
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	if assert.NotNil(suite.T(), resp) {
+		assert.Equal(suite.T(), map[string]interface{}{
+			fieldNameReleaseVersion: "1.0.0",
+			"targets": []map[string]interface{}{
+				{
+					"name":             "linux-amd64/bin/app",
+					"os":               "linux",
+					"arch":             "amd64",
+					"length":           int64(3),
+					"hashes":           map[string]string{"sha512": "abc"},
+					"signature_target": "signatures/1.0.0/linux-amd64/bin/app.sig",
+				},
+			},
+		}, resp.Data)
+	}
+
+	suite.mockedPublisher.AssertExpectations(suite.T())
+}
+
+func (suite *PathReleasesCallbacksSuite) TestRead_NotFound() {
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+
+	suite.mockedPublisher.On("GetRepository").Return(nil)
+	suite.mockedPublisher.On("GetReleaseTargets", "2.0.0").Return([]publisher.ReleaseTarget(nil))
+
+	suite.req.Path = "releases/2.0.0"
+	suite.req.Operation = logical.ReadOperation
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), logical.ErrorResponse("Release %q not found", "2.0.0"), resp)
+}
+
+func TestBackendPathReleasesCallbacks(t *testing.T) {
+	suite.Run(t, new(PathReleasesCallbacksSuite))
+}
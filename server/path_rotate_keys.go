@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+
+	"github.com/werf/logboek"
+	"github.com/werf/trdl/server/pkg/publisher"
+	"github.com/werf/trdl/server/pkg/tasks_manager"
+	"github.com/werf/trdl/server/pkg/util"
+)
+
+const (
+	fieldNameRotateKeysRoles  = "roles"
+	fieldNameRotateKeysReason = "reason"
+)
+
+func rotateKeysPath(b *Backend) *framework.Path {
+	return &framework.Path{
+		Pattern:         `rotate_keys$`,
+		HelpSynopsis:    "Rotate the TUF roles keys right away",
+		HelpDescription: "Replace the keys of the TUF roles with the new ones and publish the new root in a task, e.g. when a key is compromised. The old keys are revoked, the clients reject the metadata signed with them after the next update. The revoked key IDs are in the task result",
+		Fields: map[string]*framework.FieldSchema{
+			fieldNameRotateKeysRoles: {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "The TUF roles to rotate the keys of: root, targets, snapshot or timestamp",
+				Required:    true,
+			},
+			fieldNameRotateKeysReason: {
+				Type:        framework.TypeString,
+				Description: "The reason of the rotation to log along with the revoked keys",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Description: "Rotate the TUF roles keys",
+				Callback:    b.pathRotateKeys,
+			},
+		},
+	}
+}
+
+func (b *Backend) pathRotateKeys(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	if errResp := util.CheckRequiredFields(req, fields); errResp != nil {
+		return errResp, nil
+	}
+
+	roles := fields.Get(fieldNameRotateKeysRoles).([]string)
+	if len(roles) == 0 {
+		return logical.ErrorResponse("Field %q must not be empty", fieldNameRotateKeysRoles), nil
+	}
+
+	if err := publisher.ValidateTopLevelRoles(roles); err != nil {
+		return logical.ErrorResponse("Unable to rotate keys: %s", err), nil
+	}
+
+	cfg, err := getConfiguration(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get configuration from storage: %w", err)
+	}
+
+	if cfg == nil {
+		return errorResponseConfigurationNotFound, nil
+	}
+
+	reason := fields.Get(fieldNameRotateKeysReason).(string)
+	audit := newAuditEntry(req, AuditOperationRotateKeys)
+	audit.Details = map[string]string{"roles": strings.Join(roles, ","), "reason": reason}
+
+	// The rotation commits the new root, so it holds the TUF repository commit for the whole task.
+	taskUUID, err := b.TasksManager.RunTask(ctx, req.Storage, b.rotateKeysTask(cfg, roles, reason, audit), tasks_manager.TaskOptions{
+		Operation:         string(AuditOperationRotateKeys),
+		Params:            map[string]string{"roles": strings.Join(roles, ",")},
+		Initiator:         audit.DisplayName,
+		InitiatorEntityID: audit.EntityID,
+		Resources:         []string{taskResourceTufCommit},
+	})
+	if err != nil {
+		if errors.Is(err, tasks_manager.ErrBusy) {
+			return logical.ErrorResponse("busy"), nil
+		}
+
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"task_uuid": taskUUID,
+		},
+	}, nil
+}
+
+func (b *Backend) rotateKeysTask(cfg *configuration, roles []string, reason string, audit *auditEntry) func(context.Context, logical.Storage) error {
+	return func(ctx context.Context, storage logical.Storage) error {
+		logboek.Context(ctx).Default().LogF("Rotating the keys of the TUF roles %v\n", roles)
+		b.Logger().Debug(fmt.Sprintf("Rotating the keys of the TUF roles %v", roles))
+
+		publisherRepository, err := b.Publisher.GetRepository(ctx, storage, cfg.RepositoryOptions())
+		if err != nil {
+			return fmt.Errorf("error getting publisher repository: %w", err)
+		}
+
+		revokedKeyIDs, err := b.Publisher.RotateRoleKeys(ctx, storage, publisherRepository, roles, publisher.RotateRoleKeysOptions{Reason: reason})
+		if err != nil {
+			return fmt.Errorf("unable to rotate keys: %w", err)
+		}
+
+		if err := putRepositoryAuditEntry(ctx, storage, publisherRepository, audit); err != nil {
+			return fmt.Errorf("unable to record audit entry: %w", err)
+		}
+
+		if err := tasks_manager.PutTaskResult(ctx, storage, map[string]interface{}{"revoked_key_ids": revokedKeyIDs}); err != nil {
+			return fmt.Errorf("unable to put task result: %w", err)
+		}
+
+		logboek.Context(ctx).Default().LogF("Task finished\n")
+		b.Logger().Debug("Task finished")
+
+		return nil
+	}
+}
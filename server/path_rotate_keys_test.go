@@ -0,0 +1,51 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type PathRotateKeysCallbacksSuite struct {
+	CommonSuite
+}
+
+func (suite *PathRotateKeysCallbacksSuite) TestRotateKeys_RequiredFields() {
+	suite.req.Path = "rotate_keys"
+	suite.req.Operation = logical.UpdateOperation
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), logical.ErrorResponse("Required field %q must be set", fieldNameRotateKeysRoles), resp)
+}
+
+func (suite *PathRotateKeysCallbacksSuite) TestRotateKeys_ConfigurationNotFound() {
+	suite.req.Path = "rotate_keys"
+	suite.req.Operation = logical.UpdateOperation
+	suite.req.Data = map[string]interface{}{
+		fieldNameRotateKeysRoles:  "targets,snapshot",
+		fieldNameRotateKeysReason: "compromised",
+	}
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), errorResponseConfigurationNotFound, resp)
+}
+
+func (suite *PathRotateKeysCallbacksSuite) TestRotateKeys_UnexpectedRole() {
+	suite.req.Path = "rotate_keys"
+	suite.req.Operation = logical.UpdateOperation
+	suite.req.Data = map[string]interface{}{
+		fieldNameRotateKeysRoles: "targets,releases",
+	}
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), logical.ErrorResponse("Unable to rotate keys: %s", `unexpected role "releases": expected one of [root targets snapshot timestamp]`), resp)
+}
+
+func TestBackendPathRotateKeysCallbacks(t *testing.T) {
+	suite.Run(t, new(PathRotateKeysCallbacksSuite))
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+
+	"github.com/werf/trdl/server/pkg/publisher"
+)
+
+const (
+	fieldNameStatusExpiringThreshold = "expiring_threshold"
+
+	storageKeyLastReleaseGitTag = "last_release_git_tag"
+)
+
+func statusPath(b *Backend) *framework.Path {
+	return &framework.Path{
+		Pattern:         `status$`,
+		HelpSynopsis:    "Get the TUF repository status",
+		HelpDescription: "Get the versions, the expiration dates and the key IDs of the TUF roles, the last published git commit, the last released git tag and the last periodic run. The roles expiring within the threshold are flagged, so that the status can be scraped by the monitoring",
+		Fields: map[string]*framework.FieldSchema{
+			fieldNameStatusExpiringThreshold: {
+				Type:        framework.TypeDurationSecond,
+				Description: "The period to flag the roles expiring within",
+				Default:     "72h",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Description: "Get the TUF repository status",
+				Callback:    b.pathStatusRead,
+			},
+		},
+	}
+}
+
+func (b *Backend) pathStatusRead(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	expiringThreshold := time.Duration(fields.Get(fieldNameStatusExpiringThreshold).(int)) * time.Second
+	if expiringThreshold < 0 {
+		return logical.ErrorResponse("Field %q must not be negative", fieldNameStatusExpiringThreshold), nil
+	}
+
+	publisherRepository, errResp, err := b.getConfiguredRepository(ctx, req)
+	if errors.Is(err, publisher.ErrUninitializedRepositoryKeys) {
+		return logical.ErrorResponse("Repository is not initialized"), nil
+	}
+	if errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	statuses, err := publisherRepository.RoleStatuses()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get TUF roles statuses: %w", err)
+	}
+
+	lastPublishedGitCommit, err := getStorageString(ctx, req.Storage, storageKeyLastPublishedGitCommit)
+	if err != nil {
+		return nil, err
+	}
+
+	lastReleaseGitTag, err := getStorageString(ctx, req.Storage, storageKeyLastReleaseGitTag)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastPeriodicRun string
+	if lastRunTimestamp, err := getStorageString(ctx, req.Storage, lastPeriodicRunTimestampKey); err != nil {
+		return nil, err
+	} else if seconds, err := strconv.ParseInt(lastRunTimestamp, 10, 64); err == nil {
+		lastPeriodicRun = time.Unix(seconds, 0).UTC().Format(time.RFC3339)
+	}
+
+	rolesData, expiringRoles := rolesStatusData(statuses, SystemClock.Now(), expiringThreshold)
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"roles":                          rolesData,
+			"expiring_roles":                 expiringRoles,
+			storageKeyLastPublishedGitCommit: lastPublishedGitCommit,
+			storageKeyLastReleaseGitTag:      lastReleaseGitTag,
+			"last_periodic_run":              lastPeriodicRun,
+		},
+	}, nil
+}
+
+// rolesStatusData returns the roles statuses and the names of the roles expiring within the threshold.
+func rolesStatusData(statuses []publisher.RoleStatus, now time.Time, expiringThreshold time.Duration) ([]map[string]interface{}, []string) {
+	rolesData := []map[string]interface{}{}
+	expiringRoles := []string{}
+	for _, status := range statuses {
+		expiring := status.Expires.Sub(now) <= expiringThreshold
+		if expiring {
+			expiringRoles = append(expiringRoles, status.Role)
+		}
+
+		rolesData = append(rolesData, map[string]interface{}{
+			"role":      status.Role,
+			"version":   status.Version,
+			"expires":   status.Expires.UTC().Format(time.RFC3339),
+			"key_ids":   status.KeyIDs,
+			"threshold": status.Threshold,
+			"expiring":  expiring,
+		})
+	}
+
+	return rolesData, expiringRoles
+}
+
+func getStorageString(ctx context.Context, storage logical.Storage, key string) (string, error) {
+	entry, err := storage.Get(ctx, key)
+	if err != nil {
+		return "", fmt.Errorf("unable to get %q from storage: %w", key, err)
+	}
+
+	if entry == nil {
+		return "", nil
+	}
+
+	return string(entry.Value), nil
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/werf/trdl/server/pkg/publisher"
+)
+
+type PathStatusCallbacksSuite struct {
+	CommonSuite
+}
+
+func (suite *PathStatusCallbacksSuite) TestRead_ConfigurationNotFound() {
+	suite.req.Path = "status"
+	suite.req.Operation = logical.ReadOperation
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), errorResponseConfigurationNotFound, resp)
+}
+
+func TestBackendPathStatusCallbacks(t *testing.T) {
+	suite.Run(t, new(PathStatusCallbacksSuite))
+}
+
+func TestRolesStatusData(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	statuses := []publisher.RoleStatus{
+		{Role: "root", Version: 2, Expires: now.AddDate(1, 0, 0), KeyIDs: []string{"root-key"}, Threshold: 1},
+		{Role: "timestamp", Version: 10, Expires: now.Add(time.Hour), KeyIDs: []string{"timestamp-key"}, Threshold: 1},
+	}
+
+	rolesData, expiringRoles := rolesStatusData(statuses, now, 24*time.Hour)
+	assert.Equal(t, []string{"timestamp"}, expiringRoles)
+	assert.Equal(t, []map[string]interface{}{
+		{"role": "root", "version": int64(2), "expires": "2025-01-01T00:00:00Z", "key_ids": []string{"root-key"}, "threshold": 1, "expiring": false},
+		{"role": "timestamp", "version": int64(10), "expires": "2024-01-01T01:00:00Z", "key_ids": []string{"timestamp-key"}, "threshold": 1, "expiring": true},
+	}, rolesData)
+}
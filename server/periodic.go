@@ -79,7 +79,7 @@ func (b *Backend) Periodic(ctx context.Context, req *logical.Request) error {
 			b.Logger().Info("Periodic task succeeded")
 		}
 		return err
-	})
+	}, tasks_manager.TaskOptions{Operation: string(AuditOperationRotateKeys), Resources: []string{taskResourceTufCommit}})
 
 	if err == tasks_manager.ErrBusy {
 		b.Logger().Debug(fmt.Sprintf("Will not add new periodic task: there is currently running task which took more than %s", periodicRunPeriod))
@@ -114,5 +114,14 @@ func (b *Backend) periodicTask(ctx context.Context, storage logical.Storage, _ *
 		return fmt.Errorf("unable to update TUF repository timestamps: %w", err)
 	}
 
+	versions, err := tufVersions(publisherRepository)
+	if err != nil {
+		return err
+	}
+
+	if err := tasks_manager.PutTaskResult(ctx, storage, map[string]interface{}{"tuf_versions": versions}); err != nil {
+		return fmt.Errorf("unable to put task result: %w", err)
+	}
+
 	return nil
 }
@@ -22,6 +22,8 @@ type TrdlGroup struct {
 type TrdlGroupChannel struct {
 	Name    string `yaml:"name"`
 	Version string `yaml:"version"`
+	// AllowDowngrade allows to roll the channel back to the version lower than the published one.
+	AllowDowngrade bool `yaml:"allowDowngrade,omitempty"`
 }
 
 func ParseTrdlChannels(data []byte) (*TrdlChannels, error) {
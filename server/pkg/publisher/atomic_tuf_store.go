@@ -0,0 +1,672 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+
+	"github.com/djherbis/buffer"
+	"github.com/djherbis/nio/v3"
+	"github.com/hashicorp/go-hclog"
+	"github.com/samber/lo"
+	"github.com/theupdateframework/go-tuf"
+	"github.com/theupdateframework/go-tuf/data"
+	"github.com/theupdateframework/go-tuf/pkg/keys"
+	"github.com/theupdateframework/go-tuf/util"
+)
+
+const storeStagingDir = ".staging"
+
+var _ tuf.LocalStore = (*AtomicTufStore)(nil)
+
+// AtomicTufStore stages target files and metadata under the prefix of the repository version, e.g. .staging/42,
+// verifies what has been uploaded and only then publishes it, writing timestamp.json last.
+// Everything written during a failed commit is rolled back, the dropped staged targets are deleted.
+//
+// Clients start with timestamp.json, so with the consistent snapshots they either see the previous repository state
+// or the new one. Without them snapshot.json and targets.json are overwritten in place, and a client that has fetched
+// the previous timestamp.json may get the mismatching metadata during the commit and has to retry the update.
+//
+// The staging prefix is in the repository root, so the mirrors of the repository must exclude it.
+type AtomicTufStore struct {
+	Filesystem Filesystem
+	PrivKeys   TufRepoPrivKeys
+
+	// targetsStagingDir is the staging prefix of the version following the committed one when the first target is staged.
+	targetsStagingDir string
+	stagedMeta        map[string]json.RawMessage
+	stagedFiles       []string
+	logger            hclog.Logger
+
+	signerForKeyID map[string]keys.Signer
+	keyIDsForRole  map[string][]string
+}
+
+func NewAtomicTufStore(privKeys TufRepoPrivKeys, filesystem Filesystem, logger hclog.Logger) *AtomicTufStore {
+	return &AtomicTufStore{
+		Filesystem:     filesystem,
+		PrivKeys:       privKeys,
+		stagedMeta:     make(map[string]json.RawMessage),
+		logger:         logger,
+		signerForKeyID: make(map[string]keys.Signer),
+		keyIDsForRole:  make(map[string][]string),
+	}
+}
+
+// stagingDir returns the staging prefix of the repository version, the version of timestamp.json.
+func stagingDir(version int64) string {
+	return path.Join(storeStagingDir, strconv.FormatInt(version, 10))
+}
+
+var topLevelManifests = []string{
+	"root.json",
+	"targets.json",
+	"snapshot.json",
+	"timestamp.json",
+}
+
+func (store *AtomicTufStore) GetMeta() (map[string]json.RawMessage, error) {
+	ctx := context.Background()
+
+	meta := make(map[string]json.RawMessage)
+
+	for _, name := range topLevelManifests {
+		data, exists, err := store.readMeta(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if exists {
+			meta[name] = data
+		}
+	}
+
+	if err := store.readDelegatedMeta(ctx, meta, "targets.json"); err != nil {
+		return nil, err
+	}
+
+	store.logger.Debug(fmt.Sprintf("-- AtomicTufStore.GetMeta -> meta[targets]: %s", meta["targets.json"]))
+
+	return meta, nil
+}
+
+// readDelegatedMeta reads the metadata of the roles delegated by the specified targets metadata recursively.
+func (store *AtomicTufStore) readDelegatedMeta(ctx context.Context, meta map[string]json.RawMessage, delegatorName string) error {
+	delegatorData, ok := meta[delegatorName]
+	if !ok {
+		return nil
+	}
+
+	signed := &data.Signed{}
+	if err := json.Unmarshal(delegatorData, signed); err != nil {
+		return fmt.Errorf("unable to unmarshal %q: %w", delegatorName, err)
+	}
+
+	targets := &data.Targets{}
+	if err := json.Unmarshal(signed.Signed, targets); err != nil {
+		return fmt.Errorf("unable to unmarshal %q signed data: %w", delegatorName, err)
+	}
+
+	if targets.Delegations == nil {
+		return nil
+	}
+
+	for _, role := range targets.Delegations.Roles {
+		name := role.Name + ".json"
+		if _, ok := meta[name]; ok {
+			continue
+		}
+
+		roleData, exists, err := store.readMeta(ctx, name)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			continue
+		}
+
+		meta[name] = roleData
+
+		if err := store.readDelegatedMeta(ctx, meta, name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (store *AtomicTufStore) readMeta(ctx context.Context, name string) (json.RawMessage, bool, error) {
+	if stagedData, hasKey := store.stagedMeta[name]; hasKey {
+		return stagedData, true, nil
+	}
+	store.logger.Debug(fmt.Sprintf("-- AtomicTufStore.GetMeta %q not found in staged meta!", name))
+
+	exists, err := store.Filesystem.IsFileExist(ctx, name)
+	if err != nil {
+		return nil, false, fmt.Errorf("error checking existence of %q: %w", name, err)
+	}
+
+	if !exists {
+		store.logger.Debug(fmt.Sprintf("-- AtomicTufStore.GetMeta %q not found in the store filesystem!", name))
+		return nil, false, nil
+	}
+
+	data, err := store.Filesystem.ReadFileBytes(ctx, name)
+	if err != nil {
+		return nil, false, fmt.Errorf("error reading %q: %w", name, err)
+	}
+
+	return data, true, nil
+}
+
+func (store *AtomicTufStore) SetMeta(name string, meta json.RawMessage) error {
+	store.logger.Debug(fmt.Sprintf("-- AtomicTufStore.SetMeta %q", name))
+	store.stagedMeta[name] = meta
+	return nil
+}
+
+func (store *AtomicTufStore) WalkStagedTargets(targetPathList []string, targetsFn tuf.TargetsWalkFunc) error {
+	store.logger.Debug(fmt.Sprintf("-- AtomicTufStore.WalkStagedTargets %v", targetPathList))
+
+	ctx := context.Background()
+
+	runPipedFileReader := func(path string) io.Reader {
+		buf := buffer.New(64 * 1024 * 1024)
+		reader, writer := nio.Pipe(buf)
+
+		go func() {
+			store.logger.Debug(fmt.Sprintf("-- AtomicTufStore.WalkStagedTargets before ReadFileStream %q", path))
+
+			if err := store.Filesystem.ReadFileStream(ctx, path, writer); err != nil {
+				if err := writer.CloseWithError(fmt.Errorf("error reading file %q stream: %w", path, err)); err != nil {
+					panic(fmt.Sprintf("ERROR: failed to close pipe writer while reading file %q stream: %s\n", path, err))
+				}
+			}
+
+			store.logger.Debug(fmt.Sprintf("-- AtomicTufStore.WalkStagedTargets after ReadFileStream %q", path))
+			if err := writer.Close(); err != nil {
+				panic(fmt.Sprintf("ERROR: failed to close pipe writer while reading file %q stream: %s\n", path, err))
+			}
+		}()
+
+		return reader
+	}
+
+	if len(targetPathList) == 0 {
+		for _, filePath := range store.stagedFiles {
+			if err := targetsFn(filePath, runPipedFileReader(store.stagedTargetPath(filePath))); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+FilterStagedPaths:
+	for _, targetPath := range targetPathList {
+		for _, stagedPath := range store.stagedFiles {
+			if stagedPath == targetPath {
+				if err := targetsFn(targetPath, runPipedFileReader(store.stagedTargetPath(targetPath))); err != nil {
+					return err
+				}
+
+				continue FilterStagedPaths
+			}
+		}
+
+		return tuf.ErrFileNotFound{Path: targetPath}
+	}
+
+	return nil
+}
+
+func (store *AtomicTufStore) StageTargetFile(ctx context.Context, targetPath string, data io.Reader) error {
+	store.logger.Debug(fmt.Sprintf("-- AtomicTufStore.StageTargetFile %q", targetPath))
+
+	if store.targetsStagingDir == "" {
+		version, err := store.committedVersion(ctx)
+		if err != nil {
+			return err
+		}
+
+		store.targetsStagingDir = stagingDir(version + 1)
+	}
+
+	if err := store.Filesystem.WriteFileStream(ctx, store.stagedTargetPath(targetPath), data); err != nil {
+		// The partially written file is deleted right away unless it is already staged and deleted on clean.
+		if !lo.Contains(store.stagedFiles, targetPath) {
+			_ = store.Filesystem.DeleteFile(ctx, store.stagedTargetPath(targetPath))
+		}
+
+		return fmt.Errorf("error writing %q into the store filesystem: %w", targetPath, err)
+	}
+
+	if !lo.Contains(store.stagedFiles, targetPath) {
+		store.stagedFiles = append(store.stagedFiles, targetPath)
+	}
+
+	return nil
+}
+
+func (store *AtomicTufStore) Commit(consistentSnapshot bool, versions map[string]int64, hashes map[string]data.Hashes) error {
+	store.logger.Debug("-- AtomicTufStore.Commit")
+
+	ctx := context.Background()
+	commit := &storeCommit{store: store}
+
+	if err := commit.run(ctx, consistentSnapshot, versions, hashes); err != nil {
+		if rollbackErr := commit.rollback(ctx); rollbackErr != nil {
+			return fmt.Errorf("%w (rollback failed: %s)", err, rollbackErr)
+		}
+
+		return err
+	}
+
+	commit.cleanup(ctx)
+
+	return nil
+}
+
+func (store *AtomicTufStore) FileIsStaged(filename string) bool {
+	_, ok := store.stagedMeta[filename]
+	return ok
+}
+
+func (store *AtomicTufStore) GetSigners(role string) ([]keys.Signer, error) {
+	keyIDs, ok := store.keyIDsForRole[role]
+	if ok {
+		return store.SignersForKeyIDs(keyIDs), nil
+	}
+	return nil, nil
+}
+
+func (store *AtomicTufStore) SignersForKeyIDs(keyIDs []string) []keys.Signer {
+	signers := []keys.Signer{}
+	keyIDsSeen := map[string]struct{}{}
+
+	for _, keyID := range keyIDs {
+		signer, ok := store.signerForKeyID[keyID]
+		if !ok {
+			continue
+		}
+		addSigner := false
+
+		for _, skid := range signer.PublicData().IDs() {
+			if _, seen := keyIDsSeen[skid]; !seen {
+				addSigner = true
+			}
+
+			keyIDsSeen[skid] = struct{}{}
+		}
+
+		if addSigner {
+			signers = append(signers, signer)
+		}
+	}
+
+	return signers
+}
+
+func (store *AtomicTufStore) SaveSigner(role string, signer keys.Signer) error {
+	keyIDs := signer.PublicData().IDs()
+
+	for _, keyID := range keyIDs {
+		store.signerForKeyID[keyID] = signer
+	}
+
+	mergedKeyIDs := lo.Uniq[string](append(store.keyIDsForRole[role], keyIDs...))
+	store.keyIDsForRole[role] = mergedKeyIDs
+
+	if err := store.PrivKeys.SetKeyFromSigner(role, signer); err != nil {
+		return fmt.Errorf("unable to set private key for role %q: %w", role, err)
+	}
+
+	return nil
+}
+
+// Clean drops everything staged since the last commit.
+func (store *AtomicTufStore) Clean() error {
+	ctx := context.Background()
+
+	for _, targetPath := range store.stagedFiles {
+		if err := store.Filesystem.DeleteFile(ctx, store.stagedTargetPath(targetPath)); err != nil {
+			return fmt.Errorf("unable to delete staged target %q: %w", targetPath, err)
+		}
+	}
+
+	store.reset()
+
+	return nil
+}
+
+func (store *AtomicTufStore) reset() {
+	store.targetsStagingDir = ""
+	store.stagedFiles = nil
+	store.stagedMeta = make(map[string]json.RawMessage)
+}
+
+func (store *AtomicTufStore) stagedTargetPath(targetPath string) string {
+	return path.Join(store.targetsStagingDir, "targets", targetPath)
+}
+
+// committedVersion returns the version of the committed timestamp.json, 0 for the empty repository.
+func (store *AtomicTufStore) committedVersion(ctx context.Context) (int64, error) {
+	timestampData, err := readCommittedTimestamp(ctx, store.Filesystem)
+	if err != nil {
+		return 0, err
+	}
+
+	if timestampData == nil {
+		return 0, nil
+	}
+
+	return timestampVersion(timestampData)
+}
+
+func timestampVersion(timestampData json.RawMessage) (int64, error) {
+	signed := &data.Signed{}
+	if err := json.Unmarshal(timestampData, signed); err != nil {
+		return 0, fmt.Errorf("unable to unmarshal %q: %w", "timestamp.json", err)
+	}
+
+	timestamp := &data.Timestamp{}
+	if err := json.Unmarshal(signed.Signed, timestamp); err != nil {
+		return 0, fmt.Errorf("unable to unmarshal %q signed data: %w", "timestamp.json", err)
+	}
+
+	return timestamp.Version, nil
+}
+
+// storeCommit keeps track of everything written into the live repository, so that a failed commit can be reverted.
+type storeCommit struct {
+	store *AtomicTufStore
+	// stagingDir is the staging prefix of the committed version, where the metadata and the backups are staged.
+	stagingDir string
+
+	stagedPaths  []string
+	createdPaths []string
+	backups      []storeCommitBackup
+}
+
+type storeCommitBackup struct {
+	path       string
+	backupPath string
+}
+
+func (commit *storeCommit) run(ctx context.Context, consistentSnapshot bool, versions map[string]int64, hashes map[string]data.Hashes) error {
+	store := commit.store
+
+	version, err := commit.version(ctx)
+	if err != nil {
+		return err
+	}
+	commit.stagingDir = stagingDir(version)
+
+	for _, name := range sortedMetaNames(store.stagedMeta) {
+		if err := commit.stageFile(ctx, commit.stagedMetaPath(name), store.stagedMeta[name]); err != nil {
+			return fmt.Errorf("unable to stage %q: %w", name, err)
+		}
+	}
+
+	if err := commit.verifyStaged(ctx, hashes); err != nil {
+		return fmt.Errorf("staged repository state verification failed: %w", err)
+	}
+
+	// Target files first: metadata must never reference a target which is not yet uploaded.
+	for _, targetPath := range store.stagedFiles {
+		for _, publishPath := range computeTargetPaths(consistentSnapshot, path.Join("targets", targetPath), hashes) {
+			if err := commit.publishFile(ctx, store.stagedTargetPath(targetPath), publishPath); err != nil {
+				return fmt.Errorf("unable to publish target %q: %w", publishPath, err)
+			}
+		}
+	}
+
+	// Then metadata in the order the clients resolve it backwards: timestamp.json makes everything else live.
+	for _, name := range sortedMetaNames(store.stagedMeta) {
+		for _, publishPath := range computeMetadataPaths(consistentSnapshot, name, versions) {
+			if err := commit.publishFile(ctx, commit.stagedMetaPath(name), publishPath); err != nil {
+				return fmt.Errorf("unable to publish metadata %q: %w", publishPath, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// version returns the version of the staged timestamp.json, the one following the committed version if it is not staged.
+func (commit *storeCommit) version(ctx context.Context) (int64, error) {
+	if timestampData, ok := commit.store.stagedMeta["timestamp.json"]; ok {
+		return timestampVersion(timestampData)
+	}
+
+	version, err := commit.store.committedVersion(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return version + 1, nil
+}
+
+func (commit *storeCommit) stagedMetaPath(name string) string {
+	return path.Join(commit.stagingDir, name)
+}
+
+func (commit *storeCommit) stageFile(ctx context.Context, path string, data []byte) error {
+	commit.stagedPaths = append(commit.stagedPaths, path)
+	return commit.store.Filesystem.WriteFileBytes(ctx, path, data)
+}
+
+// verifyStaged reads back everything uploaded under the staging prefix and compares it with what has been signed.
+func (commit *storeCommit) verifyStaged(ctx context.Context, hashes map[string]data.Hashes) error {
+	store := commit.store
+
+	for name, expected := range store.stagedMeta {
+		actual, err := store.Filesystem.ReadFileBytes(ctx, commit.stagedMetaPath(name))
+		if err != nil {
+			return fmt.Errorf("unable to read staged %q: %w", name, err)
+		}
+
+		if !bytes.Equal(actual, expected) {
+			return fmt.Errorf("staged %q does not match the signed metadata", name)
+		}
+	}
+
+	for _, targetPath := range store.stagedFiles {
+		expectedHashes, ok := hashes[path.Join("targets", targetPath)]
+		if !ok {
+			return fmt.Errorf("staged target %q is not registered in the targets metadata", targetPath)
+		}
+
+		if err := verifyFileHashes(ctx, store.Filesystem, store.stagedTargetPath(targetPath), expectedHashes); err != nil {
+			return fmt.Errorf("staged target %q: %w", targetPath, err)
+		}
+	}
+
+	return nil
+}
+
+func (commit *storeCommit) publishFile(ctx context.Context, srcPath, dstPath string) error {
+	fs := commit.store.Filesystem
+
+	exists, err := fs.IsFileExist(ctx, dstPath)
+	if err != nil {
+		return fmt.Errorf("error checking existence of %q: %w", dstPath, err)
+	}
+
+	var backupPath string
+	if exists {
+		backupPath = path.Join(commit.stagingDir, "backup", dstPath)
+		commit.stagedPaths = append(commit.stagedPaths, backupPath)
+
+		if err := copyFile(ctx, fs, dstPath, backupPath); err != nil {
+			return fmt.Errorf("unable to back up %q: %w", dstPath, err)
+		}
+	}
+
+	// Single file writes are atomic for all filesystems, so a failed write leaves nothing to roll back.
+	if err := copyFile(ctx, fs, srcPath, dstPath); err != nil {
+		return err
+	}
+
+	if exists {
+		commit.backups = append(commit.backups, storeCommitBackup{path: dstPath, backupPath: backupPath})
+	} else {
+		commit.createdPaths = append(commit.createdPaths, dstPath)
+	}
+
+	return nil
+}
+
+func (commit *storeCommit) rollback(ctx context.Context) error {
+	store := commit.store
+	store.logger.Warn("Rolling back failed TUF repository commit")
+
+	var errs []error
+
+	for i := len(commit.backups) - 1; i >= 0; i-- {
+		backup := commit.backups[i]
+		if err := copyFile(ctx, store.Filesystem, backup.backupPath, backup.path); err != nil {
+			errs = append(errs, fmt.Errorf("unable to restore %q: %w", backup.path, err))
+		}
+	}
+
+	for _, createdPath := range commit.createdPaths {
+		if err := store.Filesystem.DeleteFile(ctx, createdPath); err != nil {
+			errs = append(errs, fmt.Errorf("unable to delete %q: %w", createdPath, err))
+		}
+	}
+
+	if len(errs) != 0 {
+		// Keep the staging files, the backups are needed to restore the repository manually.
+		return errors.Join(errs...)
+	}
+
+	commit.cleanup(ctx)
+
+	return nil
+}
+
+// cleanup drops the staging files. The repository state is already final, so errors are only logged.
+func (commit *storeCommit) cleanup(ctx context.Context) {
+	store := commit.store
+
+	for _, targetPath := range store.stagedFiles {
+		commit.stagedPaths = append(commit.stagedPaths, store.stagedTargetPath(targetPath))
+	}
+
+	for _, stagedPath := range commit.stagedPaths {
+		if err := store.Filesystem.DeleteFile(ctx, stagedPath); err != nil {
+			store.logger.Warn(fmt.Sprintf("Unable to delete staging file %q: %s", stagedPath, err))
+		}
+	}
+
+	store.reset()
+}
+
+// sortedMetaNames returns metadata names so that timestamp.json goes last and snapshot.json goes right before it.
+func sortedMetaNames(meta map[string]json.RawMessage) []string {
+	names := lo.Keys(meta)
+
+	rank := func(name string) int {
+		switch name {
+		case "timestamp.json":
+			return 2
+		case "snapshot.json":
+			return 1
+		default:
+			return 0
+		}
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		if rank(names[i]) != rank(names[j]) {
+			return rank(names[i]) < rank(names[j])
+		}
+		return names[i] < names[j]
+	})
+
+	return names
+}
+
+func copyFile(ctx context.Context, fs Filesystem, srcPath, dstPath string) error {
+	buf := buffer.New(64 * 1024 * 1024)
+	reader, writer := nio.Pipe(buf)
+
+	go func() {
+		if err := fs.ReadFileStream(ctx, srcPath, writer); err != nil {
+			_ = writer.CloseWithError(fmt.Errorf("error reading file %q stream: %w", srcPath, err))
+			return
+		}
+		_ = writer.Close()
+	}()
+
+	if err := fs.WriteFileStream(ctx, dstPath, reader); err != nil {
+		_ = reader.CloseWithError(err)
+		return fmt.Errorf("error writing %q: %w", dstPath, err)
+	}
+
+	return nil
+}
+
+func verifyFileHashes(ctx context.Context, fs Filesystem, filePath string, expectedHashes data.Hashes) error {
+	buf := buffer.New(64 * 1024 * 1024)
+	reader, writer := nio.Pipe(buf)
+
+	go func() {
+		if err := fs.ReadFileStream(ctx, filePath, writer); err != nil {
+			_ = writer.CloseWithError(fmt.Errorf("error reading file %q stream: %w", filePath, err))
+			return
+		}
+		_ = writer.Close()
+	}()
+
+	meta, err := util.GenerateFileMeta(reader, lo.Keys(expectedHashes)...)
+	if err != nil {
+		_ = reader.CloseWithError(err)
+		return fmt.Errorf("unable to hash %q: %w", filePath, err)
+	}
+
+	for alg, expectedHash := range expectedHashes {
+		if !bytes.Equal(meta.Hashes[alg], expectedHash) {
+			return fmt.Errorf("%s hash mismatch: expected %s, got %s", alg, expectedHash, meta.Hashes[alg])
+		}
+	}
+
+	return nil
+}
+
+func computeTargetPaths(consistentSnapshot bool, name string, hashes map[string]data.Hashes) []string {
+	if consistentSnapshot {
+		return util.HashedPaths(name, hashes[name])
+	}
+
+	return []string{name}
+}
+
+func computeMetadataPaths(consistentSnapshot bool, name string, versions map[string]int64) []string {
+	copyVersion := false
+
+	switch name {
+	case "root.json":
+		copyVersion = true
+	case "timestamp.json":
+		copyVersion = false
+	default:
+		copyVersion = consistentSnapshot
+	}
+
+	paths := []string{name}
+	if copyVersion {
+		// The versioned copy is immutable and goes first, so that the plain name never refers to a missing version.
+		paths = append([]string{util.VersionedPath(name, versions[name])}, paths...)
+	}
+
+	return paths
+}
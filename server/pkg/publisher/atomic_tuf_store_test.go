@@ -0,0 +1,168 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/theupdateframework/go-tuf"
+)
+
+// faultyFilesystem fails any write into the paths with the specified name.
+type faultyFilesystem struct {
+	Filesystem
+	failWrite string
+}
+
+func (fs *faultyFilesystem) WriteFileBytes(ctx context.Context, path string, data []byte) error {
+	if path == fs.failWrite {
+		return errors.New("injected write error")
+	}
+	return fs.Filesystem.WriteFileBytes(ctx, path, data)
+}
+
+func (fs *faultyFilesystem) WriteFileStream(ctx context.Context, path string, data io.Reader) error {
+	if path == fs.failWrite {
+		return errors.New("injected write error")
+	}
+	return fs.Filesystem.WriteFileStream(ctx, path, data)
+}
+
+func listTestDirectory(dir string) []string {
+	var files []string
+	Expect(filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			relPath, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(relPath))
+		}
+		return nil
+	})).To(Succeed())
+	return files
+}
+
+func commitTestTarget(store *AtomicTufStore, repo *tuf.Repo, name, data string) error {
+	Expect(store.StageTargetFile(context.Background(), name, bytes.NewBufferString(data))).To(Succeed())
+	Expect(repo.AddTarget(name, json.RawMessage(""))).To(Succeed())
+	Expect(repo.Snapshot()).To(Succeed())
+	Expect(repo.Timestamp()).To(Succeed())
+	return repo.Commit()
+}
+
+var _ = Describe("AtomicTufStore", func() {
+	var (
+		dir   string
+		fs    *faultyFilesystem
+		store *AtomicTufStore
+	)
+
+	newTestRepo := func(consistentSnapshot bool) *tuf.Repo {
+		repo, err := tuf.NewRepo(store)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.Init(consistentSnapshot)).To(Succeed())
+
+		for _, role := range []string{"root", "targets", "snapshot", "timestamp"} {
+			_, err := repo.GenKey(role)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		return repo
+	}
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		fs = &faultyFilesystem{Filesystem: NewLocalFilesystem(dir, hclog.NewNullLogger())}
+		store = NewAtomicTufStore(TufRepoPrivKeys{}, fs, hclog.NewNullLogger())
+	})
+
+	It("should publish targets and metadata and clean up the staging files", func() {
+		repo := newTestRepo(false)
+		Expect(commitTestTarget(store, repo, "file.txt", "data")).To(Succeed())
+
+		Expect(listTestDirectory(dir)).To(ConsistOf(
+			"1.root.json", "root.json", "targets.json", "snapshot.json", "timestamp.json", "targets/file.txt",
+		))
+		Expect(os.ReadFile(filepath.Join(dir, "targets/file.txt"))).To(Equal([]byte("data")))
+	})
+
+	It("should publish versioned metadata and hashed targets for a consistent snapshot", func() {
+		repo := newTestRepo(true)
+		Expect(commitTestTarget(store, repo, "file.txt", "data")).To(Succeed())
+
+		files := listTestDirectory(dir)
+		Expect(files).To(ContainElements(
+			"1.root.json", "1.targets.json", "1.snapshot.json", "root.json", "targets.json", "snapshot.json", "timestamp.json",
+		))
+		Expect(files).NotTo(ContainElement("targets/file.txt"))
+		Expect(files).To(ContainElement(And(HavePrefix("targets/"), HaveSuffix(".file.txt"))))
+	})
+
+	It("should roll back the previous repository state when the commit fails", func() {
+		repo := newTestRepo(false)
+		Expect(commitTestTarget(store, repo, "file.txt", "data")).To(Succeed())
+
+		filesBefore := listTestDirectory(dir)
+		metaBefore := map[string][]byte{}
+		for _, name := range topLevelManifests {
+			data, err := os.ReadFile(filepath.Join(dir, name))
+			Expect(err).NotTo(HaveOccurred())
+			metaBefore[name] = data
+		}
+
+		fs.failWrite = "timestamp.json"
+		err := commitTestTarget(store, repo, "new-file.txt", "new data")
+		Expect(err).To(MatchError(ContainSubstring("injected write error")))
+
+		Expect(listTestDirectory(dir)).To(ConsistOf(filesBefore))
+		for name, data := range metaBefore {
+			Expect(os.ReadFile(filepath.Join(dir, name))).To(Equal(data), name)
+		}
+	})
+
+	It("should not publish anything when staging fails", func() {
+		repo := newTestRepo(false)
+
+		fs.failWrite = ""
+		Expect(store.StageTargetFile(context.Background(), "file.txt", bytes.NewBufferString("data"))).To(Succeed())
+		Expect(repo.AddTarget("file.txt", json.RawMessage(""))).To(Succeed())
+		Expect(repo.Snapshot()).To(Succeed())
+		Expect(repo.Timestamp()).To(Succeed())
+
+		fs.failWrite = path.Join(stagingDir(1), "targets.json")
+		Expect(repo.Commit()).To(MatchError(ContainSubstring("injected write error")))
+
+		Expect(listTestDirectory(dir)).To(BeEmpty())
+	})
+
+	It("should stage under the prefix of the next repository version", func() {
+		repo := newTestRepo(false)
+		Expect(commitTestTarget(store, repo, "file.txt", "data")).To(Succeed())
+
+		Expect(store.StageTargetFile(context.Background(), "new-file.txt", bytes.NewBufferString("new data"))).To(Succeed())
+		Expect(listTestDirectory(dir)).To(ContainElement(path.Join(stagingDir(2), "targets", "new-file.txt")))
+	})
+
+	It("should drop staged targets on clean", func() {
+		repo := newTestRepo(false)
+		Expect(store.StageTargetFile(context.Background(), "file.txt", bytes.NewBufferString("data"))).To(Succeed())
+		Expect(repo.Clean()).To(Succeed())
+
+		for _, file := range listTestDirectory(dir) {
+			Expect(strings.HasPrefix(file, storeStagingDir)).To(BeFalse(), file)
+		}
+	})
+})
@@ -0,0 +1,108 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha512"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/theupdateframework/go-tuf/data"
+)
+
+// channelTargetCustom is the custom metadata of the channels/<group>/<channel> target.
+// The targets published before the metadata was introduced have no custom metadata.
+type channelTargetCustom struct {
+	Version         string `json:"version"`
+	GitCommit       string `json:"git_commit,omitempty"`
+	PromotedFromTag string `json:"promoted_from_tag,omitempty"`
+}
+
+// PublishedChannel is the release version the channel of the group points to.
+type PublishedChannel struct {
+	Group   string
+	Name    string
+	Version string
+	// GitCommit is the trdl channels branch commit the channel has been published from, empty if unknown.
+	GitCommit string
+	// PromotedFromTag is the release tag the channel has been promoted by, empty if published from the trdl channels branch.
+	PromotedFromTag string
+}
+
+// GetChannels returns the committed channels sorted by group and name.
+func (repository *Repository) GetChannels(ctx context.Context) ([]PublishedChannel, error) {
+	targetFiles, err := repository.GetTargetFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var channels []PublishedChannel
+	for targetPath, meta := range targetFiles {
+		parts := strings.Split(targetPath, "/")
+		if len(parts) != 3 || parts[0] != "channels" {
+			continue
+		}
+
+		channel := PublishedChannel{Group: parts[1], Name: parts[2]}
+
+		var custom channelTargetCustom
+		if meta.Custom != nil {
+			if err := json.Unmarshal(*meta.Custom, &custom); err != nil {
+				return nil, fmt.Errorf("unable to unmarshal %q custom metadata: %w", targetPath, err)
+			}
+		}
+
+		if custom.Version != "" {
+			channel.Version = custom.Version
+			channel.GitCommit = custom.GitCommit
+			channel.PromotedFromTag = custom.PromotedFromTag
+		} else {
+			content, err := repository.readTarget(ctx, targetPath, meta)
+			if err != nil {
+				return nil, err
+			}
+			channel.Version = strings.TrimSpace(string(content))
+		}
+
+		channels = append(channels, channel)
+	}
+
+	sort.Slice(channels, func(i, j int) bool {
+		if channels[i].Group != channels[j].Group {
+			return channels[i].Group < channels[j].Group
+		}
+		return channels[i].Name < channels[j].Name
+	})
+
+	return channels, nil
+}
+
+func (repository *Repository) readTarget(ctx context.Context, name string, meta data.TargetFileMeta) ([]byte, error) {
+	consistentSnapshot, err := repository.IsConsistentSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	targetPath := path.Join("targets", name)
+	publishPaths := computeTargetPaths(consistentSnapshot, targetPath, map[string]data.Hashes{targetPath: meta.Hashes})
+
+	content, err := repository.Filesystem.ReadFileBytes(ctx, publishPaths[0])
+	if err != nil {
+		return nil, fmt.Errorf("unable to read target %q: %w", name, err)
+	}
+
+	return content, nil
+}
+
+func isTargetContent(meta data.TargetFileMeta, content []byte) bool {
+	hash, ok := meta.Hashes["sha512"]
+	if !ok {
+		return false
+	}
+
+	sum := sha512.Sum512(content)
+	return bytes.Equal(hash, sum[:])
+}
@@ -0,0 +1,91 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/trdl/server/pkg/config"
+)
+
+var _ = Describe("Channels", func() {
+	var (
+		ctx       context.Context
+		storage   logical.Storage
+		publisher *Publisher
+		options   RepositoryOptions
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		storage = &logical.InmemStorage{}
+		publisher = NewPublisher(hclog.NewNullLogger())
+		options = RepositoryOptions{
+			StorageBackend:          StorageBackendLocal,
+			LocalDirectory:          GinkgoT().TempDir(),
+			InitializeTUFKeys:       true,
+			InitializePGPSigningKey: true,
+		}
+	})
+
+	getRepository := func() *Repository {
+		repository, err := publisher.GetRepository(ctx, storage, options)
+		Expect(err).NotTo(HaveOccurred())
+		return repository.(*Repository)
+	}
+
+	publishChannels := func(gitCommit string, groups ...config.TrdlGroup) *Repository {
+		repository := getRepository()
+		Expect(publisher.StageChannelsConfig(ctx, storage, repository, &config.TrdlChannels{Groups: groups}, StageChannelsConfigOptions{GitCommit: gitCommit})).To(Succeed())
+		Expect(repository.CommitStaged(ctx)).To(Succeed())
+		return repository
+	}
+
+	It("should keep the git commit of the unchanged channels", func() {
+		publishChannels("commit-1",
+			config.TrdlGroup{Name: "1", Channels: []config.TrdlGroupChannel{{Name: "stable", Version: "1.0.0"}, {Name: "alpha", Version: "1.0.0"}}},
+		)
+		repository := publishChannels("commit-2",
+			config.TrdlGroup{Name: "1", Channels: []config.TrdlGroupChannel{{Name: "stable", Version: "1.0.0"}, {Name: "alpha", Version: "1.1.0"}}},
+			config.TrdlGroup{Name: "2", Channels: []config.TrdlGroupChannel{{Name: "stable", Version: "2.0.0"}}},
+		)
+
+		Expect(repository.GetChannels(ctx)).To(Equal([]PublishedChannel{
+			{Group: "1", Name: "alpha", Version: "1.1.0", GitCommit: "commit-2"},
+			{Group: "1", Name: "stable", Version: "1.0.0", GitCommit: "commit-1"},
+			{Group: "2", Name: "stable", Version: "2.0.0", GitCommit: "commit-2"},
+		}))
+	})
+
+	It("should record the release tag of the promoted channels instead of the commit", func() {
+		publishChannels("commit-1",
+			config.TrdlGroup{Name: "1", Channels: []config.TrdlGroupChannel{{Name: "stable", Version: "1.0.0"}, {Name: "alpha", Version: "1.0.0"}}},
+		)
+
+		repository := getRepository()
+		Expect(publisher.StageChannelsConfig(ctx, storage, repository, &config.TrdlChannels{Groups: []config.TrdlGroup{
+			{Name: "1", Channels: []config.TrdlGroupChannel{{Name: "alpha", Version: "1.1.0"}}},
+		}}, StageChannelsConfigOptions{PromotedFromTag: "v1.1.0"})).To(Succeed())
+		Expect(repository.CommitStaged(ctx)).To(Succeed())
+
+		Expect(getRepository().GetChannels(ctx)).To(Equal([]PublishedChannel{
+			{Group: "1", Name: "alpha", Version: "1.1.0", PromotedFromTag: "v1.1.0"},
+			{Group: "1", Name: "stable", Version: "1.0.0", GitCommit: "commit-1"},
+		}))
+	})
+
+	It("should read the version of the channel published without the custom metadata", func() {
+		repository := getRepository()
+		Expect(publisher.ensureDelegatedRole(ctx, storage, repository, ChannelsDelegatedRoleName("1"), channelsDelegationPaths("1"))).To(Succeed())
+		Expect(repository.StageTarget(ctx, "channels/1/stable", bytes.NewBufferString("1.0.0\n"), StageTargetOptions{})).To(Succeed())
+		Expect(repository.CommitStaged(ctx)).To(Succeed())
+
+		Expect(getRepository().GetChannels(ctx)).To(Equal([]PublishedChannel{
+			{Group: "1", Name: "stable", Version: "1.0.0"},
+		}))
+	})
+})
@@ -0,0 +1,312 @@
+package publisher
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/theupdateframework/go-tuf"
+	"github.com/theupdateframework/go-tuf/data"
+	"github.com/theupdateframework/go-tuf/pkg/keys"
+)
+
+const (
+	DelegatedRoleReleases = "releases"
+	DelegatedRoleYanked   = "yanked"
+
+	channelsDelegatedRolePrefix = "channels-"
+
+	// TUF path patterns cannot match nested directories with a single wildcard,
+	// so a delegation lists a pattern for every depth up to this one.
+	maxDelegatedTargetPathDepth = 16
+)
+
+func ChannelsDelegatedRoleName(group string) string {
+	return channelsDelegatedRolePrefix + group
+}
+
+// releasesDelegationPaths covers releases/<release>/<os>-<arch>/<file> and the corresponding signatures/ files.
+func releasesDelegationPaths() []string {
+	return append(nestedPathPatterns("releases"), nestedPathPatterns("signatures")...)
+}
+
+func yankedDelegationPaths() []string {
+	return []string{"yanked/*"}
+}
+
+func channelsDelegationPaths(group string) []string {
+	return []string{"channels/" + escapePathPattern(group) + "/*"}
+}
+
+func nestedPathPatterns(dir string) []string {
+	var patterns []string
+	for depth := 1; depth <= maxDelegatedTargetPathDepth; depth++ {
+		patterns = append(patterns, dir+strings.Repeat("/*", depth))
+	}
+	return patterns
+}
+
+func escapePathPattern(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// delegatingTufRepo adds the delegated targets roles to the roles rotated by the TufRepoRotator.
+type delegatingTufRepo struct {
+	*tuf.Repo
+}
+
+var _ TufRepoRotatorAccessor = delegatingTufRepo{}
+
+// DelegatedTargetsExpires returns the earliest expiration of the delegated targets roles
+// or zero time if there are no delegations.
+func (repo delegatingTufRepo) DelegatedTargetsExpires() (time.Time, error) {
+	delegations, err := repo.delegations()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if delegations == nil {
+		return time.Time{}, nil
+	}
+
+	var expires time.Time
+	for _, role := range delegations.Roles {
+		targets, err := signedTargetsMeta(repo.Repo, role.Name+".json")
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if expires.IsZero() || targets.Expires.Before(expires) {
+			expires = targets.Expires
+		}
+	}
+
+	return expires, nil
+}
+
+// IncrementDelegatedTargetsVersionWithExpires re-signs all the delegated targets roles with the new expiration.
+// The TUF repo has no dedicated method for this, so the delegations are re-added with the same keys,
+// which bumps the version and sets the expiration of both the delegating and the delegated metadata.
+func (repo delegatingTufRepo) IncrementDelegatedTargetsVersionWithExpires(expires time.Time) error {
+	delegations, err := repo.delegations()
+	if err != nil {
+		return err
+	}
+
+	if delegations == nil {
+		return nil
+	}
+
+	if err := repo.ResetTargetsDelegationsWithExpires("targets", expires); err != nil {
+		return fmt.Errorf("unable to reset targets delegations: %w", err)
+	}
+
+	for _, role := range delegations.Roles {
+		var roleKeys []*data.PublicKey
+		for _, keyID := range role.KeyIDs {
+			if key, ok := delegations.Keys[keyID]; ok && !lo.Contains(roleKeys, key) {
+				roleKeys = append(roleKeys, key)
+			}
+		}
+
+		if err := repo.AddDelegatedRoleWithExpires("targets", role, roleKeys, expires); err != nil {
+			return fmt.Errorf("unable to re-add delegated role %q: %w", role.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (repo delegatingTufRepo) delegations() (*data.Delegations, error) {
+	targets, err := signedTargetsMeta(repo.Repo, "targets.json")
+	if err != nil {
+		return nil, err
+	}
+
+	return targets.Delegations, nil
+}
+
+func signedTargetsMeta(tufRepo *tuf.Repo, name string) (*data.Targets, error) {
+	signed, err := tufRepo.SignedMeta(name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get %q metadata: %w", name, err)
+	}
+
+	targets := &data.Targets{}
+	if err := json.Unmarshal(signed.Signed, targets); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal %q metadata: %w", name, err)
+	}
+
+	return targets, nil
+}
+
+func (repository *Repository) HasDelegatedRole(name string) (bool, error) {
+	delegations, err := delegatingTufRepo{repository.TufRepo}.delegations()
+	if err != nil {
+		return false, err
+	}
+
+	if delegations == nil {
+		return false, nil
+	}
+
+	return lo.ContainsBy(delegations.Roles, func(role data.DelegatedRole) bool {
+		return role.Name == name
+	}), nil
+}
+
+type AddDelegatedRoleOptions struct {
+	// Key is the role key stored by another repository handle, which has not committed the role yet.
+	Key *data.PrivateKey
+}
+
+// AddDelegatedRole delegates the targets matching the paths to the new role signed by its own new key.
+// The matching targets already signed by the top-level targets role are moved to the new role,
+// otherwise the clients would keep resolving them by the top-level targets metadata.
+func (repository *Repository) AddDelegatedRole(name string, paths []string, opts AddDelegatedRoleOptions) error {
+	topLevelTargets, err := repository.TufRepo.Targets()
+	if err != nil {
+		return fmt.Errorf("unable to get targets: %w", err)
+	}
+
+	role := data.DelegatedRole{Name: name, Paths: paths, Threshold: 1}
+
+	movedTargets := make(data.TargetFiles)
+	for targetPath, meta := range topLevelTargets {
+		matches, err := role.MatchesPath(targetPath)
+		if err != nil {
+			return fmt.Errorf("unable to match target %q: %w", targetPath, err)
+		}
+
+		if matches {
+			movedTargets[targetPath] = meta
+		}
+	}
+
+	movedTargetPaths := lo.Keys(movedTargets)
+	sort.Strings(movedTargetPaths)
+
+	// Remove before adding the delegation: the removal affects the delegated role metadata too.
+	if len(movedTargetPaths) > 0 {
+		if err := repository.TufRepo.RemoveTargets(movedTargetPaths); err != nil {
+			return fmt.Errorf("unable to remove targets delegated to %q from the top-level targets: %w", name, err)
+		}
+	}
+
+	// The key might be stored by the previous attempt, which has not been committed.
+	signers, err := repository.TufStore.PrivKeys.GetSigners(name)
+	if err != nil {
+		return fmt.Errorf("unable to get key signer for the delegated role %q: %w", name, err)
+	}
+
+	var signer keys.Signer
+	if opts.Key != nil {
+		signer, err = keys.GetSigner(opts.Key)
+		if err != nil {
+			return fmt.Errorf("unable to get key signer for the delegated role %q: %w", name, err)
+		}
+	} else if len(signers) > 0 {
+		signer = signers[0]
+	} else {
+		signer, err = keys.GenerateEd25519Key()
+		if err != nil {
+			return fmt.Errorf("unable to generate key for the delegated role %q: %w", name, err)
+		}
+	}
+
+	if err := repository.TufStore.SaveSigner(name, signer); err != nil {
+		return fmt.Errorf("unable to save key for the delegated role %q: %w", name, err)
+	}
+
+	role.KeyIDs = signer.PublicData().IDs()
+	if err := repository.TufRepo.AddDelegatedRole("targets", role, []*data.PublicKey{signer.PublicData()}); err != nil {
+		return fmt.Errorf("unable to add delegated role %q: %w", name, err)
+	}
+	repository.hasStagedDelegations = true
+
+	for _, targetPath := range movedTargetPaths {
+		meta := movedTargets[targetPath]
+
+		alg, hash := targetHash(meta.Hashes)
+
+		var custom json.RawMessage
+		if meta.Custom != nil {
+			custom = *meta.Custom
+		}
+
+		if err := repository.TufRepo.AddTargetsWithDigest(hex.EncodeToString(hash), alg, meta.Length, targetPath, custom); err != nil {
+			return fmt.Errorf("unable to move target %q to the delegated role %q: %w", targetPath, name, err)
+		}
+	}
+
+	repository.logger.Info(fmt.Sprintf("Added delegated targets role %q, moved %d existing targets", name, len(movedTargetPaths)))
+
+	return nil
+}
+
+// allTargets returns the targets of the top-level targets role and all the delegated roles.
+func (repository *Repository) allTargets() (data.TargetFiles, error) {
+	result := make(data.TargetFiles)
+	visited := make(map[string]bool)
+
+	var collect func(name string) error
+	collect = func(name string) error {
+		if visited[name] {
+			return nil
+		}
+		visited[name] = true
+
+		targets, err := signedTargetsMeta(repository.TufRepo, name)
+		if err != nil {
+			return err
+		}
+
+		for targetPath, meta := range targets.Targets {
+			if _, ok := result[targetPath]; !ok {
+				result[targetPath] = meta
+			}
+		}
+
+		if targets.Delegations == nil {
+			return nil
+		}
+
+		for _, role := range targets.Delegations.Roles {
+			if err := collect(role.Name + ".json"); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if err := collect("targets.json"); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// targetHash prefers sha512, which is the hash algorithm used by the TUF repo by default.
+func targetHash(hashes data.Hashes) (string, data.HexBytes) {
+	if hash, ok := hashes["sha512"]; ok {
+		return "sha512", hash
+	}
+
+	algs := lo.Keys(hashes)
+	sort.Strings(algs)
+
+	return algs[0], hashes[algs[0]]
+}
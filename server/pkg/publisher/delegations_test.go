@@ -0,0 +1,176 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/theupdateframework/go-tuf/data"
+
+	"github.com/werf/trdl/server/pkg/config"
+)
+
+var _ = Describe("Delegations", func() {
+	DescribeTable("delegation paths",
+		func(paths []string, target string, expectMatch bool) {
+			role := data.DelegatedRole{Name: "role", Paths: paths}
+			Expect(role.MatchesPath(target)).To(Equal(expectMatch))
+		},
+		Entry("release target", releasesDelegationPaths(), "releases/1.0.0/linux-amd64/bin/app", true),
+		Entry("nested release target", releasesDelegationPaths(), "releases/1.0.0/any-any/share/app/data/file", true),
+		Entry("release signature", releasesDelegationPaths(), "signatures/1.0.0/linux-amd64/bin/app.sig", true),
+		Entry("channel by releases", releasesDelegationPaths(), "channels/1/stable", false),
+		Entry("channel of the group", channelsDelegationPaths("1"), "channels/1/stable", true),
+		Entry("channel of another group", channelsDelegationPaths("1"), "channels/2/stable", false),
+		Entry("release by channels", channelsDelegationPaths("1"), "releases/1.0.0/linux-amd64/bin/app", false),
+		Entry("group with pattern characters", channelsDelegationPaths("1*"), "channels/12/stable", false),
+	)
+
+	var (
+		ctx     context.Context
+		storage logical.Storage
+		options RepositoryOptions
+		repoUrl string
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		storage = &logical.InmemStorage{}
+
+		dir := GinkgoT().TempDir()
+		server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+		DeferCleanup(server.Close)
+
+		options = RepositoryOptions{StorageBackend: StorageBackendLocal, LocalDirectory: dir, DelegatedRoles: true}
+		repoUrl = server.URL
+	})
+
+	It("should move existing top-level targets into the new delegated role", func() {
+		repository, err := NewRepositoryWithOptions(newTestRepositoryFilesystem(options), TufRepoOptions{}, hclog.NewNullLogger())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repository.Init()).To(Succeed())
+		Expect(repository.GenPrivKeys()).To(Succeed())
+		Expect(repository.StageTarget(ctx, "releases/1.0.0/linux-amd64/bin/app", bytes.NewBufferString("app 1.0.0"), StageTargetOptions{})).To(Succeed())
+		Expect(repository.StageTarget(ctx, "channels/1/stable", bytes.NewBufferString("1.0.0\n"), StageTargetOptions{})).To(Succeed())
+		Expect(repository.CommitStaged(ctx)).To(Succeed())
+
+		Expect(repository.AddDelegatedRole(DelegatedRoleReleases, releasesDelegationPaths(), AddDelegatedRoleOptions{})).To(Succeed())
+		Expect(repository.CommitStaged(ctx)).To(Succeed())
+
+		Expect(repository.HasDelegatedRole(DelegatedRoleReleases)).To(BeTrue())
+		Expect(repository.HasDelegatedRole(ChannelsDelegatedRoleName("1"))).To(BeFalse())
+		Expect(repository.GetPrivKeys().Delegations).To(HaveKey(DelegatedRoleReleases))
+		Expect(repository.TufRepo.Targets()).To(And(HaveLen(1), HaveKey("channels/1/stable")))
+		Expect(repository.GetTargets(ctx)).To(ConsistOf("releases/1.0.0/linux-amd64/bin/app", "channels/1/stable"))
+
+		rootJSON, err := repository.Filesystem.ReadFileBytes(ctx, "1.root.json")
+		Expect(err).NotTo(HaveOccurred())
+
+		client := newTestTufClient(repoUrl, rootJSON)
+		Expect(client.Update()).To(And(HaveLen(1), HaveKey("channels/1/stable")))
+		Expect(downloadTestTarget(client, "releases/1.0.0/linux-amd64/bin/app")).To(Equal("app 1.0.0"))
+	})
+
+	It("should keep signing the targets with the top-level role unless switched to the delegated roles", func() {
+		publisher := NewPublisher(hclog.NewNullLogger())
+
+		options.DelegatedRoles = false
+		options.InitializeTUFKeys = true
+		options.InitializePGPSigningKey = true
+		repositoryInterface, err := publisher.GetRepository(ctx, storage, options)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(publisher.StageReleaseTarget(ctx, repositoryInterface, "1.0.0", "linux-amd64/bin/app", bytes.NewBufferString("app 1.0.0"), nil)).To(Succeed())
+		Expect(publisher.StageChannelsConfig(ctx, storage, repositoryInterface, &config.TrdlChannels{
+			Groups: []config.TrdlGroup{
+				{Name: "1", Channels: []config.TrdlGroupChannel{{Name: "stable", Version: "1.0.0"}}},
+			},
+		}, StageChannelsConfigOptions{})).To(Succeed())
+		Expect(repositoryInterface.CommitStaged(ctx)).To(Succeed())
+
+		repository := repositoryInterface.(*Repository)
+		Expect(repository.HasDelegatedRole(DelegatedRoleReleases)).To(BeFalse())
+		Expect(repository.HasDelegatedRole(ChannelsDelegatedRoleName("1"))).To(BeFalse())
+		Expect(repository.TufRepo.Targets()).To(And(
+			HaveKey("releases/1.0.0/linux-amd64/bin/app"),
+			HaveKey("signatures/1.0.0/linux-amd64/bin/app.sig"),
+			HaveKey("channels/1/stable"),
+		))
+
+		options.DelegatedRoles = true
+		switchedRepository, err := publisher.GetRepository(ctx, storage, options)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(switchedRepository.CommitStaged(ctx)).To(Succeed())
+		Expect(switchedRepository.HasDelegatedRole(DelegatedRoleReleases)).To(BeTrue())
+		Expect(switchedRepository.(*Repository).TufRepo.Targets()).To(And(HaveLen(1), HaveKey("channels/1/stable")))
+	})
+
+	It("should sign new targets of the reopened repository with the stored delegated role keys", func() {
+		publisher := NewPublisher(hclog.NewNullLogger())
+
+		options.InitializeTUFKeys = true
+		options.InitializePGPSigningKey = true
+		repository, err := publisher.GetRepository(ctx, storage, options)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(publisher.StageReleaseTarget(ctx, repository, "1.0.0", "linux-amd64/bin/app", bytes.NewBufferString("app 1.0.0"), nil)).To(Succeed())
+		Expect(repository.CommitStaged(ctx)).To(Succeed())
+
+		options.InitializeTUFKeys = false
+		reopenedRepository, err := publisher.GetRepository(ctx, storage, options)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reopenedRepository.HasDelegatedRole(DelegatedRoleReleases)).To(BeTrue())
+		Expect(publisher.StageReleaseTarget(ctx, reopenedRepository, "1.1.0", "linux-amd64/bin/app", bytes.NewBufferString("app 1.1.0"), nil)).To(Succeed())
+		Expect(reopenedRepository.CommitStaged(ctx)).To(Succeed())
+
+		Expect(publisher.GetExistingReleases(ctx, reopenedRepository)).To(ConsistOf("1.0.0", "1.1.0"))
+
+		rootJSON, err := newTestRepositoryFilesystem(options).ReadFileBytes(ctx, "1.root.json")
+		Expect(err).NotTo(HaveOccurred())
+
+		client := newTestTufClient(repoUrl, rootJSON)
+		_, err = client.Update()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(downloadTestTarget(client, "releases/1.1.0/linux-amd64/bin/app")).To(Equal("app 1.1.0"))
+	})
+
+	It("should rotate the delegated targets roles", func() {
+		publisher := NewPublisher(hclog.NewNullLogger())
+
+		options.InitializeTUFKeys = true
+		options.InitializePGPSigningKey = true
+		repositoryInterface, err := publisher.GetRepository(ctx, storage, options)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(publisher.StageReleaseTarget(ctx, repositoryInterface, "1.0.0", "linux-amd64/bin/app", bytes.NewBufferString("app 1.0.0"), nil)).To(Succeed())
+		Expect(repositoryInterface.CommitStaged(ctx)).To(Succeed())
+
+		repository := repositoryInterface.(*Repository)
+		tufRepo := delegatingTufRepo{repository.TufRepo}
+
+		prevReleases, err := signedTargetsMeta(repository.TufRepo, "releases.json")
+		Expect(err).NotTo(HaveOccurred())
+
+		expires := time.Now().AddDate(0, 3, 0).Round(time.Second)
+		Expect(tufRepo.IncrementDelegatedTargetsVersionWithExpires(expires)).To(Succeed())
+		Expect(repository.CommitStaged(ctx)).To(Succeed())
+
+		releases, err := signedTargetsMeta(repository.TufRepo, "releases.json")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(releases.Version).To(Equal(prevReleases.Version + 1))
+		Expect(releases.Targets).To(Equal(prevReleases.Targets))
+		Expect(tufRepo.DelegatedTargetsExpires()).To(BeTemporally("==", expires))
+		Expect(repository.HasDelegatedRole(DelegatedRoleReleases)).To(BeTrue())
+
+		rootJSON, err := repository.Filesystem.ReadFileBytes(ctx, "1.root.json")
+		Expect(err).NotTo(HaveOccurred())
+
+		client := newTestTufClient(repoUrl, rootJSON)
+		_, err = client.Update()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(downloadTestTarget(client, "releases/1.0.0/linux-amd64/bin/app")).To(Equal("app 1.0.0"))
+	})
+})
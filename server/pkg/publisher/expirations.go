@@ -0,0 +1,134 @@
+package publisher
+
+import (
+	"fmt"
+	"time"
+)
+
+// MinMetadataRefreshLead leaves the periodic function enough runs to re-sign the metadata before it expires.
+const MinMetadataRefreshLead = time.Hour
+
+// MetadataExpiration is the lifetime of the TUF role metadata. The zero values keep the defaults.
+type MetadataExpiration struct {
+	// Expires is the period the metadata is signed for.
+	Expires time.Duration
+	// RefreshLead is how long before the expiration the metadata is re-signed.
+	RefreshLead time.Duration
+}
+
+type TufExpirations struct {
+	Root      MetadataExpiration
+	Targets   MetadataExpiration
+	Snapshot  MetadataExpiration
+	Timestamp MetadataExpiration
+}
+
+// defaultMetadataExpirations approximate the default calendar periods to validate the configured ones against.
+var defaultMetadataExpirations = map[string]MetadataExpiration{
+	"root":      {Expires: 365 * 24 * time.Hour, RefreshLead: 273 * 24 * time.Hour},
+	"targets":   {Expires: 90 * 24 * time.Hour, RefreshLead: 69 * 24 * time.Hour},
+	"snapshot":  {Expires: 7 * 24 * time.Hour, RefreshLead: 5 * 24 * time.Hour},
+	"timestamp": {Expires: 24 * time.Hour, RefreshLead: 20 * time.Hour},
+}
+
+// Validate rejects the expirations, which would leave the clients with the expired metadata.
+// The metadata signed by the role must not outlive the metadata it refers to, e.g. the timestamp must not outlive the snapshot.
+func (expirations TufExpirations) Validate() error {
+	var prevRole string
+	var prevExpires time.Duration
+
+	for _, role := range []string{"timestamp", "snapshot", "targets", "root"} {
+		configured := expirations.role(role)
+		if configured.Expires < 0 {
+			return fmt.Errorf("the %s expiration period must not be negative", role)
+		}
+
+		if configured.RefreshLead < 0 {
+			return fmt.Errorf("the %s refresh lead must not be negative", role)
+		}
+
+		effective := expirations.effective(role)
+		if effective.RefreshLead < MinMetadataRefreshLead {
+			return fmt.Errorf("the %s refresh lead %s must be at least %s", role, effective.RefreshLead, MinMetadataRefreshLead)
+		}
+
+		if effective.RefreshLead >= effective.Expires {
+			return fmt.Errorf("the %s refresh lead %s must be less than the expiration period %s", role, effective.RefreshLead, effective.Expires)
+		}
+
+		if prevRole != "" && effective.Expires < prevExpires {
+			return fmt.Errorf("the %s expiration period %s must not be less than the %s expiration period %s", role, effective.Expires, prevRole, prevExpires)
+		}
+
+		prevRole, prevExpires = role, effective.Expires
+	}
+
+	return nil
+}
+
+func (expirations TufExpirations) role(role string) MetadataExpiration {
+	switch role {
+	case "root":
+		return expirations.Root
+	case "targets":
+		return expirations.Targets
+	case "snapshot":
+		return expirations.Snapshot
+	case "timestamp":
+		return expirations.Timestamp
+	default:
+		panic(fmt.Sprintf("unexpected top-level role %q", role))
+	}
+}
+
+func (expirations TufExpirations) effective(role string) MetadataExpiration {
+	result := expirations.role(role)
+	if result.Expires == 0 {
+		result.Expires = defaultMetadataExpirations[role].Expires
+	}
+	if result.RefreshLead == 0 {
+		result.RefreshLead = defaultMetadataExpirations[role].RefreshLead
+	}
+	return result
+}
+
+// expiresAt returns the expiration of the role metadata signed at the moment.
+// Delegated targets roles expire as the top-level targets role.
+func (expirations TufExpirations) expiresAt(role string, now time.Time) time.Time {
+	if expires := expirations.role(role).Expires; expires != 0 {
+		return now.Add(expires)
+	}
+
+	switch role {
+	case "root":
+		return now.AddDate(1, 0, 0)
+	case "targets":
+		return now.AddDate(0, 3, 0)
+	case "snapshot":
+		return now.AddDate(0, 0, 7)
+	default:
+		return now.AddDate(0, 0, 1)
+	}
+}
+
+// refreshAt returns the moment to re-sign the role metadata expiring at the time.
+func (expirations TufExpirations) refreshAt(role string, expiresAt time.Time) time.Time {
+	if refreshLead := expirations.role(role).RefreshLead; refreshLead != 0 {
+		return expiresAt.Add(-refreshLead)
+	}
+
+	switch role {
+	case "root":
+		// Root expires every year, rotate every 3 month.
+		return expiresAt.AddDate(-1, 0, 0).AddDate(0, 3, 0)
+	case "targets":
+		// Targets expires every 3 month, rotate every 3 weeks.
+		return expiresAt.AddDate(0, -3, 0).AddDate(0, 0, 21)
+	case "snapshot":
+		// Snapshot expires every 7 days, rotate every 2nd day.
+		return expiresAt.AddDate(0, 0, -7).AddDate(0, 0, 2)
+	default:
+		// Timestamp expires every day, rotate every 4th hour.
+		return expiresAt.AddDate(0, 0, -1).Add(time.Hour * 4)
+	}
+}
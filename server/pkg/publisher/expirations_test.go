@@ -0,0 +1,32 @@
+package publisher
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TUF expirations", func() {
+	DescribeTable("validation",
+		func(expirations TufExpirations, expectedErr string) {
+			err := expirations.Validate()
+			if expectedErr == "" {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+			}
+		},
+		Entry("defaults", TufExpirations{}, ""),
+		Entry("longer timestamp and shorter targets",
+			TufExpirations{
+				Targets:   MetadataExpiration{Expires: 30 * 24 * time.Hour, RefreshLead: 10 * 24 * time.Hour},
+				Timestamp: MetadataExpiration{Expires: 72 * time.Hour, RefreshLead: 48 * time.Hour},
+			}, ""),
+		Entry("negative expiration", TufExpirations{Root: MetadataExpiration{Expires: -time.Hour}}, "the root expiration period must not be negative"),
+		Entry("refresh lead exceeding the expiration", TufExpirations{Snapshot: MetadataExpiration{RefreshLead: 8 * 24 * time.Hour}}, "the snapshot refresh lead 192h0m0s must be less than the expiration period 168h0m0s"),
+		Entry("default refresh lead exceeding the expiration", TufExpirations{Targets: MetadataExpiration{Expires: 30 * 24 * time.Hour}}, "the targets refresh lead"),
+		Entry("too short refresh lead", TufExpirations{Timestamp: MetadataExpiration{RefreshLead: time.Minute}}, "the timestamp refresh lead 1m0s must be at least 1h0m0s"),
+		Entry("timestamp outliving snapshot", TufExpirations{Timestamp: MetadataExpiration{Expires: 10 * 24 * time.Hour}}, "the snapshot expiration period 168h0m0s must not be less than the timestamp expiration period 240h0m0s"),
+	)
+})
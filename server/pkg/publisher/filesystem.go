@@ -12,4 +12,5 @@ type Filesystem interface {
 	ReadFileBytes(ctx context.Context, path string) ([]byte, error)
 	WriteFileBytes(ctx context.Context, path string, data []byte) error
 	WriteFileStream(ctx context.Context, path string, reader io.Reader) error
+	DeleteFile(ctx context.Context, path string) error
 }
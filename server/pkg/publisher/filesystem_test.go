@@ -0,0 +1,125 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/hashicorp/go-hclog"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// The S3 specs run only against a real empty bucket, e.g. the MinIO one from the dev stand.
+func s3TestRepositoryOptions() (RepositoryOptions, bool) {
+	options := RepositoryOptions{
+		StorageBackend:    StorageBackendS3,
+		S3Endpoint:        os.Getenv("TRDL_TEST_S3_ENDPOINT"),
+		S3Region:          os.Getenv("TRDL_TEST_S3_REGION"),
+		S3AccessKeyID:     os.Getenv("TRDL_TEST_S3_ACCESS_KEY_ID"),
+		S3SecretAccessKey: os.Getenv("TRDL_TEST_S3_SECRET_ACCESS_KEY"),
+		S3BucketName:      os.Getenv("TRDL_TEST_S3_BUCKET_NAME"),
+	}
+
+	return options, options.S3Endpoint != "" && options.S3BucketName != ""
+}
+
+func newTestRepositoryFilesystem(options RepositoryOptions) Filesystem {
+	fs, err := newRepositoryFilesystem(options, hclog.NewNullLogger())
+	Expect(err).NotTo(HaveOccurred())
+
+	return fs
+}
+
+func itBehavesLikeFilesystem(newFilesystem func() Filesystem) {
+	var fs Filesystem
+	ctx := context.Background()
+
+	BeforeEach(func() {
+		fs = newFilesystem()
+	})
+
+	It("should report a missing file as not existing", func() {
+		Expect(fs.IsFileExist(ctx, "missing/file.txt")).To(BeFalse())
+	})
+
+	It("should write and read file bytes", func() {
+		Expect(fs.WriteFileBytes(ctx, "dir/file.txt", []byte("data"))).To(Succeed())
+
+		Expect(fs.IsFileExist(ctx, "dir/file.txt")).To(BeTrue())
+		Expect(fs.ReadFileBytes(ctx, "dir/file.txt")).To(Equal([]byte("data")))
+	})
+
+	It("should write and read file streams", func() {
+		Expect(fs.WriteFileStream(ctx, "dir/stream.txt", bytes.NewBufferString("stream data"))).To(Succeed())
+
+		buf := bytes.NewBuffer(nil)
+		Expect(fs.ReadFileStream(ctx, "dir/stream.txt", buf)).To(Succeed())
+		Expect(buf.String()).To(Equal("stream data"))
+
+		writerAt := aws.NewWriteAtBuffer(nil)
+		Expect(fs.ReadFile(ctx, "dir/stream.txt", writerAt)).To(Succeed())
+		Expect(string(writerAt.Bytes())).To(Equal("stream data"))
+	})
+
+	It("should overwrite an existing file", func() {
+		Expect(fs.WriteFileBytes(ctx, "file.txt", []byte("old data"))).To(Succeed())
+		Expect(fs.WriteFileBytes(ctx, "file.txt", []byte("new"))).To(Succeed())
+
+		Expect(fs.ReadFileBytes(ctx, "file.txt")).To(Equal([]byte("new")))
+	})
+
+	It("should delete a file", func() {
+		Expect(fs.WriteFileBytes(ctx, "dir/deleted.txt", []byte("data"))).To(Succeed())
+		Expect(fs.DeleteFile(ctx, "dir/deleted.txt")).To(Succeed())
+
+		Expect(fs.IsFileExist(ctx, "dir/deleted.txt")).To(BeFalse())
+	})
+
+	It("should not fail to delete a missing file", func() {
+		Expect(fs.DeleteFile(ctx, "missing/file.txt")).To(Succeed())
+	})
+
+	It("should fail to read a missing file", func() {
+		_, err := fs.ReadFileBytes(ctx, "missing/file.txt")
+		Expect(err).To(HaveOccurred())
+	})
+}
+
+var _ = Describe("Filesystem", func() {
+	Context("LocalFilesystem", func() {
+		itBehavesLikeFilesystem(func() Filesystem {
+			return NewLocalFilesystem(GinkgoT().TempDir(), hclog.NewNullLogger())
+		})
+
+		It("should keep files inside the directory", func() {
+			dir := GinkgoT().TempDir()
+			fs := NewLocalFilesystem(dir, hclog.NewNullLogger())
+
+			Expect(fs.WriteFileBytes(context.Background(), "../../outside.txt", []byte("data"))).To(Succeed())
+			Expect(os.ReadFile(dir + "/outside.txt")).To(Equal([]byte("data")))
+		})
+	})
+
+	Context("S3Filesystem", func() {
+		itBehavesLikeFilesystem(func() Filesystem {
+			options, ok := s3TestRepositoryOptions()
+			if !ok {
+				Skip("TRDL_TEST_S3_ENDPOINT and TRDL_TEST_S3_BUCKET_NAME are not set")
+			}
+
+			return NewS3Filesystem(&aws.Config{
+				Endpoint:    aws.String(options.S3Endpoint),
+				Region:      aws.String(options.S3Region),
+				Credentials: credentials.NewStaticCredentials(options.S3AccessKeyID, options.S3SecretAccessKey, ""),
+			}, options.S3BucketName, hclog.NewNullLogger())
+		})
+	})
+
+	It("should fail for an unknown storage backend", func() {
+		_, err := newRepositoryFilesystem(RepositoryOptions{StorageBackend: "gcs"}, hclog.NewNullLogger())
+		Expect(err).To(MatchError(`unexpected storage backend "gcs"`))
+	})
+})
@@ -3,8 +3,10 @@ package publisher
 import (
 	"context"
 	"io"
+	"time"
 
 	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/theupdateframework/go-tuf/data"
 
 	"github.com/werf/trdl/server/pkg/config"
 	"github.com/werf/trdl/server/pkg/elf_signing"
@@ -16,9 +18,16 @@ type Interface interface {
 	RotateRepositoryKeys(ctx context.Context, storage logical.Storage, repository RepositoryInterface, systemClock util.Clock) error
 	UpdateTimestamps(ctx context.Context, storage logical.Storage, repository RepositoryInterface, systemClock util.Clock) error
 	StageReleaseTarget(ctx context.Context, repository RepositoryInterface, releaseName, path string, data io.Reader, elfSigner *elf_signing.ELFSigner) error
-	StageChannelsConfig(ctx context.Context, repository RepositoryInterface, trdlChannelsConfig *config.TrdlChannels) error
+	StageChannelsConfig(ctx context.Context, storage logical.Storage, repository RepositoryInterface, trdlChannelsConfig *config.TrdlChannels, opts StageChannelsConfigOptions) error
 	StageInMemoryFiles(ctx context.Context, repository RepositoryInterface, files []*InMemoryFile) error
 	GetExistingReleases(ctx context.Context, repository RepositoryInterface) ([]string, error)
+	GetReleaseTargets(ctx context.Context, repository RepositoryInterface, releaseName string) ([]ReleaseTarget, error)
+	RemoveReleaseTargets(ctx context.Context, repository RepositoryInterface, releaseName string) error
+	ImportRoot(ctx context.Context, storage logical.Storage, repository RepositoryInterface, signed *data.Signed) error
+	UpdateRoleKeys(ctx context.Context, storage logical.Storage, repository RepositoryInterface, role string, opts UpdateRoleKeysOptions) error
+	RotateRoleKeys(ctx context.Context, storage logical.Storage, repository RepositoryInterface, roles []string, opts RotateRoleKeysOptions) (map[string][]string, error)
+	YankRelease(ctx context.Context, storage logical.Storage, repository RepositoryInterface, releaseName string, opts YankReleaseOptions) error
+	GetYankedReleases(ctx context.Context, repository RepositoryInterface) ([]YankedRelease, error)
 }
 
 type RepositoryInterface interface {
@@ -28,7 +37,20 @@ type RepositoryInterface interface {
 	GenPrivKeys() error
 	RotatePrivKeys(ctx context.Context) (bool, TufRepoPrivKeys, error)
 	UpdateTimestamps(ctx context.Context, systemClock util.Clock) error
-	StageTarget(ctx context.Context, pathInsideTargets string, data io.Reader) error
+	StageTarget(ctx context.Context, pathInsideTargets string, data io.Reader, opts StageTargetOptions) error
+	RemoveTargets(ctx context.Context, pathsInsideTargets []string) error
 	CommitStaged(ctx context.Context) error
+	DiscardStaged(ctx context.Context) error
 	GetTargets(ctx context.Context) ([]string, error)
+	GetTargetFiles(ctx context.Context) (data.TargetFiles, error)
+	HasDelegatedRole(name string) (bool, error)
+	AddDelegatedRole(name string, paths []string, opts AddDelegatedRoleOptions) error
+	RootCandidate(rootKeys []*data.PublicKey, threshold int, expires time.Time) (*data.Signed, error)
+	ImportRoot(signed *data.Signed) error
+	PruneUntrustedKeys() error
+	RoleKeys(role string) ([]RoleKey, int, error)
+	UpdateRoleKeys(role string, opts UpdateRoleKeysOptions) error
+	RotateRoleKey(ctx context.Context, role string) error
+	RoleStatuses() ([]RoleStatus, error)
+	GetChannels(ctx context.Context) ([]PublishedChannel, error)
 }
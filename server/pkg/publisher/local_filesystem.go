@@ -0,0 +1,163 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+var _ Filesystem = (*LocalFilesystem)(nil)
+
+type LocalFilesystem struct {
+	Directory string
+
+	logger hclog.Logger
+}
+
+func NewLocalFilesystem(directory string, logger hclog.Logger) *LocalFilesystem {
+	return &LocalFilesystem{Directory: directory, logger: logger}
+}
+
+func (fs *LocalFilesystem) IsFileExist(_ context.Context, path string) (bool, error) {
+	filePath, err := fs.filePath(path)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = os.Stat(filePath)
+	fs.logger.Debug(fmt.Sprintf("-- LocalFilesystem.IsFileExist %q err=%v", path, err))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("error checking file %q: %w", filePath, err)
+	}
+
+	return true, nil
+}
+
+func (fs *LocalFilesystem) ReadFile(ctx context.Context, path string, writerAt io.WriterAt) error {
+	data, err := fs.ReadFileBytes(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := writerAt.WriteAt(data, 0); err != nil {
+		return fmt.Errorf("unable to write %q data: %w", path, err)
+	}
+
+	return nil
+}
+
+func (fs *LocalFilesystem) ReadFileStream(_ context.Context, path string, writer io.Writer) error {
+	filePath, err := fs.filePath(path)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("unable to open file %q: %w", filePath, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	numBytes, err := io.Copy(writer, f)
+	if err != nil {
+		return fmt.Errorf("unable to read file %q: %w", filePath, err)
+	}
+
+	fs.logger.Debug(fmt.Sprintf("-- LocalFilesystem.ReadFileStream read %q %d bytes", path, numBytes))
+
+	return nil
+}
+
+func (fs *LocalFilesystem) ReadFileBytes(ctx context.Context, path string) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	if err := fs.ReadFileStream(ctx, path, buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (fs *LocalFilesystem) WriteFileBytes(ctx context.Context, path string, data []byte) error {
+	return fs.WriteFileStream(ctx, path, bytes.NewReader(data))
+}
+
+// WriteFileStream writes data into a temporary file next to the destination and renames it,
+// so that a web server serving the directory never exposes a partially written file.
+func (fs *LocalFilesystem) WriteFileStream(_ context.Context, path string, data io.Reader) error {
+	filePath, err := fs.filePath(path)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return fmt.Errorf("unable to create directory for %q: %w", filePath, err)
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("unable to create temporary file for %q: %w", filePath, err)
+	}
+	tmpFilePath := tmpFile.Name()
+	defer func() { _ = os.Remove(tmpFilePath) }()
+
+	numBytes, err := io.Copy(tmpFile, data)
+	if err != nil {
+		_ = tmpFile.Close()
+		return fmt.Errorf("error writing %q: %w", filePath, err)
+	}
+
+	if err := tmpFile.Sync(); err != nil {
+		_ = tmpFile.Close()
+		return fmt.Errorf("unable to sync %q: %w", tmpFilePath, err)
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("unable to close %q: %w", tmpFilePath, err)
+	}
+
+	if err := os.Chmod(tmpFilePath, 0o644); err != nil {
+		return fmt.Errorf("unable to chmod %q: %w", tmpFilePath, err)
+	}
+
+	if err := os.Rename(tmpFilePath, filePath); err != nil {
+		return fmt.Errorf("unable to rename %q to %q: %w", tmpFilePath, filePath, err)
+	}
+
+	fs.logger.Debug(fmt.Sprintf("-- LocalFilesystem.WriteFileStream written %q %d bytes", path, numBytes))
+
+	return nil
+}
+
+func (fs *LocalFilesystem) DeleteFile(_ context.Context, path string) error {
+	filePath, err := fs.filePath(path)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(filePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("unable to remove %q: %w", filePath, err)
+	}
+
+	fs.logger.Debug(fmt.Sprintf("-- LocalFilesystem.DeleteFile deleted %q", path))
+
+	return nil
+}
+
+func (fs *LocalFilesystem) filePath(path string) (string, error) {
+	cleanPath := filepath.Clean(filepath.FromSlash("/" + path))
+	if strings.Trim(cleanPath, string(filepath.Separator)) == "" {
+		return "", fmt.Errorf("bad file path %q", path)
+	}
+
+	return filepath.Join(fs.Directory, cleanPath), nil
+}
@@ -0,0 +1,157 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
+	"github.com/theupdateframework/go-tuf/pkg/keys"
+
+	"github.com/werf/trdl/server/pkg/config"
+)
+
+var _ = Describe("Multiple role keys", func() {
+	var (
+		ctx       context.Context
+		storage   logical.Storage
+		publisher *Publisher
+		options   RepositoryOptions
+		repoUrl   string
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		storage = &logical.InmemStorage{}
+		publisher = NewPublisher(hclog.NewNullLogger())
+
+		dir := GinkgoT().TempDir()
+		server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+		DeferCleanup(server.Close)
+
+		options = RepositoryOptions{
+			StorageBackend:          StorageBackendLocal,
+			LocalDirectory:          dir,
+			InitializeTUFKeys:       true,
+			InitializePGPSigningKey: true,
+		}
+		repoUrl = server.URL
+	})
+
+	getRepository := func() *Repository {
+		repository, err := publisher.GetRepository(ctx, storage, options)
+		Expect(err).NotTo(HaveOccurred())
+		return repository.(*Repository)
+	}
+
+	publishRelease := func(release string) *Repository {
+		repository := getRepository()
+		Expect(publisher.StageReleaseTarget(ctx, repository, release, "linux-amd64/bin/app", bytes.NewBufferString("app "+release), nil)).To(Succeed())
+		Expect(publisher.StageChannelsConfig(ctx, storage, repository, &config.TrdlChannels{
+			Groups: []config.TrdlGroup{
+				{Name: "1", Channels: []config.TrdlGroupChannel{{Name: "stable", Version: release}}},
+			},
+		}, StageChannelsConfigOptions{})).To(Succeed())
+		Expect(repository.CommitStaged(ctx)).To(Succeed())
+		return repository
+	}
+
+	storedPrivKeys := func() TufRepoPrivKeys {
+		entry, err := storage.Get(ctx, storageKeyTufRepositoryKeys)
+		Expect(err).NotTo(HaveOccurred())
+		var privKeys TufRepoPrivKeys
+		Expect(json.Unmarshal(entry.Value, &privKeys)).To(Succeed())
+		return privKeys
+	}
+
+	expectClientToUpdate := func(release string) {
+		rootJSON, err := newTestRepositoryFilesystem(options).ReadFileBytes(ctx, "1.root.json")
+		Expect(err).NotTo(HaveOccurred())
+
+		client := newTestTufClient(repoUrl, rootJSON)
+		_, err = client.Update()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(downloadTestTarget(client, "releases/"+release+"/linux-amd64/bin/app")).To(Equal("app " + release))
+	}
+
+	roleKeyIDs := func(repository *Repository, role string) []string {
+		roleKeys, _, err := repository.RoleKeys(role)
+		Expect(err).NotTo(HaveOccurred())
+		return lo.Map(roleKeys, func(roleKey RoleKey, _ int) string { return roleKey.KeyID })
+	}
+
+	It("should sign the roles with the threshold of the stored keys", func() {
+		repository := publishRelease("1.0.0")
+
+		By("adding the root and targets keys")
+		Expect(publisher.UpdateRoleKeys(ctx, storage, repository, "root", UpdateRoleKeysOptions{AddKeys: 2, Threshold: 2})).To(Succeed())
+		Expect(publisher.UpdateRoleKeys(ctx, storage, getRepository(), "targets", UpdateRoleKeysOptions{AddKeys: 1, Threshold: 2})).To(Succeed())
+
+		repository = getRepository()
+		for role, keysNumber := range map[string]int{"root": 3, "targets": 2} {
+			roleKeys, threshold, err := repository.RoleKeys(role)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(threshold).To(Equal(2))
+			Expect(roleKeys).To(HaveLen(keysNumber))
+			for _, roleKey := range roleKeys {
+				Expect(roleKey.Location).To(Equal(RoleKeyLocationStored))
+			}
+		}
+		Expect(storedPrivKeys().Root).To(HaveLen(3))
+		Expect(storedPrivKeys().Targets).To(HaveLen(2))
+
+		expectClientToUpdate("1.0.0")
+
+		By("publishing with the new keys")
+		publishRelease("1.1.0")
+		expectClientToUpdate("1.1.0")
+
+		By("revoking the root key and pruning it from the storage")
+		removedKeyID := roleKeyIDs(repository, "root")[0]
+		Expect(publisher.UpdateRoleKeys(ctx, storage, getRepository(), "root", UpdateRoleKeysOptions{RemoveKeyIDs: []string{removedKeyID}})).To(Succeed())
+
+		repository = getRepository()
+		Expect(roleKeyIDs(repository, "root")).To(HaveLen(2))
+		Expect(roleKeyIDs(repository, "root")).NotTo(ContainElement(removedKeyID))
+		Expect(storedPrivKeys().Root).To(HaveLen(2))
+		Expect(toPublicKeyIDs(storedPrivKeys().Root)).NotTo(ContainElement(removedKeyID))
+
+		publishRelease("1.2.0")
+		expectClientToUpdate("1.2.0")
+	})
+
+	It("should refuse the invalid threshold", func() {
+		repository := publishRelease("1.0.0")
+
+		Expect(publisher.UpdateRoleKeys(ctx, storage, repository, "targets", UpdateRoleKeysOptions{Threshold: 2})).
+			To(MatchError(ContainSubstring("invalid targets threshold 2")))
+
+		keyID := roleKeyIDs(repository, "targets")[0]
+		Expect(publisher.UpdateRoleKeys(ctx, storage, repository, "targets", UpdateRoleKeysOptions{RemoveKeyIDs: []string{keyID}})).
+			To(MatchError(ContainSubstring("invalid targets threshold 1")))
+
+		Expect(publisher.UpdateRoleKeys(ctx, storage, repository, "targets", UpdateRoleKeysOptions{RemoveKeyIDs: []string{"unknown"}})).
+			To(MatchError(ContainSubstring("no targets key unknown")))
+	})
+
+	It("should read the single key stored before the roles got multiple keys", func() {
+		key, err := keys.GenerateEd25519Key()
+		Expect(err).NotTo(HaveOccurred())
+		privateKey, err := key.MarshalPrivateKey()
+		Expect(err).NotTo(HaveOccurred())
+
+		legacyJSON, err := json.Marshal(map[string]interface{}{"root": privateKey, "targets": nil})
+		Expect(err).NotTo(HaveOccurred())
+
+		var privKeys TufRepoPrivKeys
+		Expect(json.Unmarshal(legacyJSON, &privKeys)).To(Succeed())
+		Expect(toPublicKeyIDs(privKeys.Root)).To(Equal(key.PublicData().IDs()))
+		Expect(privKeys.Targets).To(BeEmpty())
+	})
+})
@@ -0,0 +1,225 @@
+package publisher
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/theupdateframework/go-tuf"
+	"github.com/theupdateframework/go-tuf/data"
+	"github.com/theupdateframework/go-tuf/pkg/keys"
+	"github.com/theupdateframework/go-tuf/sign"
+	"github.com/theupdateframework/go-tuf/verify"
+)
+
+var onlineRoles = []string{"targets", "snapshot", "timestamp"}
+
+var ErrRootKeyOffline = errors.New("the root key is offline: export the root candidate, sign it with the offline root keys and import it")
+
+// IsRootKeyOffline reports whether the plugin has not enough root keys to sign the root metadata.
+func (repository *Repository) IsRootKeyOffline() (bool, error) {
+	root, err := repository.root()
+	if err != nil {
+		return false, err
+	}
+
+	roleData, ok := root.Roles["root"]
+	if !ok {
+		return true, nil
+	}
+
+	signers, err := repository.TufStore.GetSigners("root")
+	if err != nil {
+		return false, fmt.Errorf("unable to get root key signers: %w", err)
+	}
+
+	trustedSigners := lo.Filter(signers, func(signer keys.Signer, _ int) bool {
+		return lo.Some(roleData.KeyIDs, signer.PublicData().IDs())
+	})
+
+	return len(trustedSigners) < roleData.Threshold, nil
+}
+
+// RootCandidate returns the unsigned root metadata of the next version to be signed with the offline root keys.
+// The root keys are replaced with the specified ones unless the list is empty, the online roles keys are kept.
+func (repository *Repository) RootCandidate(rootKeys []*data.PublicKey, threshold int, expires time.Time) (*data.Signed, error) {
+	root, err := repository.root()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rootKeys) > 0 {
+		if threshold < 1 || threshold > len(rootKeys) {
+			return nil, fmt.Errorf("invalid root threshold %d: expected a value between 1 and the number of root keys %d", threshold, len(rootKeys))
+		}
+
+		if roleData, ok := root.Roles["root"]; ok {
+			for _, keyID := range roleData.KeyIDs {
+				if !isKeyIDUsedByRoles(root, keyID, onlineRoles) {
+					delete(root.Keys, keyID)
+				}
+			}
+		}
+
+		roleData := &data.Role{Threshold: threshold}
+		for _, key := range rootKeys {
+			root.AddKey(key)
+			roleData.KeyIDs = append(roleData.KeyIDs, key.IDs()...)
+		}
+		roleData.KeyIDs = lo.Uniq(roleData.KeyIDs)
+		sort.Strings(roleData.KeyIDs)
+
+		root.Roles["root"] = roleData
+	}
+
+	root.Version++
+	root.Expires = expires.Round(time.Second).UTC()
+
+	signed, err := json.Marshal(root)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal root metadata: %w", err)
+	}
+
+	return &data.Signed{Signed: signed, Signatures: []data.Signature{}}, nil
+}
+
+// ImportRoot stages the root metadata signed with the offline root keys.
+// The root must be the next version signed both by the current and the new root keys, the online roles keys must be kept.
+// The new root is cross-signed with the root keys of the plugin if there are any, so it is possible to move the root key offline.
+// The stored root keys are not trusted anymore and must be pruned after the root is committed.
+func (repository *Repository) ImportRoot(signed *data.Signed) error {
+	currentRoot, err := repository.root()
+	if err != nil {
+		return err
+	}
+
+	newRoot := &data.Root{}
+	if err := json.Unmarshal(signed.Signed, newRoot); err != nil {
+		return fmt.Errorf("unable to unmarshal root metadata: %w", err)
+	}
+
+	if newRoot.Type != "root" {
+		return fmt.Errorf("unexpected metadata type %q: expected root", newRoot.Type)
+	}
+
+	if newRoot.Roles["root"] == nil {
+		return errors.New("no root role in root")
+	}
+
+	if newRoot.Version != currentRoot.Version+1 {
+		return fmt.Errorf("unexpected root version %d: expected %d", newRoot.Version, currentRoot.Version+1)
+	}
+
+	if !newRoot.Expires.After(time.Now()) {
+		return fmt.Errorf("root expired at %s", newRoot.Expires)
+	}
+
+	if newRoot.ConsistentSnapshot != currentRoot.ConsistentSnapshot {
+		return fmt.Errorf("unable to change consistent snapshot to %t", newRoot.ConsistentSnapshot)
+	}
+
+	for _, role := range onlineRoles {
+		if err := checkRoleKeysKept(currentRoot, newRoot, role); err != nil {
+			return err
+		}
+	}
+
+	signers, err := repository.TufStore.GetSigners("root")
+	if err != nil {
+		return fmt.Errorf("unable to get root key signers: %w", err)
+	}
+
+	for _, signer := range signers {
+		if lo.Some(newRoot.Roles["root"].KeyIDs, signer.PublicData().IDs()) {
+			return fmt.Errorf("the root key %s is kept in the plugin: the new root keys must be offline", signer.PublicData().IDs()[0])
+		}
+
+		if !lo.Some(currentRoot.Roles["root"].KeyIDs, signer.PublicData().IDs()) {
+			continue
+		}
+
+		if err := sign.Sign(signed, signer); err != nil {
+			return fmt.Errorf("unable to cross-sign root: %w", err)
+		}
+	}
+
+	for _, desc := range []struct {
+		name string
+		root *data.Root
+	}{
+		{"current", currentRoot},
+		{"new", newRoot},
+	} {
+		db, err := rootKeysDB(desc.root)
+		if err != nil {
+			return fmt.Errorf("unable to get %s root keys: %w", desc.name, err)
+		}
+
+		if err := db.VerifySignatures(signed, "root"); err != nil {
+			return fmt.Errorf("root is not signed by the %s root keys: %w", desc.name, err)
+		}
+	}
+
+	rootJSON, err := json.Marshal(signed)
+	if err != nil {
+		return fmt.Errorf("unable to marshal root metadata: %w", err)
+	}
+
+	if err := repository.TufStore.SetMeta("root.json", rootJSON); err != nil {
+		return fmt.Errorf("unable to stage root metadata: %w", err)
+	}
+
+	// The TUF repo reads the metadata only on creation.
+	tufRepo, err := tuf.NewRepo(repository.TufStore)
+	if err != nil {
+		return fmt.Errorf("error initializing tuf repo: %w", err)
+	}
+	repository.TufRepo = tufRepo
+
+	return nil
+}
+
+func checkRoleKeysKept(currentRoot, newRoot *data.Root, role string) error {
+	currentRoleData, newRoleData := currentRoot.Roles[role], newRoot.Roles[role]
+	if currentRoleData == nil || newRoleData == nil {
+		return fmt.Errorf("no %s role in root", role)
+	}
+
+	currentKeyIDs, newKeyIDs := currentRoleData.KeyIDs, newRoleData.KeyIDs
+	if newRoleData.Threshold != currentRoleData.Threshold || !lo.Every(currentKeyIDs, newKeyIDs) || !lo.Every(newKeyIDs, currentKeyIDs) {
+		return fmt.Errorf("the %s role keys are changed: the online roles keys are managed by the plugin", role)
+	}
+
+	for _, keyID := range newKeyIDs {
+		if _, ok := newRoot.Keys[keyID]; !ok {
+			return fmt.Errorf("no %s key %s in root", role, keyID)
+		}
+	}
+
+	return nil
+}
+
+func isKeyIDUsedByRoles(root *data.Root, keyID string, roles []string) bool {
+	return lo.SomeBy(roles, func(role string) bool {
+		roleData, ok := root.Roles[role]
+		return ok && lo.Contains(roleData.KeyIDs, keyID)
+	})
+}
+
+func rootKeysDB(root *data.Root) (*verify.DB, error) {
+	db := verify.NewDB()
+	for id, key := range root.Keys {
+		if err := db.AddKey(id, key); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := db.AddRole("root", root.Roles["root"]); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
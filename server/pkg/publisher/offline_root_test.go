@@ -0,0 +1,186 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/theupdateframework/go-tuf/data"
+	"github.com/theupdateframework/go-tuf/pkg/keys"
+	"github.com/theupdateframework/go-tuf/sign"
+
+	"github.com/werf/trdl/server/pkg/config"
+)
+
+var _ = Describe("Offline root", func() {
+	var (
+		ctx         context.Context
+		storage     logical.Storage
+		publisher   *Publisher
+		options     RepositoryOptions
+		repoUrl     string
+		offlineKeys []keys.Signer
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		storage = &logical.InmemStorage{}
+		publisher = NewPublisher(hclog.NewNullLogger())
+
+		dir := GinkgoT().TempDir()
+		server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+		DeferCleanup(server.Close)
+
+		options = RepositoryOptions{
+			StorageBackend:          StorageBackendLocal,
+			LocalDirectory:          dir,
+			InitializeTUFKeys:       true,
+			InitializePGPSigningKey: true,
+		}
+		repoUrl = server.URL
+
+		offlineKeys = nil
+		for i := 0; i < 2; i++ {
+			key, err := keys.GenerateEd25519Key()
+			Expect(err).NotTo(HaveOccurred())
+			offlineKeys = append(offlineKeys, key)
+		}
+	})
+
+	publishRelease := func(release string) *Repository {
+		repository, err := publisher.GetRepository(ctx, storage, options)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(publisher.StageReleaseTarget(ctx, repository, release, "linux-amd64/bin/app", bytes.NewBufferString("app "+release), nil)).To(Succeed())
+		Expect(publisher.StageChannelsConfig(ctx, storage, repository, &config.TrdlChannels{
+			Groups: []config.TrdlGroup{
+				{Name: "1", Channels: []config.TrdlGroupChannel{{Name: "stable", Version: release}}},
+			},
+		}, StageChannelsConfigOptions{})).To(Succeed())
+		Expect(repository.CommitStaged(ctx)).To(Succeed())
+		return repository.(*Repository)
+	}
+
+	getRepository := func() *Repository {
+		repository, err := publisher.GetRepository(ctx, storage, options)
+		Expect(err).NotTo(HaveOccurred())
+		return repository.(*Repository)
+	}
+
+	offlinePublicKeys := func() []*data.PublicKey {
+		var publicKeys []*data.PublicKey
+		for _, key := range offlineKeys {
+			publicKeys = append(publicKeys, key.PublicData())
+		}
+		return publicKeys
+	}
+
+	// signCandidate signs the candidate the way the offline tooling does, the signed data is kept as is.
+	signCandidate := func(candidate *data.Signed, signers ...keys.Signer) *data.Signed {
+		candidateJSON, err := json.Marshal(candidate)
+		Expect(err).NotTo(HaveOccurred())
+
+		signed := &data.Signed{}
+		Expect(json.Unmarshal(candidateJSON, signed)).To(Succeed())
+		for _, signer := range signers {
+			Expect(sign.Sign(signed, signer)).To(Succeed())
+		}
+		return signed
+	}
+
+	expectClientToUpdate := func(release string) {
+		rootJSON, err := newTestRepositoryFilesystem(options).ReadFileBytes(ctx, "1.root.json")
+		Expect(err).NotTo(HaveOccurred())
+
+		client := newTestTufClient(repoUrl, rootJSON)
+		_, err = client.Update()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(downloadTestTarget(client, "releases/"+release+"/linux-amd64/bin/app")).To(Equal("app " + release))
+	}
+
+	It("should move the root key offline and keep publishing with the online roles", func() {
+		repository := publishRelease("1.0.0")
+		onlineRoot, err := repository.root()
+		Expect(err).NotTo(HaveOccurred())
+
+		candidate, err := repository.RootCandidate(offlinePublicKeys(), 2, time.Now().AddDate(1, 0, 0))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(candidate.Signatures).To(BeEmpty())
+
+		By("refusing the root signed with less than the threshold of the offline keys")
+		Expect(publisher.ImportRoot(ctx, storage, getRepository(), signCandidate(candidate, offlineKeys[0]))).
+			To(MatchError(ContainSubstring("root is not signed by the new root keys")))
+
+		By("importing the root signed with the offline keys and cross-signed with the stored root key")
+		repository = getRepository()
+		Expect(publisher.ImportRoot(ctx, storage, repository, signCandidate(candidate, offlineKeys...))).To(Succeed())
+
+		root, err := repository.root()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(root.Version).To(Equal(onlineRoot.Version + 1))
+		Expect(root.Roles["root"].Threshold).To(Equal(2))
+		Expect(root.Roles["targets"]).To(Equal(onlineRoot.Roles["targets"]))
+
+		entry, err := storage.Get(ctx, storageKeyTufRepositoryKeys)
+		Expect(err).NotTo(HaveOccurred())
+		var storedPrivKeys TufRepoPrivKeys
+		Expect(json.Unmarshal(entry.Value, &storedPrivKeys)).To(Succeed())
+		Expect(storedPrivKeys.Root).To(BeEmpty())
+		Expect(storedPrivKeys.Targets).To(HaveLen(1))
+
+		expectClientToUpdate("1.0.0")
+
+		By("publishing with the online roles")
+		repository = publishRelease("1.1.0")
+		Expect(repository.IsRootKeyOffline()).To(BeTrue())
+		expectClientToUpdate("1.1.0")
+
+		By("refusing to change the root in the plugin")
+		Expect(repository.RotateRoleKey(ctx, "targets")).To(MatchError(ErrRootKeyOffline))
+
+		By("re-signing the root with the offline keys only")
+		repository = getRepository()
+		candidate, err = repository.RootCandidate(nil, 0, time.Now().AddDate(1, 0, 0))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(publisher.ImportRoot(ctx, storage, repository, signCandidate(candidate, offlineKeys...))).To(Succeed())
+
+		root, err = repository.root()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(root.Version).To(Equal(onlineRoot.Version + 2))
+
+		expectClientToUpdate("1.1.0")
+	})
+
+	It("should refuse the root changing the online roles keys", func() {
+		repository := publishRelease("1.0.0")
+
+		candidate, err := repository.RootCandidate(offlinePublicKeys(), 1, time.Now().AddDate(1, 0, 0))
+		Expect(err).NotTo(HaveOccurred())
+
+		root := &data.Root{}
+		Expect(json.Unmarshal(candidate.Signed, root)).To(Succeed())
+		root.Roles["targets"] = root.Roles["root"]
+		candidate.Signed, err = json.Marshal(root)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(publisher.ImportRoot(ctx, storage, getRepository(), signCandidate(candidate, offlineKeys...))).
+			To(MatchError(ContainSubstring("the targets role keys are changed")))
+	})
+
+	It("should refuse the root of an unexpected version", func() {
+		repository := publishRelease("1.0.0")
+
+		candidate, err := repository.RootCandidate(offlinePublicKeys(), 1, time.Now().AddDate(1, 0, 0))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(publisher.ImportRoot(ctx, storage, getRepository(), signCandidate(candidate, offlineKeys...))).To(Succeed())
+
+		Expect(publisher.ImportRoot(ctx, storage, getRepository(), signCandidate(candidate, offlineKeys...))).
+			To(MatchError(ContainSubstring("unexpected root version")))
+	})
+})
@@ -3,6 +3,7 @@ package publisher
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -17,10 +18,14 @@ import (
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/samber/lo"
+	"github.com/theupdateframework/go-tuf/data"
+	"github.com/theupdateframework/go-tuf/pkg/keys"
 
 	"github.com/werf/trdl/server/pkg/config"
 	"github.com/werf/trdl/server/pkg/elf_signing"
 	"github.com/werf/trdl/server/pkg/pgp"
+	"github.com/werf/trdl/server/pkg/transit"
 	"github.com/werf/trdl/server/pkg/util"
 )
 
@@ -32,15 +37,32 @@ const (
 var (
 	ErrUninitializedRepositoryKeys = errors.New("uninitialized repository keys")
 	ErrUninitializedPGPSigningKey  = errors.New("uninitialized pgp signing key")
+	ErrConcurrentCommit            = errors.New("the repository has been committed concurrently: the staged changes of the keys or delegations cannot be re-applied")
+)
+
+type StorageBackend string
+
+const (
+	StorageBackendS3    StorageBackend = "s3"
+	StorageBackendLocal StorageBackend = "local"
 )
 
 type RepositoryOptions struct {
+	StorageBackend StorageBackend
+
 	S3Endpoint        string
 	S3Region          string
 	S3AccessKeyID     string
 	S3SecretAccessKey string
 	S3BucketName      string
 
+	LocalDirectory string
+
+	ConsistentSnapshot bool
+	Expirations        TufExpirations
+	// DelegatedRoles switches the repository to the delegated targets roles, which the clients have to follow.
+	DelegatedRoles bool
+
 	InitializeTUFKeys       bool
 	InitializePGPSigningKey bool
 }
@@ -55,8 +77,10 @@ func NewErrIncorrectTargetPath(path string) error {
 }
 
 type Publisher struct {
-	mu     sync.Mutex
-	logger hclog.Logger
+	mu sync.Mutex
+	// commitMu serializes the commits of the repository handles, so the concurrent tasks stage their changes independently.
+	commitMu sync.Mutex
+	logger   hclog.Logger
 
 	PGPSigningKey *pgp.RSASigningKey
 }
@@ -72,21 +96,239 @@ func (publisher *Publisher) RotateRepositoryKeys(ctx context.Context, storage lo
 	}
 
 	if updated {
-		entry, err := logical.StorageEntryJSON(storageKeyTufRepositoryKeys, updatedPrivKeys)
+		if err := putRepositoryKeys(ctx, storage, updatedPrivKeys); err != nil {
+			return err
+		}
+
+		publisher.logger.Info("Successfully rotated repository private keys")
+	}
+
+	return nil
+}
+
+func putRepositoryKeys(ctx context.Context, storage logical.Storage, privKeys TufRepoPrivKeys) error {
+	entry, err := logical.StorageEntryJSON(storageKeyTufRepositoryKeys, privKeys)
+	if err != nil {
+		return fmt.Errorf("error creating storage json entry by key %q: %w", storageKeyTufRepositoryKeys, err)
+	}
+
+	if err := storage.Put(ctx, entry); err != nil {
+		return fmt.Errorf("error putting private keys json entry by key %q into the storage: %w", storageKeyTufRepositoryKeys, err)
+	}
+
+	return nil
+}
+
+// getRepositoryKeys returns the stored keys, the zero keys if they are not stored yet.
+func getRepositoryKeys(ctx context.Context, storage logical.Storage) (TufRepoPrivKeys, error) {
+	var privKeys TufRepoPrivKeys
+
+	entry, err := storage.Get(ctx, storageKeyTufRepositoryKeys)
+	if err != nil {
+		return privKeys, fmt.Errorf("error getting storage private keys json entry by the key %q: %w", storageKeyTufRepositoryKeys, err)
+	}
+
+	if entry == nil {
+		return privKeys, nil
+	}
+
+	if err := entry.DecodeJSON(&privKeys); err != nil {
+		return privKeys, fmt.Errorf("unable to decode keys json by the %q storage key:\n%s---\n%w", storageKeyTufRepositoryKeys, entry.Value, err)
+	}
+
+	return privKeys, nil
+}
+
+// ensureDelegatedRole adds the delegated targets role unless the repository already has it.
+// The other roles are added only to the repository switched to the delegated roles, the one with the releases role,
+// since the clients not following the delegations would not find the targets.
+// The new role key is stored right away, so it is reused if the repository changes are not committed.
+// Only the role key is merged into the stored keys: the other keys of the handle might be rotated since it has been loaded.
+func (publisher *Publisher) ensureDelegatedRole(ctx context.Context, storage logical.Storage, repository RepositoryInterface, name string, paths []string) error {
+	if name != DelegatedRoleReleases {
+		switched, err := repository.HasDelegatedRole(DelegatedRoleReleases)
 		if err != nil {
-			return fmt.Errorf("error creating storage json entry by key %q: %w", storageKeyTufRepositoryKeys, err)
+			return fmt.Errorf("unable to check delegated role %q: %w", DelegatedRoleReleases, err)
 		}
 
-		if err := storage.Put(ctx, entry); err != nil {
-			return fmt.Errorf("error putting private keys json entry by key %q into the storage: %w", storageKeyTufRepositoryKeys, err)
+		if !switched {
+			return nil
 		}
+	}
 
-		publisher.logger.Info("Successfully rotated repository private keys")
+	exists, err := repository.HasDelegatedRole(name)
+	if err != nil {
+		return fmt.Errorf("unable to check delegated role %q: %w", name, err)
+	}
+
+	if exists {
+		return nil
+	}
+
+	storedPrivKeys, err := getRepositoryKeys(ctx, storage)
+	if err != nil {
+		return err
+	}
+
+	if err := repository.AddDelegatedRole(name, paths, AddDelegatedRoleOptions{Key: storedPrivKeys.Delegations[name]}); err != nil {
+		return err
+	}
+
+	signers, err := repository.GetPrivKeys().GetSigners(name)
+	if err != nil {
+		return fmt.Errorf("unable to get key signer for the delegated role %q: %w", name, err)
+	}
+
+	for _, signer := range signers {
+		if err := storedPrivKeys.SetKeyFromSigner(name, signer); err != nil {
+			return err
+		}
 	}
 
+	if err := putRepositoryKeys(ctx, storage, storedPrivKeys); err != nil {
+		return err
+	}
+
+	publisher.logger.Info(fmt.Sprintf("Added delegated targets role %q", name))
+
 	return nil
 }
 
+// setupExternalSigners switches the roles between the external signers and the stored keys.
+// The replaced keys are committed right away and only then dropped from the storage.
+func (publisher *Publisher) setupExternalSigners(ctx context.Context, storage logical.Storage, repository *Repository, allowKeyReplacement bool) error {
+	replaced, err := repository.SetupExternalSigners(allowKeyReplacement)
+	if err != nil {
+		return err
+	}
+
+	if !replaced {
+		return nil
+	}
+
+	if err := putRepositoryKeys(ctx, storage, repository.GetPrivKeys()); err != nil {
+		return err
+	}
+
+	if err := repository.CommitStaged(ctx); err != nil {
+		return fmt.Errorf("unable to commit replaced keys: %w", err)
+	}
+
+	if err := publisher.pruneRepositoryKeys(ctx, storage, repository); err != nil {
+		return err
+	}
+
+	publisher.logger.Info("Successfully replaced repository keys")
+
+	return nil
+}
+
+// ImportRoot commits the root signed with the offline root keys and only then drops the stored root key.
+func (publisher *Publisher) ImportRoot(ctx context.Context, storage logical.Storage, repository RepositoryInterface, signed *data.Signed) error {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	if err := repository.ImportRoot(signed); err != nil {
+		return err
+	}
+
+	if err := repository.CommitStaged(ctx); err != nil {
+		return fmt.Errorf("unable to commit imported root: %w", err)
+	}
+
+	if err := publisher.pruneRepositoryKeys(ctx, storage, repository); err != nil {
+		return err
+	}
+
+	publisher.logger.Info("Successfully imported root signed with the offline root keys")
+
+	return nil
+}
+
+// UpdateRoleKeys stores the new role keys before the root is committed and prunes the revoked keys after that,
+// so the storage always has the keys trusted by the published root.
+func (publisher *Publisher) UpdateRoleKeys(ctx context.Context, storage logical.Storage, repository RepositoryInterface, role string, opts UpdateRoleKeysOptions) error {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	if err := repository.UpdateRoleKeys(role, opts); err != nil {
+		return err
+	}
+
+	if err := putRepositoryKeys(ctx, storage, repository.GetPrivKeys()); err != nil {
+		return err
+	}
+
+	if err := repository.CommitStaged(ctx); err != nil {
+		return fmt.Errorf("unable to commit %s keys: %w", role, err)
+	}
+
+	if err := publisher.pruneRepositoryKeys(ctx, storage, repository); err != nil {
+		return err
+	}
+
+	publisher.logger.Info(fmt.Sprintf("Successfully updated %s keys", role))
+
+	return nil
+}
+
+type RotateRoleKeysOptions struct {
+	// Reason is logged along with the revoked keys, e.g. the key compromise.
+	Reason string
+}
+
+// RotateRoleKeys replaces the keys of the top-level roles right away, e.g. when a key is compromised,
+// and returns the revoked key IDs by the role. The root is re-signed once for all the roles.
+func (publisher *Publisher) RotateRoleKeys(ctx context.Context, storage logical.Storage, repository RepositoryInterface, roles []string, opts RotateRoleKeysOptions) (map[string][]string, error) {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	roles = lo.Uniq(roles)
+	if err := ValidateTopLevelRoles(roles); err != nil {
+		return nil, err
+	}
+
+	revokedKeyIDs := make(map[string][]string)
+	for _, role := range roles {
+		roleKeys, _, err := repository.RoleKeys(role)
+		if err != nil {
+			return nil, err
+		}
+		revokedKeyIDs[role] = lo.Map(roleKeys, func(roleKey RoleKey, _ int) string { return roleKey.KeyID })
+
+		if err := repository.RotateRoleKey(ctx, role); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := putRepositoryKeys(ctx, storage, repository.GetPrivKeys()); err != nil {
+		return nil, err
+	}
+
+	if err := repository.CommitStaged(ctx); err != nil {
+		return nil, fmt.Errorf("unable to commit rotated keys: %w", err)
+	}
+
+	if err := publisher.pruneRepositoryKeys(ctx, storage, repository); err != nil {
+		return nil, err
+	}
+
+	for _, role := range roles {
+		publisher.logger.Info(fmt.Sprintf("Rotated %s keys: revoked keys %v, reason: %q", role, revokedKeyIDs[role], opts.Reason))
+	}
+
+	return revokedKeyIDs, nil
+}
+
+// pruneRepositoryKeys removes the stored keys revoked by the committed root.
+func (publisher *Publisher) pruneRepositoryKeys(ctx context.Context, storage logical.Storage, repository RepositoryInterface) error {
+	if err := repository.PruneUntrustedKeys(); err != nil {
+		return fmt.Errorf("unable to prune revoked keys: %w", err)
+	}
+
+	return putRepositoryKeys(ctx, storage, repository.GetPrivKeys())
+}
+
 func (publisher *Publisher) UpdateTimestamps(ctx context.Context, storage logical.Storage, repository RepositoryInterface, systemClock util.Clock) error {
 	return repository.UpdateTimestamps(ctx, systemClock)
 }
@@ -112,15 +354,8 @@ func (publisher *Publisher) setRepositoryKeys(ctx context.Context, storage logic
 			return fmt.Errorf("error generating repository private keys: %w", err)
 		}
 
-		privKeys := repository.GetPrivKeys()
-
-		entry, err := logical.StorageEntryJSON(storageKeyTufRepositoryKeys, privKeys)
-		if err != nil {
-			return fmt.Errorf("error creating storage json entry by key %q: %w", storageKeyTufRepositoryKeys, err)
-		}
-
-		if err := storage.Put(ctx, entry); err != nil {
-			return fmt.Errorf("error putting private keys json entry by key %q into the storage: %w", storageKeyTufRepositoryKeys, err)
+		if err := putRepositoryKeys(ctx, storage, repository.GetPrivKeys()); err != nil {
+			return err
 		}
 
 		publisher.logger.Info("Generated new repository private keys")
@@ -194,20 +429,38 @@ func (publisher *Publisher) GetRepository(ctx context.Context, storage logical.S
 	publisher.mu.Lock()
 	defer publisher.mu.Unlock()
 
-	awsConfig := &aws.Config{
-		Endpoint:    aws.String(options.S3Endpoint),
-		Region:      aws.String(options.S3Region),
-		Credentials: credentials.NewStaticCredentials(options.S3AccessKeyID, options.S3SecretAccessKey, ""),
+	externalSigners, err := transit.GetSigners(ctx, storage)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing transit signers: %w", err)
+	}
+
+	fs, err := newRepositoryFilesystem(options, publisher.logger)
+	if err != nil {
+		return nil, err
 	}
 
 	repository, err := NewRepositoryWithOptions(
-		S3Options{AwsConfig: awsConfig, BucketName: options.S3BucketName},
-		TufRepoOptions{},
+		fs,
+		TufRepoOptions{ConsistentSnapshot: options.ConsistentSnapshot, Expirations: options.Expirations, ExternalSigners: externalSigners},
 		publisher.logger,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("error initializing publisher repository handle: %w", err)
 	}
+	repository.commitMu = &publisher.commitMu
+	repository.loadSigners = func(ctx context.Context) (TufRepoPrivKeys, map[string]keys.Signer, error) {
+		privKeys, err := getRepositoryKeys(ctx, storage)
+		if err != nil {
+			return TufRepoPrivKeys{}, nil, err
+		}
+
+		externalSigners, err := transit.GetSigners(ctx, storage)
+		if err != nil {
+			return TufRepoPrivKeys{}, nil, fmt.Errorf("error initializing transit signers: %w", err)
+		}
+
+		return privKeys, externalSigners, nil
+	}
 
 	if err := repository.Init(); err != nil {
 		return nil, fmt.Errorf("error initializing repository: %w", err)
@@ -219,6 +472,16 @@ func (publisher *Publisher) GetRepository(ctx context.Context, storage logical.S
 		return nil, fmt.Errorf("error initializing repository keys: %w", err)
 	}
 
+	if err := publisher.setupExternalSigners(ctx, storage, repository, options.InitializeTUFKeys); err != nil {
+		return nil, fmt.Errorf("error initializing repository external signers: %w", err)
+	}
+
+	if options.InitializeTUFKeys && options.DelegatedRoles {
+		if err := publisher.ensureDelegatedRole(ctx, storage, repository, DelegatedRoleReleases, releasesDelegationPaths()); err != nil {
+			return nil, fmt.Errorf("error initializing releases delegated role: %w", err)
+		}
+	}
+
 	pgpSigningKey, err := publisher.fetchPGPSigningKey(ctx, storage, options.InitializePGPSigningKey)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching pgp signing key: %w", err)
@@ -228,9 +491,30 @@ func (publisher *Publisher) GetRepository(ctx context.Context, storage logical.S
 	return repository, nil
 }
 
+// newRepositoryFilesystem returns an error for an unknown storage backend, since it comes from the stored configuration.
+func newRepositoryFilesystem(options RepositoryOptions, logger hclog.Logger) (Filesystem, error) {
+	switch options.StorageBackend {
+	case StorageBackendS3:
+		awsConfig := &aws.Config{
+			Endpoint:    aws.String(options.S3Endpoint),
+			Region:      aws.String(options.S3Region),
+			Credentials: credentials.NewStaticCredentials(options.S3AccessKeyID, options.S3SecretAccessKey, ""),
+		}
+
+		return NewS3Filesystem(awsConfig, options.S3BucketName, logger), nil
+	case StorageBackendLocal:
+		return NewLocalFilesystem(options.LocalDirectory, logger), nil
+	default:
+		return nil, fmt.Errorf("unexpected storage backend %q", options.StorageBackend)
+	}
+}
+
+// StageReleaseTarget does not lock the publisher for the whole staging, which is as long as the artifact upload,
+// so the other tasks are not blocked by the release build.
 func (publisher *Publisher) StageReleaseTarget(ctx context.Context, repository RepositoryInterface, releaseName, releaseFilePath string, data io.Reader, elfSigner *elf_signing.ELFSigner) error {
 	publisher.mu.Lock()
-	defer publisher.mu.Unlock()
+	pgpSigningKey := publisher.PGPSigningKey
+	publisher.mu.Unlock()
 
 	pathParts := SplitFilepath(filepath.Clean(releaseFilePath))
 	if len(pathParts) == 0 {
@@ -272,7 +556,7 @@ func (publisher *Publisher) StageReleaseTarget(ctx context.Context, repository R
 		}()
 		signDataReader := io.TeeReader(source, w)
 
-		if err := pgp.SignDataStream(gpgSignBuf, signDataReader, publisher.PGPSigningKey); err != nil {
+		if err := pgp.SignDataStream(gpgSignBuf, signDataReader, pgpSigningKey); err != nil {
 			gpgSignErrCh <- fmt.Errorf("unable to sign %q: %w", releaseFilePath, err)
 			return
 		}
@@ -288,7 +572,7 @@ func (publisher *Publisher) StageReleaseTarget(ctx context.Context, repository R
 
 	pathToReleaseTarget := path.Join("releases", releaseName, releaseFilePath)
 	hclog.L().Debug(fmt.Sprintf("Stage release target %q ...\n", pathToReleaseTarget))
-	if err := repository.StageTarget(ctx, pathToReleaseTarget, r); err != nil {
+	if err := repository.StageTarget(ctx, pathToReleaseTarget, r, StageTargetOptions{}); err != nil {
 		return fmt.Errorf("unable to stage release target %q into the repository: %w", pathToReleaseTarget, err)
 	}
 
@@ -300,23 +584,54 @@ func (publisher *Publisher) StageReleaseTarget(ctx context.Context, repository R
 
 	pathToReleaseTargetSignature := path.Join("signatures", releaseName, fmt.Sprintf("%s.sig", releaseFilePath))
 	hclog.L().Debug(fmt.Sprintf("Stage release target signature %q ...\n", pathToReleaseTargetSignature))
-	if err := repository.StageTarget(ctx, pathToReleaseTargetSignature, bytes.NewBufferString(gpgSignBuf.String())); err != nil {
+	if err := repository.StageTarget(ctx, pathToReleaseTargetSignature, bytes.NewBufferString(gpgSignBuf.String()), StageTargetOptions{}); err != nil {
 		return fmt.Errorf("unable to stage release target signature %q into the repository: %w", pathToReleaseTargetSignature, err)
 	}
 
 	return nil
 }
 
-func (publisher *Publisher) StageChannelsConfig(ctx context.Context, repository RepositoryInterface, trdlChannelsConfig *config.TrdlChannels) error {
+type StageChannelsConfigOptions struct {
+	// GitCommit is the trdl channels branch commit the channels are published from, it is recorded for the changed channels.
+	GitCommit string
+	// PromotedFromTag is the release tag the channels are promoted by, it is recorded for the changed channels instead of the commit.
+	PromotedFromTag string
+}
+
+func (publisher *Publisher) StageChannelsConfig(ctx context.Context, storage logical.Storage, repository RepositoryInterface, trdlChannelsConfig *config.TrdlChannels, opts StageChannelsConfigOptions) error {
 	publisher.mu.Lock()
 	defer publisher.mu.Unlock()
 
+	existingTargets, err := repository.GetTargetFiles(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting existing targets: %w", err)
+	}
+
 	// publish /channels/GROUP/CHANNEL -> VERSION
 	for _, grp := range trdlChannelsConfig.Groups {
+		if grp.Name == "" || grp.Name == "." || grp.Name == ".." || strings.Contains(grp.Name, "/") {
+			return fmt.Errorf("bad channels group name %q", grp.Name)
+		}
+
+		if err := publisher.ensureDelegatedRole(ctx, storage, repository, ChannelsDelegatedRoleName(grp.Name), channelsDelegationPaths(grp.Name)); err != nil {
+			return fmt.Errorf("error initializing channels group %q delegated role: %w", grp.Name, err)
+		}
+
 		for _, chnl := range grp.Channels {
 			publishPath := path.Join("channels", grp.Name, chnl.Name)
+			content := []byte(chnl.Version + "\n")
+
+			// The unchanged channel keeps the commit it has been published from.
+			var stageOpts StageTargetOptions
+			if existingTarget, ok := existingTargets[publishPath]; !ok || !isTargetContent(existingTarget, content) {
+				custom, err := json.Marshal(channelTargetCustom{Version: chnl.Version, GitCommit: opts.GitCommit, PromotedFromTag: opts.PromotedFromTag})
+				if err != nil {
+					return fmt.Errorf("unable to marshal %q custom metadata: %w", publishPath, err)
+				}
+				stageOpts.Custom = custom
+			}
 
-			if err := repository.StageTarget(ctx, publishPath, bytes.NewBuffer([]byte(chnl.Version+"\n"))); err != nil {
+			if err := repository.StageTarget(ctx, publishPath, bytes.NewBuffer(content), stageOpts); err != nil {
 				return fmt.Errorf("error publishing %q: %w", publishPath, err)
 			}
 		}
@@ -330,7 +645,7 @@ func (publisher *Publisher) StageInMemoryFiles(ctx context.Context, repository R
 	defer publisher.mu.Unlock()
 
 	for _, file := range files {
-		if err := repository.StageTarget(ctx, file.Name, bytes.NewReader(file.Data)); err != nil {
+		if err := repository.StageTarget(ctx, file.Name, bytes.NewReader(file.Data), StageTargetOptions{}); err != nil {
 			return fmt.Errorf("error publishing %q: %w", file.Name, err)
 		}
 	}
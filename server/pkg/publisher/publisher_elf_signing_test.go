@@ -9,11 +9,13 @@ import (
 	"encoding/binary"
 	"io"
 	"testing"
+	"time"
 
 	"github.com/deckhouse/delivery-kit-sdk/test/pkg/cert_utils"
 	"github.com/hashicorp/go-hclog"
 	"github.com/onsi/gomega"
 	"github.com/stretchr/testify/require"
+	"github.com/theupdateframework/go-tuf/data"
 
 	"github.com/werf/trdl/server/pkg/elf_signing"
 	"github.com/werf/trdl/server/pkg/util"
@@ -39,7 +41,32 @@ func (r *stageTargetFailRepository) RotatePrivKeys(context.Context) (bool, TufRe
 	return false, TufRepoPrivKeys{}, nil
 }
 
-func (r *stageTargetFailRepository) StageTarget(context.Context, string, io.Reader) error {
+func (r *stageTargetFailRepository) HasDelegatedRole(string) (bool, error) { return false, nil }
+func (r *stageTargetFailRepository) AddDelegatedRole(string, []string, AddDelegatedRoleOptions) error {
+	return nil
+}
+func (r *stageTargetFailRepository) RootCandidate([]*data.PublicKey, int, time.Time) (*data.Signed, error) {
+	return nil, nil
+}
+func (r *stageTargetFailRepository) ImportRoot(*data.Signed) error { return nil }
+func (r *stageTargetFailRepository) PruneUntrustedKeys() error     { return nil }
+func (r *stageTargetFailRepository) RoleKeys(string) ([]RoleKey, int, error) {
+	return nil, 0, nil
+}
+func (r *stageTargetFailRepository) UpdateRoleKeys(string, UpdateRoleKeysOptions) error { return nil }
+func (r *stageTargetFailRepository) RotateRoleKey(context.Context, string) error        { return nil }
+func (r *stageTargetFailRepository) GetTargetFiles(context.Context) (data.TargetFiles, error) {
+	return nil, nil
+}
+func (r *stageTargetFailRepository) RemoveTargets(context.Context, []string) error {
+	return nil
+}
+func (r *stageTargetFailRepository) GetChannels(context.Context) ([]PublishedChannel, error) {
+	return nil, nil
+}
+func (r *stageTargetFailRepository) RoleStatuses() ([]RoleStatus, error) { return nil, nil }
+
+func (r *stageTargetFailRepository) StageTarget(context.Context, string, io.Reader, StageTargetOptions) error {
 	r.t.Fatal("StageTarget must not be called when ELF signing fails")
 	return nil
 }
@@ -0,0 +1,102 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/theupdateframework/go-tuf/data"
+)
+
+// ReleaseTarget is the published release artifact.
+type ReleaseTarget struct {
+	// Name is the path inside the release, e.g. linux-amd64/bin/app.
+	Name   string
+	OS     string
+	Arch   string
+	Length int64
+	// Hashes are hex-encoded by the hash algorithm.
+	Hashes map[string]string
+	// SignatureTarget is the target path of the PGP signature, empty if the signature is not published.
+	SignatureTarget string
+}
+
+// GetReleaseTargets returns the artifacts of the release sorted by name or nil if the release is not published.
+func (publisher *Publisher) GetReleaseTargets(ctx context.Context, repository RepositoryInterface, releaseName string) ([]ReleaseTarget, error) {
+	targetFiles, err := repository.GetTargetFiles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting existing targets: %w", err)
+	}
+
+	releasePrefix := path.Join("releases", releaseName) + "/"
+
+	var releaseTargets []ReleaseTarget
+	for targetPath, meta := range targetFiles {
+		if !strings.HasPrefix(targetPath, releasePrefix) {
+			continue
+		}
+
+		name := strings.TrimPrefix(targetPath, releasePrefix)
+		releaseTarget := ReleaseTarget{
+			Name:   name,
+			Length: meta.Length,
+			Hashes: make(map[string]string),
+		}
+
+		osAndArch := strings.SplitN(strings.SplitN(name, "/", 2)[0], "-", 2)
+		releaseTarget.OS = osAndArch[0]
+		if len(osAndArch) == 2 {
+			releaseTarget.Arch = osAndArch[1]
+		}
+
+		for alg, hash := range meta.Hashes {
+			releaseTarget.Hashes[alg] = hash.String()
+		}
+
+		signatureTarget := path.Join("signatures", releaseName, name+".sig")
+		if _, ok := targetFiles[signatureTarget]; ok {
+			releaseTarget.SignatureTarget = signatureTarget
+		}
+
+		releaseTargets = append(releaseTargets, releaseTarget)
+	}
+
+	sort.Slice(releaseTargets, func(i, j int) bool {
+		return releaseTargets[i].Name < releaseTargets[j].Name
+	})
+
+	return releaseTargets, nil
+}
+
+// RemoveReleaseTargets removes the release targets and signatures, so the rebuilt release does not keep the stale artifacts.
+func (publisher *Publisher) RemoveReleaseTargets(ctx context.Context, repository RepositoryInterface, releaseName string) error {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	targetFiles, err := repository.GetTargetFiles(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting existing targets: %w", err)
+	}
+
+	if err := repository.RemoveTargets(ctx, releaseTargetPaths(targetFiles, releaseName)); err != nil {
+		return fmt.Errorf("unable to remove release %q targets: %w", releaseName, err)
+	}
+
+	return nil
+}
+
+// releaseTargetPaths returns the sorted paths of the release targets and signatures.
+func releaseTargetPaths(targetFiles data.TargetFiles, releaseName string) []string {
+	releasePrefix := path.Join("releases", releaseName) + "/"
+	signaturesPrefix := path.Join("signatures", releaseName) + "/"
+
+	paths := lo.Filter(lo.Keys(targetFiles), func(targetPath string, _ int) bool {
+		return strings.HasPrefix(targetPath, releasePrefix) || strings.HasPrefix(targetPath, signaturesPrefix)
+	})
+	sort.Strings(paths)
+
+	return paths
+}
@@ -0,0 +1,45 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Release targets removal", func() {
+	It("should remove the targets and signatures of the release only", func() {
+		ctx := context.Background()
+		storage := &logical.InmemStorage{}
+		publisher := NewPublisher(hclog.NewNullLogger())
+		options := RepositoryOptions{
+			StorageBackend:          StorageBackendLocal,
+			LocalDirectory:          GinkgoT().TempDir(),
+			InitializeTUFKeys:       true,
+			InitializePGPSigningKey: true,
+		}
+
+		repository, err := publisher.GetRepository(ctx, storage, options)
+		Expect(err).NotTo(HaveOccurred())
+		for _, release := range []string{"1.0.0", "1.0.0-rc.1"} {
+			Expect(publisher.StageReleaseTarget(ctx, repository, release, "linux-amd64/bin/app", bytes.NewBufferString("app "+release), nil)).To(Succeed())
+		}
+		Expect(repository.CommitStaged(ctx)).To(Succeed())
+
+		repository, err = publisher.GetRepository(ctx, storage, options)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(publisher.RemoveReleaseTargets(ctx, repository, "1.0.0")).To(Succeed())
+		Expect(publisher.StageReleaseTarget(ctx, repository, "1.0.0", "darwin-arm64/bin/app", bytes.NewBufferString("app 1.0.0"), nil)).To(Succeed())
+		Expect(repository.CommitStaged(ctx)).To(Succeed())
+
+		Expect(repository.GetTargets(ctx)).To(ConsistOf(
+			"releases/1.0.0/darwin-arm64/bin/app",
+			"signatures/1.0.0/darwin-arm64/bin/app.sig",
+			"releases/1.0.0-rc.1/linux-amd64/bin/app",
+			"signatures/1.0.0-rc.1/linux-amd64/bin/app.sig",
+		))
+	})
+})
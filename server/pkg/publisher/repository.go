@@ -1,37 +1,51 @@
 package publisher
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"sync"
+	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
 	"github.com/hashicorp/go-hclog"
 	"github.com/theupdateframework/go-tuf"
+	"github.com/theupdateframework/go-tuf/data"
+	"github.com/theupdateframework/go-tuf/pkg/keys"
 
 	"github.com/werf/trdl/server/pkg/util"
 )
 
-type S3Options struct {
-	AwsConfig  *aws.Config
-	BucketName string
-}
-
 type TufRepoOptions struct {
-	PrivKeys TufRepoPrivKeys
+	PrivKeys           TufRepoPrivKeys
+	ConsistentSnapshot bool
+	Expirations        TufExpirations
+
+	// ExternalSigners are the signers by the top-level role, which private keys are kept outside the plugin storage.
+	ExternalSigners map[string]keys.Signer
 }
 
-func NewRepositoryWithOptions(s3Options S3Options, tufRepoOptions TufRepoOptions, logger hclog.Logger) (*S3Repository, error) {
-	s3fs := NewS3Filesystem(s3Options.AwsConfig, s3Options.BucketName, logger)
-	tufStore := NewNonAtomicTufStore(tufRepoOptions.PrivKeys, s3fs, logger)
+func NewRepositoryWithOptions(filesystem Filesystem, tufRepoOptions TufRepoOptions, logger hclog.Logger) (*Repository, error) {
+	tufStore := NewAtomicTufStore(tufRepoOptions.PrivKeys, filesystem, logger)
+
+	// Read before the metadata is loaded: a commit in between only leads to the unnecessary re-applying of the staged changes.
+	committedTimestamp, err := readCommittedTimestamp(context.Background(), filesystem)
+	if err != nil {
+		return nil, err
+	}
 
 	tufRepo, err := tuf.NewRepo(tufStore)
 	if err != nil {
 		return nil, fmt.Errorf("error initializing tuf repo: %w", err)
 	}
 
-	repository := NewRepository(s3fs, tufStore, tufRepo, logger)
+	repository := NewRepository(filesystem, tufStore, tufRepo, logger)
+	repository.committedTimestamp = committedTimestamp
+	repository.ConsistentSnapshot = tufRepoOptions.ConsistentSnapshot
+	repository.Expirations = tufRepoOptions.Expirations
+	repository.ExternalSigners = tufRepoOptions.ExternalSigners
 
 	if err := tufStore.PrivKeys.SetupStoreSigners(tufStore); err != nil {
 		return nil, fmt.Errorf("unable to set private keys into tuf store: %w", err)
@@ -40,78 +54,110 @@ func NewRepositoryWithOptions(s3Options S3Options, tufRepoOptions TufRepoOptions
 	return repository, nil
 }
 
-type S3Repository struct {
-	S3Filesystem *S3Filesystem
-	TufStore     *NonAtomicTufStore
-	TufRepo      *tuf.Repo
+var _ RepositoryInterface = (*Repository)(nil)
+
+type Repository struct {
+	Filesystem Filesystem
+	TufStore   *AtomicTufStore
+	TufRepo    *tuf.Repo
+
+	// ConsistentSnapshot is used only to initialize a new repository.
+	ConsistentSnapshot bool
+	Expirations        TufExpirations
+
+	ExternalSigners map[string]keys.Signer
+
+	// commitMu serializes the commits of the repository handles sharing it.
+	commitMu *sync.Mutex
+	// committedTimestamp is the timestamp metadata committed when the handle has been loaded or committed.
+	committedTimestamp []byte
+	// stagedTargetChanges re-apply the staged target changes on top of the metadata committed by another handle.
+	stagedTargetChanges  []func() error
+	hasStagedDelegations bool
+	// loadSigners loads the stored and the external keys, which might be rotated by another handle.
+	loadSigners func(ctx context.Context) (TufRepoPrivKeys, map[string]keys.Signer, error)
 
 	logger hclog.Logger
 }
 
-func NewRepository(s3Filesystem *S3Filesystem, tufStore *NonAtomicTufStore, tufRepo *tuf.Repo, logger hclog.Logger) *S3Repository {
-	return &S3Repository{
-		S3Filesystem: s3Filesystem,
-		TufStore:     tufStore,
-		TufRepo:      tufRepo,
-		logger:       logger,
+func NewRepository(filesystem Filesystem, tufStore *AtomicTufStore, tufRepo *tuf.Repo, logger hclog.Logger) *Repository {
+	return &Repository{
+		Filesystem: filesystem,
+		TufStore:   tufStore,
+		TufRepo:    tufRepo,
+		logger:     logger,
 	}
 }
 
-func (repository *S3Repository) SetPrivKeys(privKeys TufRepoPrivKeys) error {
-	repository.logger.Debug("-- S3Repository.SetPrivKeys")
+func (repository *Repository) SetPrivKeys(privKeys TufRepoPrivKeys) error {
+	repository.logger.Debug("-- Repository.SetPrivKeys")
 
 	repository.TufStore.PrivKeys = privKeys
 
-	repository.logger.Debug(fmt.Sprintf("-- S3Repository.SetPrivKeys BEFORE AddPrivateKeyWithExpires: %#v\n", privKeys))
+	repository.logger.Debug(fmt.Sprintf("-- Repository.SetPrivKeys BEFORE AddPrivateKeyWithExpires: %#v\n", privKeys))
 
 	if err := privKeys.SetupStoreSigners(repository.TufStore); err != nil {
 		return fmt.Errorf("unable to set private keys into tuf store: %w", err)
 	}
 
-	if err := privKeys.SetupTufRepoSigners(repository.TufRepo); err != nil {
+	if err := privKeys.SetupTufRepoSigners(repository.TufRepo, repository.Expirations.expiresAt("root", time.Now())); err != nil {
 		return fmt.Errorf("unable to set private keys into tuf repo: %w", err)
 	}
 
-	repository.logger.Debug(fmt.Sprintf("-- S3Repository.SetPrivKeys AFTER AddPrivateKeyWithExpires: %#v\n", privKeys))
+	repository.logger.Debug(fmt.Sprintf("-- Repository.SetPrivKeys AFTER AddPrivateKeyWithExpires: %#v\n", privKeys))
 
 	return nil
 }
 
-func (repository *S3Repository) GetPrivKeys() TufRepoPrivKeys {
+func (repository *Repository) GetPrivKeys() TufRepoPrivKeys {
 	return repository.TufStore.PrivKeys
 }
 
-func (repository *S3Repository) GenPrivKeys() error {
-	if _, err := repository.TufRepo.GenKey("root"); err != nil {
-		return fmt.Errorf("error generating tuf repository root key: %w", err)
-	}
-
-	if _, err := repository.TufRepo.GenKey("targets"); err != nil {
-		return fmt.Errorf("error generating tuf repository targets key: %w", err)
-	}
+func (repository *Repository) GenPrivKeys() error {
+	for _, role := range topLevelRoles {
+		if signer, ok := repository.ExternalSigners[role]; ok {
+			if err := repository.TufRepo.AddPrivateKeyWithExpires(role, signer, repository.Expirations.expiresAt("root", time.Now())); err != nil {
+				return fmt.Errorf("error adding tuf repository %s external key: %w", role, err)
+			}
 
-	if _, err := repository.TufRepo.GenKey("snapshot"); err != nil {
-		return fmt.Errorf("error generating tuf repository snapshot key: %w", err)
-	}
+			continue
+		}
 
-	if _, err := repository.TufRepo.GenKey("timestamp"); err != nil {
-		return fmt.Errorf("error generating tuf repository timestamp key: %w", err)
+		if _, err := repository.TufRepo.GenKeyWithExpires(role, repository.Expirations.expiresAt("root", time.Now())); err != nil {
+			return fmt.Errorf("error generating tuf repository %s key: %w", role, err)
+		}
 	}
 
 	return nil
 }
 
-func (repository *S3Repository) RotatePrivKeys(ctx context.Context) (bool, TufRepoPrivKeys, error) {
+func (repository *Repository) RotatePrivKeys(ctx context.Context) (bool, TufRepoPrivKeys, error) {
 	// TODO: Check priv keys expiration and generate new keys when necessary.
 
 	return false, TufRepoPrivKeys{}, nil
 }
 
-func (repository *S3Repository) Init() error {
-	err := repository.TufRepo.Init(false)
+func (repository *Repository) Init() error {
+	// The TUF repo considers itself initialized only when the top-level targets metadata has targets,
+	// which is not the case when all the targets are signed by the delegated roles.
+	_, err := repository.TufRepo.SignedMeta("root.json")
+	if errors.As(err, &tuf.ErrMissingMetadata{}) {
+		err = repository.TufRepo.Init(repository.ConsistentSnapshot)
+	} else if err == nil {
+		err = tuf.ErrInitNotAllowed
+	}
 
 	if err == tuf.ErrInitNotAllowed {
 		repository.logger.Info("Tuf repository already initialized: skip initialization")
+
+		consistentSnapshot, err := repository.IsConsistentSnapshot()
+		if err != nil {
+			return err
+		}
+
+		if consistentSnapshot != repository.ConsistentSnapshot {
+			repository.logger.Warn(fmt.Sprintf("Tuf repository already initialized with consistent snapshot %t: the configured value %t is ignored", consistentSnapshot, repository.ConsistentSnapshot))
+		}
 	} else if err != nil {
 		return fmt.Errorf("unable to init tuf repository: %w", err)
 	}
@@ -119,39 +165,210 @@ func (repository *S3Repository) Init() error {
 	return nil
 }
 
-func (repository *S3Repository) StageTarget(ctx context.Context, pathInsideTargets string, data io.Reader) error {
+func (repository *Repository) IsConsistentSnapshot() (bool, error) {
+	root, err := repository.root()
+	if err != nil {
+		return false, err
+	}
+
+	return root.ConsistentSnapshot, nil
+}
+
+type StageTargetOptions struct {
+	// Custom is the custom target metadata, the existing one is kept if not set.
+	Custom json.RawMessage
+}
+
+func (repository *Repository) StageTarget(ctx context.Context, pathInsideTargets string, data io.Reader, opts StageTargetOptions) error {
 	if err := repository.TufStore.StageTargetFile(ctx, pathInsideTargets, data); err != nil {
 		return fmt.Errorf("unable to add staged file %q: %w", pathInsideTargets, err)
 	}
 
-	if err := repository.TufRepo.AddTarget(pathInsideTargets, json.RawMessage("")); err != nil {
-		return fmt.Errorf("unable to register target file %q in the tuf repo: %w", pathInsideTargets, err)
+	return repository.applyStagedTargetChange(func() error {
+		if err := repository.TufRepo.AddTargetWithExpires(pathInsideTargets, opts.Custom, repository.Expirations.expiresAt("targets", time.Now())); err != nil {
+			return fmt.Errorf("unable to register target file %q in the tuf repo: %w", pathInsideTargets, err)
+		}
+
+		return nil
+	})
+}
+
+func (repository *Repository) RemoveTargets(_ context.Context, pathsInsideTargets []string) error {
+	return repository.applyStagedTargetChange(func() error {
+		if err := repository.TufRepo.RemoveTargetsWithExpires(pathsInsideTargets, repository.Expirations.expiresAt("targets", time.Now())); err != nil {
+			return fmt.Errorf("unable to remove targets from the tuf repo: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (repository *Repository) applyStagedTargetChange(change func() error) error {
+	if err := change(); err != nil {
+		return err
 	}
 
+	repository.stagedTargetChanges = append(repository.stagedTargetChanges, change)
+
 	return nil
 }
 
-func (repository *S3Repository) UpdateTimestamps(_ context.Context, systemClock util.Clock) error {
-	return NewTufRepoRotator(repository.TufRepo).Rotate(repository.logger, systemClock.Now())
+func (repository *Repository) UpdateTimestamps(ctx context.Context, systemClock util.Clock) error {
+	return repository.withCommitLock(ctx, func() error {
+		offline, err := repository.IsRootKeyOffline()
+		if err != nil {
+			return err
+		}
+
+		rotator := NewTufRepoRotator(delegatingTufRepo{repository.TufRepo})
+		rotator.OfflineRootKey = offline
+		rotator.Expirations = repository.Expirations
+
+		return rotator.Rotate(repository.logger, systemClock.Now())
+	})
+}
+
+func (repository *Repository) CommitStaged(ctx context.Context) error {
+	return repository.withCommitLock(ctx, func() error {
+		now := time.Now()
+		if err := repository.TufRepo.SnapshotWithExpires(repository.Expirations.expiresAt("snapshot", now)); err != nil {
+			return fmt.Errorf("tuf repo snapshot failed: %w", err)
+		}
+		if err := repository.TufRepo.TimestampWithExpires(repository.Expirations.expiresAt("timestamp", now)); err != nil {
+			return fmt.Errorf("tuf repo timestamp failed: %w", err)
+		}
+		if err := repository.TufRepo.Commit(); err != nil {
+			return fmt.Errorf("unable to commit staged changes into the repo: %w", err)
+		}
+		return nil
+	})
+}
+
+// DiscardStaged drops the staged changes and deletes the uploaded target files, the handle must not be used after that.
+func (repository *Repository) DiscardStaged(_ context.Context) error {
+	if err := repository.TufRepo.Clean(); err != nil {
+		return fmt.Errorf("unable to drop staged changes: %w", err)
+	}
+
+	repository.stagedTargetChanges = nil
+	repository.hasStagedDelegations = false
+
+	return nil
 }
 
-func (repository *S3Repository) CommitStaged(_ context.Context) error {
-	if err := repository.TufRepo.Snapshot(); err != nil {
-		return fmt.Errorf("tuf repo snapshot failed: %w", err)
+// withCommitLock serializes the commit with the commits of the other repository handles sharing the lock.
+// If another handle has committed since this one has been loaded, the staged changes are re-applied on top of that commit,
+// so the concurrent tasks do not overwrite each other's changes.
+func (repository *Repository) withCommitLock(ctx context.Context, commit func() error) error {
+	if repository.commitMu == nil {
+		return commit()
+	}
+
+	repository.commitMu.Lock()
+	defer repository.commitMu.Unlock()
+
+	committedTimestamp, err := readCommittedTimestamp(ctx, repository.Filesystem)
+	if err != nil {
+		return err
 	}
-	if err := repository.TufRepo.Timestamp(); err != nil {
-		return fmt.Errorf("tuf repo timestamp failed: %w", err)
+
+	if !bytes.Equal(committedTimestamp, repository.committedTimestamp) {
+		if err := repository.reapplyStagedTargetChanges(ctx); err != nil {
+			return err
+		}
+
+		repository.committedTimestamp = committedTimestamp
 	}
-	if err := repository.TufRepo.Commit(); err != nil {
-		return fmt.Errorf("unable to commit staged changes into the repo: %w", err)
+
+	if err := commit(); err != nil {
+		return err
 	}
+
+	repository.stagedTargetChanges = nil
+	repository.hasStagedDelegations = false
+
+	repository.committedTimestamp, err = readCommittedTimestamp(ctx, repository.Filesystem)
+
+	return err
+}
+
+// reapplyStagedTargetChanges reloads the committed metadata and the signers and re-applies the staged target changes to it.
+// The staged changes of the keys and the delegations cannot be re-applied safely.
+func (repository *Repository) reapplyStagedTargetChanges(ctx context.Context) error {
+	if repository.hasStagedDelegations || repository.TufStore.FileIsStaged("root.json") {
+		return ErrConcurrentCommit
+	}
+
+	repository.logger.Info(fmt.Sprintf("The repository has been committed concurrently: re-applying %d staged target changes", len(repository.stagedTargetChanges)))
+
+	repository.TufStore.stagedMeta = make(map[string]json.RawMessage)
+
+	tufRepo, err := tuf.NewRepo(repository.TufStore)
+	if err != nil {
+		return fmt.Errorf("error initializing tuf repo: %w", err)
+	}
+	repository.TufRepo = tufRepo
+
+	if err := repository.reloadSigners(ctx); err != nil {
+		return err
+	}
+
+	for _, change := range repository.stagedTargetChanges {
+		if err := change(); err != nil {
+			return fmt.Errorf("unable to re-apply staged target changes: %w", err)
+		}
+	}
+
 	return nil
 }
 
-func (repository *S3Repository) GetTargets(ctx context.Context) ([]string, error) {
-	targetsMeta, err := repository.TufRepo.Targets()
+// reloadSigners adds the keys rotated since the handle has been loaded, the revoked keys are not trusted by the reloaded metadata.
+func (repository *Repository) reloadSigners(ctx context.Context) error {
+	if repository.loadSigners == nil {
+		return nil
+	}
+
+	privKeys, externalSigners, err := repository.loadSigners(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("unable to get TUF-repo targets metadata: %w", err)
+		return fmt.Errorf("unable to reload signers: %w", err)
+	}
+
+	if err := privKeys.SetupStoreSigners(repository.TufStore); err != nil {
+		return fmt.Errorf("unable to set private keys into tuf store: %w", err)
+	}
+
+	for role, signer := range externalSigners {
+		if err := repository.TufStore.SaveSigner(role, signer); err != nil {
+			return fmt.Errorf("unable to save %s key signer into tuf store: %w", role, err)
+		}
+	}
+	repository.ExternalSigners = externalSigners
+
+	return nil
+}
+
+func readCommittedTimestamp(ctx context.Context, filesystem Filesystem) ([]byte, error) {
+	exists, err := filesystem.IsFileExist(ctx, "timestamp.json")
+	if err != nil {
+		return nil, fmt.Errorf("error checking existence of %q: %w", "timestamp.json", err)
+	}
+
+	if !exists {
+		return nil, nil
+	}
+
+	data, err := filesystem.ReadFileBytes(ctx, "timestamp.json")
+	if err != nil {
+		return nil, fmt.Errorf("error reading %q: %w", "timestamp.json", err)
+	}
+
+	return data, nil
+}
+
+func (repository *Repository) GetTargets(ctx context.Context) ([]string, error) {
+	targetsMeta, err := repository.GetTargetFiles(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	var res []string
@@ -160,3 +377,13 @@ func (repository *S3Repository) GetTargets(ctx context.Context) ([]string, error
 	}
 	return res, nil
 }
+
+// GetTargetFiles returns the metadata of the targets signed by the top-level targets role and the delegated roles.
+func (repository *Repository) GetTargetFiles(_ context.Context) (data.TargetFiles, error) {
+	targetsMeta, err := repository.allTargets()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get TUF-repo targets metadata: %w", err)
+	}
+
+	return targetsMeta, nil
+}
@@ -0,0 +1,290 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	tufClient "github.com/theupdateframework/go-tuf/client"
+
+	"github.com/werf/trdl/server/pkg/config"
+)
+
+type bufferDestination struct {
+	bytes.Buffer
+}
+
+func (d *bufferDestination) Delete() error {
+	d.Reset()
+	return nil
+}
+
+func newTestTufClient(repoUrl string, rootJSON []byte) *tufClient.Client {
+	remote, err := tufClient.HTTPRemoteStore(repoUrl, nil, nil)
+	Expect(err).NotTo(HaveOccurred())
+
+	client := tufClient.NewClient(tufClient.MemoryLocalStore(), remote)
+	Expect(client.Init(rootJSON)).To(Succeed())
+
+	return client
+}
+
+func downloadTestTarget(client *tufClient.Client, name string) string {
+	dest := &bufferDestination{}
+	Expect(client.Download(name, dest)).To(Succeed())
+	return dest.String()
+}
+
+// itPublishesRepository checks the whole publisher flow against a storage backend:
+// the published repository must be consumable by the TUF client.
+func itPublishesRepository(newRepositoryOptions func() (RepositoryOptions, string)) {
+	var (
+		ctx       context.Context
+		storage   logical.Storage
+		publisher *Publisher
+		options   RepositoryOptions
+		repoUrl   string
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		storage = &logical.InmemStorage{}
+		publisher = NewPublisher(hclog.NewNullLogger())
+
+		options, repoUrl = newRepositoryOptions()
+		options.DelegatedRoles = true
+		options.InitializeTUFKeys = true
+		options.InitializePGPSigningKey = true
+	})
+
+	It("should publish releases and channels verifiable by the TUF client", func() {
+		repository, err := publisher.GetRepository(ctx, storage, options)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(publisher.StageReleaseTarget(ctx, repository, "1.0.0", "linux-amd64/bin/app", bytes.NewBufferString("app 1.0.0"), nil)).To(Succeed())
+		Expect(repository.CommitStaged(ctx)).To(Succeed())
+
+		Expect(publisher.StageChannelsConfig(ctx, storage, repository, &config.TrdlChannels{
+			Groups: []config.TrdlGroup{
+				{Name: "1", Channels: []config.TrdlGroupChannel{{Name: "stable", Version: "1.0.0"}}},
+			},
+		}, StageChannelsConfigOptions{})).To(Succeed())
+		Expect(repository.CommitStaged(ctx)).To(Succeed())
+
+		Expect(publisher.GetExistingReleases(ctx, repository)).To(Equal([]string{"1.0.0"}))
+
+		releaseTargets, err := publisher.GetReleaseTargets(ctx, repository, "1.0.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(releaseTargets).To(HaveLen(1))
+		Expect(releaseTargets[0].Name).To(Equal("linux-amd64/bin/app"))
+		Expect(releaseTargets[0].OS).To(Equal("linux"))
+		Expect(releaseTargets[0].Arch).To(Equal("amd64"))
+		Expect(releaseTargets[0].Length).To(Equal(int64(len("app 1.0.0"))))
+		Expect(releaseTargets[0].Hashes).To(HaveKey("sha512"))
+		Expect(releaseTargets[0].SignatureTarget).To(Equal("signatures/1.0.0/linux-amd64/bin/app.sig"))
+
+		Expect(publisher.GetReleaseTargets(ctx, repository, "2.0.0")).To(BeEmpty())
+
+		rootJSON, err := newTestRepositoryFilesystem(options).ReadFileBytes(ctx, "1.root.json")
+		Expect(err).NotTo(HaveOccurred())
+
+		client := newTestTufClient(repoUrl, rootJSON)
+		topLevelTargets, err := client.Update()
+		Expect(err).NotTo(HaveOccurred())
+		// Releases and channels are signed by the delegated roles only.
+		Expect(topLevelTargets).To(BeEmpty())
+
+		for _, name := range []string{"releases/1.0.0/linux-amd64/bin/app", "signatures/1.0.0/linux-amd64/bin/app.sig", "channels/1/stable"} {
+			_, err := client.Target(name)
+			Expect(err).NotTo(HaveOccurred(), name)
+		}
+
+		Expect(downloadTestTarget(client, "releases/1.0.0/linux-amd64/bin/app")).To(Equal("app 1.0.0"))
+		Expect(downloadTestTarget(client, "channels/1/stable")).To(Equal("1.0.0\n"))
+	})
+
+	It("should reopen the published repository with the stored keys", func() {
+		repository, err := publisher.GetRepository(ctx, storage, options)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(publisher.StageInMemoryFiles(ctx, repository, []*InMemoryFile{{Name: "file.txt", Data: []byte("data")}})).To(Succeed())
+		Expect(repository.CommitStaged(ctx)).To(Succeed())
+
+		options.InitializeTUFKeys = false
+		reopenedRepository, err := publisher.GetRepository(ctx, storage, options)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reopenedRepository.GetTargets(ctx)).To(ConsistOf("file.txt"))
+	})
+}
+
+var _ = Describe("Repository", func() {
+	Context("local storage backend", func() {
+		itPublishesRepository(func() (RepositoryOptions, string) {
+			dir := GinkgoT().TempDir()
+			server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+			DeferCleanup(server.Close)
+
+			return RepositoryOptions{StorageBackend: StorageBackendLocal, LocalDirectory: dir}, server.URL
+		})
+	})
+
+	Context("local storage backend with consistent snapshot", func() {
+		itPublishesRepository(func() (RepositoryOptions, string) {
+			dir := GinkgoT().TempDir()
+			server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+			DeferCleanup(server.Close)
+
+			return RepositoryOptions{StorageBackend: StorageBackendLocal, LocalDirectory: dir, ConsistentSnapshot: true}, server.URL
+		})
+
+		It("should publish hash-prefixed targets and version-prefixed metadata", func() {
+			ctx := context.Background()
+			dir := GinkgoT().TempDir()
+			options := RepositoryOptions{
+				StorageBackend:          StorageBackendLocal,
+				LocalDirectory:          dir,
+				ConsistentSnapshot:      true,
+				InitializeTUFKeys:       true,
+				InitializePGPSigningKey: true,
+			}
+
+			repository, err := NewPublisher(hclog.NewNullLogger()).GetRepository(ctx, &logical.InmemStorage{}, options)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(repository.StageTarget(ctx, "file.txt", bytes.NewBufferString("data"), StageTargetOptions{})).To(Succeed())
+			Expect(repository.CommitStaged(ctx)).To(Succeed())
+
+			Expect(filepath.Join(dir, "targets", "file.txt")).NotTo(BeAnExistingFile())
+			Expect(filepath.Glob(filepath.Join(dir, "targets", "*.file.txt"))).To(HaveLen(1))
+			Expect(filepath.Join(dir, "1.targets.json")).To(BeAnExistingFile())
+			Expect(filepath.Join(dir, "1.snapshot.json")).To(BeAnExistingFile())
+		})
+	})
+
+	Context("concurrent commits", func() {
+		var (
+			ctx       context.Context
+			storage   logical.Storage
+			publisher *Publisher
+			options   RepositoryOptions
+			repoUrl   string
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			storage = &logical.InmemStorage{}
+			publisher = NewPublisher(hclog.NewNullLogger())
+
+			dir := GinkgoT().TempDir()
+			server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+			DeferCleanup(server.Close)
+
+			options = RepositoryOptions{StorageBackend: StorageBackendLocal, LocalDirectory: dir, InitializeTUFKeys: true, InitializePGPSigningKey: true}
+			repoUrl = server.URL
+		})
+
+		getRepository := func() *Repository {
+			repository, err := publisher.GetRepository(ctx, storage, options)
+			Expect(err).NotTo(HaveOccurred())
+			return repository.(*Repository)
+		}
+
+		BeforeEach(func() {
+			repository := getRepository()
+			Expect(repository.StageTarget(ctx, "base.txt", bytes.NewBufferString("base"), StageTargetOptions{})).To(Succeed())
+			Expect(repository.CommitStaged(ctx)).To(Succeed())
+		})
+
+		It("should re-apply the staged target changes on top of the concurrent commit", func() {
+			first := getRepository()
+			second := getRepository()
+
+			Expect(first.StageTarget(ctx, "first.txt", bytes.NewBufferString("first"), StageTargetOptions{})).To(Succeed())
+			Expect(second.StageTarget(ctx, "second.txt", bytes.NewBufferString("second"), StageTargetOptions{})).To(Succeed())
+			Expect(second.RemoveTargets(ctx, []string{"base.txt"})).To(Succeed())
+
+			Expect(second.CommitStaged(ctx)).To(Succeed())
+			Expect(first.CommitStaged(ctx)).To(Succeed())
+
+			Expect(getRepository().GetTargets(ctx)).To(ConsistOf("first.txt", "second.txt"))
+
+			rootJSON, err := newTestRepositoryFilesystem(options).ReadFileBytes(ctx, "root.json")
+			Expect(err).NotTo(HaveOccurred())
+
+			client := newTestTufClient(repoUrl, rootJSON)
+			_, err = client.Update()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(downloadTestTarget(client, "first.txt")).To(Equal("first"))
+			Expect(downloadTestTarget(client, "second.txt")).To(Equal("second"))
+		})
+
+		It("should sign the re-applied target changes with the keys rotated concurrently", func() {
+			first := getRepository()
+			Expect(first.StageTarget(ctx, "first.txt", bytes.NewBufferString("first"), StageTargetOptions{})).To(Succeed())
+
+			_, err := publisher.RotateRoleKeys(ctx, storage, getRepository(), []string{"targets", "snapshot", "timestamp"}, RotateRoleKeysOptions{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(first.CommitStaged(ctx)).To(Succeed())
+
+			rootJSON, err := newTestRepositoryFilesystem(options).ReadFileBytes(ctx, "root.json")
+			Expect(err).NotTo(HaveOccurred())
+
+			client := newTestTufClient(repoUrl, rootJSON)
+			_, err = client.Update()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(downloadTestTarget(client, "first.txt")).To(Equal("first"))
+		})
+
+		It("should keep the keys rotated concurrently when storing the new delegated role key", func() {
+			first := getRepository()
+
+			_, err := publisher.RotateRoleKeys(ctx, storage, getRepository(), []string{"targets"}, RotateRoleKeysOptions{})
+			Expect(err).NotTo(HaveOccurred())
+
+			rotatedPrivKeys, err := getRepositoryKeys(ctx, storage)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(publisher.ensureDelegatedRole(ctx, storage, first, DelegatedRoleReleases, releasesDelegationPaths())).To(Succeed())
+			second := getRepository()
+			Expect(publisher.ensureDelegatedRole(ctx, storage, second, DelegatedRoleReleases, releasesDelegationPaths())).To(Succeed())
+
+			storedPrivKeys, err := getRepositoryKeys(ctx, storage)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(storedPrivKeys.Targets).To(Equal(rotatedPrivKeys.Targets))
+			Expect(storedPrivKeys.Delegations).To(HaveKey(DelegatedRoleReleases))
+
+			By("reusing the stored key of the role added by the other handle")
+			Expect(second.GetPrivKeys().Delegations[DelegatedRoleReleases]).To(Equal(storedPrivKeys.Delegations[DelegatedRoleReleases]))
+			Expect(first.GetPrivKeys().Delegations[DelegatedRoleReleases]).To(Equal(storedPrivKeys.Delegations[DelegatedRoleReleases]))
+		})
+
+		It("should not re-apply the staged delegations", func() {
+			first := getRepository()
+			second := getRepository()
+
+			Expect(first.StageTarget(ctx, "first.txt", bytes.NewBufferString("first"), StageTargetOptions{})).To(Succeed())
+			Expect(first.CommitStaged(ctx)).To(Succeed())
+
+			Expect(second.AddDelegatedRole("docs", []string{"docs/*"}, AddDelegatedRoleOptions{})).To(Succeed())
+			Expect(second.CommitStaged(ctx)).To(MatchError(ErrConcurrentCommit))
+
+			Expect(getRepository().GetTargets(ctx)).To(ConsistOf("base.txt", "first.txt"))
+		})
+	})
+
+	Context("s3 storage backend", func() {
+		itPublishesRepository(func() (RepositoryOptions, string) {
+			options, ok := s3TestRepositoryOptions()
+			if !ok {
+				Skip("TRDL_TEST_S3_ENDPOINT and TRDL_TEST_S3_BUCKET_NAME are not set")
+			}
+
+			return options, options.S3Endpoint + "/" + options.S3BucketName
+		})
+	})
+})
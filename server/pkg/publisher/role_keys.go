@@ -0,0 +1,359 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/theupdateframework/go-tuf"
+	"github.com/theupdateframework/go-tuf/data"
+	"github.com/theupdateframework/go-tuf/pkg/keys"
+
+	"github.com/werf/trdl/server/pkg/transit"
+)
+
+// SetupExternalSigners signs the top-level roles with the external signers and the stored keys.
+// When the role is switched between the external signer and the stored key, the root does not trust the new key yet,
+// so the current role keys are replaced, if allowed, and true is returned. The replaced keys must be pruned
+// only after the replacement is committed, otherwise the repository could be left without the trusted keys.
+func (repository *Repository) SetupExternalSigners(allowKeyReplacement bool) (bool, error) {
+	var replaced bool
+
+	for _, role := range topLevelRoles {
+		signer, isExternal := repository.ExternalSigners[role]
+		if !isExternal {
+			storedSigners, err := repository.TufStore.PrivKeys.GetSigners(role)
+			if err != nil {
+				return false, fmt.Errorf("unable to get key signers for role %q: %w", role, err)
+			}
+
+			if len(storedSigners) > 0 {
+				continue
+			}
+
+			// The root key is offline, the root is signed outside the plugin.
+			if role == "root" {
+				continue
+			}
+
+			// The role has been signed by the external signer, which is not configured anymore.
+			if !allowKeyReplacement {
+				return false, fmt.Errorf("no key for the %s role: the key will be replaced with a new stored key on the next publication", role)
+			}
+
+			signer, err = keys.GenerateEd25519Key()
+			if err != nil {
+				return false, fmt.Errorf("unable to generate %s key: %w", role, err)
+			}
+		} else {
+			trusted, err := repository.isKeyTrusted(role, signer.PublicData())
+			if err != nil {
+				return false, err
+			}
+
+			if trusted {
+				if err := repository.TufStore.SaveSigner(role, signer); err != nil {
+					return false, fmt.Errorf("unable to save %s key signer into tuf store: %w", role, err)
+				}
+
+				continue
+			}
+
+			if !allowKeyReplacement {
+				repository.logger.Warn(fmt.Sprintf("The %s external key is not trusted by the TUF root yet: the stored key is used until the next publication", role))
+				continue
+			}
+		}
+
+		if err := repository.replaceRoleKey(role, signer); err != nil {
+			return false, err
+		}
+
+		replaced = true
+		repository.logger.Info(fmt.Sprintf("Replaced the %s role keys with the key %s", role, signer.PublicData().IDs()[0]))
+	}
+
+	return replaced, nil
+}
+
+// RotateRoleKey replaces the top-level role keys with a new key.
+// The Transit key gets a new version, otherwise a new key is generated and stored.
+func (repository *Repository) RotateRoleKey(ctx context.Context, role string) error {
+	var signer keys.Signer
+
+	if transitSigner, ok := repository.ExternalSigners[role].(*transit.Signer); ok {
+		rotatedSigner, err := transitSigner.Rotate(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to rotate %s transit key: %w", role, err)
+		}
+
+		repository.ExternalSigners[role] = rotatedSigner
+		signer = rotatedSigner
+	} else if _, ok := repository.ExternalSigners[role]; ok {
+		return fmt.Errorf("unable to rotate %s external key", role)
+	} else {
+		generatedSigner, err := keys.GenerateEd25519Key()
+		if err != nil {
+			return fmt.Errorf("unable to generate %s key: %w", role, err)
+		}
+
+		signer = generatedSigner
+	}
+
+	return repository.replaceRoleKey(role, signer)
+}
+
+// replaceRoleKey makes the signer the only key of the top-level role and re-signs the affected metadata.
+// The new key is added before the old ones are revoked, so the new root is signed both by the old and the new root keys,
+// and the clients trusting the old root can update.
+func (repository *Repository) replaceRoleKey(role string, signer keys.Signer) error {
+	offline, err := repository.IsRootKeyOffline()
+	if err != nil {
+		return err
+	}
+
+	if offline {
+		return fmt.Errorf("unable to replace %s key: %w", role, ErrRootKeyOffline)
+	}
+
+	root, err := repository.root()
+	if err != nil {
+		return err
+	}
+
+	var previousKeyIDs []string
+	if roleData, ok := root.Roles[role]; ok {
+		previousKeyIDs = append(previousKeyIDs, roleData.KeyIDs...)
+	}
+
+	if err := repository.TufRepo.AddPrivateKeyWithExpires(role, signer, repository.Expirations.expiresAt("root", time.Now())); err != nil {
+		return fmt.Errorf("unable to add %s key: %w", role, err)
+	}
+
+	if err := repository.TufRepo.SetThreshold(role, 1); err != nil {
+		return fmt.Errorf("unable to set %s threshold: %w", role, err)
+	}
+
+	newKeyIDs := signer.PublicData().IDs()
+	for _, keyID := range previousKeyIDs {
+		if lo.Contains(newKeyIDs, keyID) {
+			continue
+		}
+
+		// A key with several IDs is revoked by the first one.
+		if err := repository.TufRepo.RevokeKeyWithExpires(role, keyID, repository.Expirations.expiresAt("root", time.Now())); err != nil && !errors.As(err, &tuf.ErrKeyNotFound{}) {
+			return fmt.Errorf("unable to revoke %s key %q: %w", role, keyID, err)
+		}
+	}
+
+	// The snapshot and timestamp metadata is re-signed on commit.
+	if role == "targets" {
+		if err := repository.TufRepo.IncrementTargetsVersionWithExpires(repository.Expirations.expiresAt("targets", time.Now())); err != nil {
+			return fmt.Errorf("unable to re-sign targets: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// PruneUntrustedKeys removes the stored keys of the top-level roles, which are not trusted by the root anymore.
+// The keys must be pruned only after the root is committed.
+func (repository *Repository) PruneUntrustedKeys() error {
+	root, err := repository.root()
+	if err != nil {
+		return err
+	}
+
+	for _, role := range topLevelRoles {
+		signers, err := repository.TufStore.PrivKeys.GetSigners(role)
+		if err != nil {
+			return fmt.Errorf("unable to get key signers for role %q: %w", role, err)
+		}
+
+		for _, signer := range signers {
+			roleData, ok := root.Roles[role]
+			if ok && lo.Some(roleData.KeyIDs, signer.PublicData().IDs()) {
+				continue
+			}
+
+			repository.TufStore.PrivKeys.RemoveKey(role, signer.PublicData().IDs()[0])
+			repository.logger.Info(fmt.Sprintf("Removed the revoked %s key %s", role, signer.PublicData().IDs()[0]))
+		}
+	}
+
+	return nil
+}
+
+func (repository *Repository) isKeyTrusted(role string, key *data.PublicKey) (bool, error) {
+	root, err := repository.root()
+	if err != nil {
+		return false, err
+	}
+
+	roleData, ok := root.Roles[role]
+	if !ok {
+		return false, nil
+	}
+
+	return lo.Some(roleData.KeyIDs, key.IDs()), nil
+}
+
+func (repository *Repository) root() (*data.Root, error) {
+	return signedRoot(repository.TufRepo)
+}
+
+func signedRoot(tufRepo *tuf.Repo) (*data.Root, error) {
+	signed, err := tufRepo.SignedMeta("root.json")
+	if err != nil {
+		return nil, fmt.Errorf("unable to get root metadata: %w", err)
+	}
+
+	root := &data.Root{}
+	if err := json.Unmarshal(signed.Signed, root); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal root metadata: %w", err)
+	}
+
+	return root, nil
+}
+
+type RoleKeyLocation string
+
+const (
+	RoleKeyLocationStored   RoleKeyLocation = "stored"
+	RoleKeyLocationExternal RoleKeyLocation = "external"
+	RoleKeyLocationOffline  RoleKeyLocation = "offline"
+)
+
+type RoleKey struct {
+	KeyID     string
+	PublicKey *data.PublicKey
+	Location  RoleKeyLocation
+}
+
+// RoleKeys returns the keys trusted by the root for the top-level role and the role threshold.
+func (repository *Repository) RoleKeys(role string) ([]RoleKey, int, error) {
+	root, err := repository.root()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	roleData, ok := root.Roles[role]
+	if !ok {
+		return nil, 0, fmt.Errorf("no %s role in root", role)
+	}
+
+	storedSigners, err := repository.TufStore.PrivKeys.GetSigners(role)
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to get key signers for role %q: %w", role, err)
+	}
+
+	var roleKeys []RoleKey
+	for _, keyID := range roleData.KeyIDs {
+		location := RoleKeyLocationOffline
+		if lo.SomeBy(storedSigners, func(signer keys.Signer) bool { return lo.Contains(signer.PublicData().IDs(), keyID) }) {
+			location = RoleKeyLocationStored
+		} else if signer, ok := repository.ExternalSigners[role]; ok && lo.Contains(signer.PublicData().IDs(), keyID) {
+			location = RoleKeyLocationExternal
+		}
+
+		roleKeys = append(roleKeys, RoleKey{KeyID: keyID, PublicKey: root.Keys[keyID], Location: location})
+	}
+
+	return roleKeys, roleData.Threshold, nil
+}
+
+type UpdateRoleKeysOptions struct {
+	// AddKeys is the number of the new keys to generate and store.
+	AddKeys int
+	// RemoveKeyIDs are the IDs of the keys to revoke.
+	RemoveKeyIDs []string
+	// Threshold is the new role threshold, zero keeps the current one.
+	Threshold int
+}
+
+// UpdateRoleKeys adds and revokes the keys of the top-level role and changes the role threshold with the one root version.
+// The new root is signed both by the current and the new root keys, so the clients trusting the current root can update.
+// The revoked keys must be pruned only after the root is committed.
+func (repository *Repository) UpdateRoleKeys(role string, opts UpdateRoleKeysOptions) error {
+	if !lo.Contains(topLevelRoles, role) {
+		return fmt.Errorf("unexpected role %q: expected one of %v", role, topLevelRoles)
+	}
+
+	offline, err := repository.IsRootKeyOffline()
+	if err != nil {
+		return err
+	}
+
+	if offline {
+		return fmt.Errorf("unable to update %s keys: %w", role, ErrRootKeyOffline)
+	}
+
+	roleKeys, threshold, err := repository.RoleKeys(role)
+	if err != nil {
+		return err
+	}
+
+	for _, keyID := range opts.RemoveKeyIDs {
+		roleKey, found := lo.Find(roleKeys, func(roleKey RoleKey) bool { return roleKey.KeyID == keyID })
+		if !found {
+			return fmt.Errorf("no %s key %s", role, keyID)
+		}
+
+		if roleKey.Location == RoleKeyLocationExternal {
+			return fmt.Errorf("unable to remove %s key %s: the external key is configured, unset it first", role, keyID)
+		}
+	}
+
+	if opts.Threshold != 0 {
+		threshold = opts.Threshold
+	}
+
+	keptRoleKeys := lo.Reject(roleKeys, func(roleKey RoleKey, _ int) bool { return lo.Contains(opts.RemoveKeyIDs, roleKey.KeyID) })
+	keysNumber := len(keptRoleKeys) + opts.AddKeys
+	if threshold < 1 || threshold > keysNumber {
+		return fmt.Errorf("invalid %s threshold %d: expected a value between 1 and the number of keys %d", role, threshold, keysNumber)
+	}
+
+	signingKeysNumber := opts.AddKeys + lo.CountBy(keptRoleKeys, func(roleKey RoleKey) bool { return roleKey.Location != RoleKeyLocationOffline })
+	if signingKeysNumber < threshold {
+		return fmt.Errorf("invalid %s threshold %d: the plugin would sign with %d keys only", role, threshold, signingKeysNumber)
+	}
+
+	for i := 0; i < opts.AddKeys; i++ {
+		signer, err := keys.GenerateEd25519Key()
+		if err != nil {
+			return fmt.Errorf("unable to generate %s key: %w", role, err)
+		}
+
+		if err := repository.TufRepo.AddPrivateKeyWithExpires(role, signer, repository.Expirations.expiresAt("root", time.Now())); err != nil {
+			return fmt.Errorf("unable to add %s key: %w", role, err)
+		}
+
+		repository.logger.Info(fmt.Sprintf("Added the %s key %s", role, signer.PublicData().IDs()[0]))
+	}
+
+	// Lower the threshold before the revocation, so that the role always has enough keys.
+	if err := repository.TufRepo.SetThreshold(role, threshold); err != nil {
+		return fmt.Errorf("unable to set %s threshold: %w", role, err)
+	}
+
+	for _, keyID := range opts.RemoveKeyIDs {
+		if err := repository.TufRepo.RevokeKeyWithExpires(role, keyID, repository.Expirations.expiresAt("root", time.Now())); err != nil {
+			return fmt.Errorf("unable to revoke %s key %q: %w", role, keyID, err)
+		}
+
+		repository.logger.Info(fmt.Sprintf("Revoked the %s key %s", role, keyID))
+	}
+
+	// The snapshot and timestamp metadata is re-signed on commit.
+	if role == "targets" {
+		if err := repository.TufRepo.IncrementTargetsVersionWithExpires(repository.Expirations.expiresAt("targets", time.Now())); err != nil {
+			return fmt.Errorf("unable to re-sign targets: %w", err)
+		}
+	}
+
+	return nil
+}
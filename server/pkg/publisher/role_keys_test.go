@@ -0,0 +1,245 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	tufClient "github.com/theupdateframework/go-tuf/client"
+	"github.com/theupdateframework/go-tuf/data"
+
+	"github.com/werf/trdl/server/pkg/config"
+	"github.com/werf/trdl/server/pkg/transit"
+	transitTestutil "github.com/werf/trdl/server/pkg/transit/testutil"
+)
+
+var _ = Describe("Role keys", func() {
+	var (
+		ctx           context.Context
+		storage       logical.Storage
+		publisher     *Publisher
+		options       RepositoryOptions
+		repoUrl       string
+		transitServer *transitTestutil.Server
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		storage = &logical.InmemStorage{}
+		publisher = NewPublisher(hclog.NewNullLogger())
+
+		dir := GinkgoT().TempDir()
+		server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+		DeferCleanup(server.Close)
+
+		options = RepositoryOptions{
+			StorageBackend:          StorageBackendLocal,
+			LocalDirectory:          dir,
+			InitializeTUFKeys:       true,
+			InitializePGPSigningKey: true,
+		}
+		repoUrl = server.URL
+
+		transitServer = transitTestutil.NewServer()
+		DeferCleanup(transitServer.Close)
+		transitServer.CreateKey("tuf-root")
+		transitServer.CreateKey("tuf-targets")
+	})
+
+	configureTransit := func() {
+		Expect(transit.PutConfiguration(ctx, storage, &transit.Configuration{
+			Address:  transitServer.URL,
+			Token:    transitTestutil.Token,
+			Mount:    "transit",
+			KeyNames: map[string]string{"root": "tuf-root", "targets": "tuf-targets"},
+		})).To(Succeed())
+	}
+
+	publishRelease := func(release string) *Repository {
+		repository, err := publisher.GetRepository(ctx, storage, options)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(publisher.StageReleaseTarget(ctx, repository, release, "linux-amd64/bin/app", bytes.NewBufferString("app "+release), nil)).To(Succeed())
+		Expect(publisher.StageChannelsConfig(ctx, storage, repository, &config.TrdlChannels{
+			Groups: []config.TrdlGroup{
+				{Name: "1", Channels: []config.TrdlGroupChannel{{Name: "stable", Version: release}}},
+			},
+		}, StageChannelsConfigOptions{})).To(Succeed())
+		Expect(repository.CommitStaged(ctx)).To(Succeed())
+		return repository.(*Repository)
+	}
+
+	getRepository := func() *Repository {
+		repository, err := publisher.GetRepository(ctx, storage, options)
+		Expect(err).NotTo(HaveOccurred())
+		return repository.(*Repository)
+	}
+
+	readRoot := func(repository *Repository) *data.Root {
+		root, err := repository.root()
+		Expect(err).NotTo(HaveOccurred())
+		return root
+	}
+
+	readRootJSON := func(name string) []byte {
+		rootJSON, err := newTestRepositoryFilesystem(options).ReadFileBytes(ctx, name)
+		Expect(err).NotTo(HaveOccurred())
+		return rootJSON
+	}
+
+	expectClientToUpdate := func(rootJSON []byte, release string) {
+		client := newTestTufClient(repoUrl, rootJSON)
+		_, err := client.Update()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(downloadTestTarget(client, "releases/"+release+"/linux-amd64/bin/app")).To(Equal("app " + release))
+		Expect(downloadTestTarget(client, "channels/1/stable")).To(Equal(release + "\n"))
+	}
+
+	It("should sign a new repository with the Transit keys without storing them", func() {
+		configureTransit()
+		repository := publishRelease("1.0.0")
+
+		privKeys := repository.GetPrivKeys()
+		Expect(privKeys.Root).To(BeEmpty())
+		Expect(privKeys.Targets).To(BeEmpty())
+		Expect(privKeys.Snapshot).To(HaveLen(1))
+		Expect(privKeys.Timestamp).To(HaveLen(1))
+
+		root := readRoot(repository)
+		Expect(root.Roles["root"].KeyIDs).To(Equal(repository.ExternalSigners["root"].PublicData().IDs()))
+		Expect(root.Roles["targets"].KeyIDs).To(Equal(repository.ExternalSigners["targets"].PublicData().IDs()))
+
+		expectClientToUpdate(readRootJSON("1.root.json"), "1.0.0")
+	})
+
+	It("should switch the roles of an existing repository to the Transit keys and back on publication", func() {
+		repository := publishRelease("1.0.0")
+		storedRootKeyIDs := readRoot(repository).Roles["root"].KeyIDs
+
+		configureTransit()
+
+		By("keeping the stored keys until publication")
+		options.InitializeTUFKeys = false
+		readOnlyRepository, err := publisher.GetRepository(ctx, storage, options)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(readRoot(readOnlyRepository.(*Repository)).Roles["root"].KeyIDs).To(Equal(storedRootKeyIDs))
+		Expect(readOnlyRepository.GetPrivKeys().Root).To(HaveLen(1))
+
+		By("replacing the stored keys on publication")
+		options.InitializeTUFKeys = true
+		repository = publishRelease("1.1.0")
+
+		root := readRoot(repository)
+		Expect(root.Roles["root"].KeyIDs).To(Equal(repository.ExternalSigners["root"].PublicData().IDs()))
+		Expect(root.Roles["targets"].KeyIDs).To(Equal(repository.ExternalSigners["targets"].PublicData().IDs()))
+		for _, keyID := range storedRootKeyIDs {
+			Expect(root.Keys).NotTo(HaveKey(keyID))
+		}
+
+		entry, err := storage.Get(ctx, storageKeyTufRepositoryKeys)
+		Expect(err).NotTo(HaveOccurred())
+		var storedPrivKeys TufRepoPrivKeys
+		Expect(json.Unmarshal(entry.Value, &storedPrivKeys)).To(Succeed())
+		Expect(storedPrivKeys.Root).To(BeEmpty())
+		Expect(storedPrivKeys.Targets).To(BeEmpty())
+
+		// The clients trusting the initial root must accept the root signed with the new key.
+		expectClientToUpdate(readRootJSON("1.root.json"), "1.1.0")
+
+		By("refusing to replace the keys without the root Transit key, which is required to sign the new root")
+		Expect(transit.DeleteConfiguration(ctx, storage)).To(Succeed())
+		_, err = publisher.GetRepository(ctx, storage, options)
+		Expect(err).To(MatchError(ErrRootKeyOffline))
+
+		By("replacing the Transit key with a new stored key when the role is not configured anymore")
+		Expect(transit.PutConfiguration(ctx, storage, &transit.Configuration{
+			Address:  transitServer.URL,
+			Token:    transitTestutil.Token,
+			Mount:    "transit",
+			KeyNames: map[string]string{"root": "tuf-root"},
+		})).To(Succeed())
+		repository = publishRelease("1.2.0")
+
+		Expect(repository.GetPrivKeys().Targets).To(HaveLen(1))
+		Expect(readRoot(repository).Roles["targets"].KeyIDs).To(Equal(toPublicKeyIDs(repository.GetPrivKeys().Targets)))
+
+		expectClientToUpdate(readRootJSON("1.root.json"), "1.2.0")
+	})
+
+	It("should rotate the Transit key by creating a new key version", func() {
+		configureTransit()
+		repository := publishRelease("1.0.0")
+		previousKeyIDs := readRoot(repository).Roles["root"].KeyIDs
+
+		Expect(repository.RotateRoleKey(ctx, "root")).To(Succeed())
+		Expect(repository.CommitStaged(ctx)).To(Succeed())
+
+		Expect(repository.ExternalSigners["root"].(*transit.Signer).KeyVersion()).To(Equal(2))
+		Expect(readRoot(repository).Roles["root"].KeyIDs).NotTo(Equal(previousKeyIDs))
+		Expect(readRoot(repository).Roles["root"].KeyIDs).To(Equal(repository.ExternalSigners["root"].PublicData().IDs()))
+
+		expectClientToUpdate(readRootJSON("1.root.json"), "1.0.0")
+	})
+
+	It("should rotate the compromised keys right away and revoke them for the updated clients", func() {
+		repository := publishRelease("1.0.0")
+		Expect(publisher.UpdateRoleKeys(ctx, storage, repository, "targets", UpdateRoleKeysOptions{AddKeys: 1})).To(Succeed())
+
+		remote, err := tufClient.HTTPRemoteStore(repoUrl, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		clientStore := tufClient.MemoryLocalStore()
+		client := tufClient.NewClient(clientStore, remote)
+		Expect(client.Init(readRootJSON("1.root.json"))).To(Succeed())
+		_, err = client.Update()
+		Expect(err).NotTo(HaveOccurred())
+
+		compromisedKeyIDs := append(append([]string{}, readRoot(repository).Roles["targets"].KeyIDs...), readRoot(repository).Roles["snapshot"].KeyIDs...)
+
+		revokedKeyIDs, err := publisher.RotateRoleKeys(ctx, storage, getRepository(), []string{"targets", "snapshot", "targets"}, RotateRoleKeysOptions{Reason: "compromised"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(revokedKeyIDs).To(HaveLen(2))
+		Expect(append(revokedKeyIDs["targets"], revokedKeyIDs["snapshot"]...)).To(ConsistOf(compromisedKeyIDs))
+
+		repository = getRepository()
+		root := readRoot(repository)
+		Expect(root.Roles["targets"].KeyIDs).To(HaveLen(1))
+		for _, keyID := range compromisedKeyIDs {
+			Expect(root.Keys).NotTo(HaveKey(keyID))
+			Expect(toPublicKeyIDs(repository.GetPrivKeys().Targets)).NotTo(ContainElement(keyID))
+			Expect(toPublicKeyIDs(repository.GetPrivKeys().Snapshot)).NotTo(ContainElement(keyID))
+		}
+
+		_, err = client.Update()
+		Expect(err).NotTo(HaveOccurred())
+		clientMeta, err := clientStore.GetMeta()
+		Expect(err).NotTo(HaveOccurred())
+		clientSignedRoot := &data.Signed{}
+		Expect(json.Unmarshal(clientMeta["root.json"], clientSignedRoot)).To(Succeed())
+		clientRoot := &data.Root{}
+		Expect(json.Unmarshal(clientSignedRoot.Signed, clientRoot)).To(Succeed())
+		Expect(clientRoot.Version).To(Equal(root.Version))
+		for _, keyID := range compromisedKeyIDs {
+			Expect(clientRoot.Keys).NotTo(HaveKey(keyID))
+		}
+
+		publishRelease("1.1.0")
+		expectClientToUpdate(readRootJSON("1.root.json"), "1.1.0")
+
+		By("refusing the unexpected role")
+		_, err = publisher.RotateRoleKeys(ctx, storage, getRepository(), []string{"releases"}, RotateRoleKeysOptions{})
+		Expect(err).To(MatchError(ContainSubstring(`unexpected role "releases"`)))
+	})
+})
+
+func toPublicKeyIDs(privateKeys PrivateKeys) []string {
+	var ids []string
+	for _, key := range privateKeys {
+		ids = append(ids, publicKeyIDs(key)...)
+	}
+	return ids
+}
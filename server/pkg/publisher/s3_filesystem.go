@@ -15,6 +15,8 @@ import (
 	"github.com/hashicorp/go-hclog"
 )
 
+var _ Filesystem = (*S3Filesystem)(nil)
+
 type S3Filesystem struct {
 	AwsConfig  *aws.Config
 	BucketName string
@@ -179,3 +181,23 @@ func (fs *S3Filesystem) WriteFileStream(ctx context.Context, path string, data i
 
 	return nil
 }
+
+func (fs *S3Filesystem) DeleteFile(ctx context.Context, path string) error {
+	sess, err := session.NewSession(fs.AwsConfig)
+	if err != nil {
+		return fmt.Errorf("error opening s3 session: %w", err)
+	}
+
+	svc := s3.New(sess)
+
+	if _, err := svc.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: &fs.BucketName,
+		Key:    &path,
+	}); err != nil {
+		return fmt.Errorf("error deleting s3 object by key %q: %w", path, err)
+	}
+
+	fs.logger.Debug(fmt.Sprintf("-- S3Filesystem.DeleteFile deleted %q", path))
+
+	return nil
+}
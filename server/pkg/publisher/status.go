@@ -0,0 +1,79 @@
+package publisher
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// RoleStatus is the state of the published role metadata.
+type RoleStatus struct {
+	Role      string
+	Version   int64
+	Expires   time.Time
+	KeyIDs    []string
+	Threshold int
+}
+
+type signedCommon struct {
+	Version int64     `json:"version"`
+	Expires time.Time `json:"expires"`
+}
+
+// RoleStatuses returns the statuses of the top-level roles followed by the delegated targets roles.
+func (repository *Repository) RoleStatuses() ([]RoleStatus, error) {
+	root, err := repository.root()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []RoleStatus
+	for _, role := range topLevelRoles {
+		status := RoleStatus{Role: role}
+		if roleData, ok := root.Roles[role]; ok {
+			status.KeyIDs = roleData.KeyIDs
+			status.Threshold = roleData.Threshold
+		}
+
+		if err := repository.readRoleStatus(&status); err != nil {
+			return nil, err
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	delegations, err := delegatingTufRepo{repository.TufRepo}.delegations()
+	if err != nil {
+		return nil, err
+	}
+
+	if delegations != nil {
+		for _, role := range delegations.Roles {
+			status := RoleStatus{Role: role.Name, KeyIDs: role.KeyIDs, Threshold: role.Threshold}
+			if err := repository.readRoleStatus(&status); err != nil {
+				return nil, err
+			}
+
+			statuses = append(statuses, status)
+		}
+	}
+
+	return statuses, nil
+}
+
+func (repository *Repository) readRoleStatus(status *RoleStatus) error {
+	signed, err := repository.TufRepo.SignedMeta(status.Role + ".json")
+	if err != nil {
+		return fmt.Errorf("unable to get %s metadata: %w", status.Role, err)
+	}
+
+	var common signedCommon
+	if err := json.Unmarshal(signed.Signed, &common); err != nil {
+		return fmt.Errorf("unable to unmarshal %s metadata: %w", status.Role, err)
+	}
+
+	status.Version = common.Version
+	status.Expires = common.Expires
+
+	return nil
+}
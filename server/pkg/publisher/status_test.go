@@ -0,0 +1,57 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
+
+	"github.com/werf/trdl/server/pkg/config"
+)
+
+var _ = Describe("Repository status", func() {
+	It("should return the versions, expirations and keys of the roles", func() {
+		ctx := context.Background()
+		storage := &logical.InmemStorage{}
+		publisher := NewPublisher(hclog.NewNullLogger())
+
+		repository, err := publisher.GetRepository(ctx, storage, RepositoryOptions{
+			StorageBackend:          StorageBackendLocal,
+			LocalDirectory:          GinkgoT().TempDir(),
+			DelegatedRoles:          true,
+			InitializeTUFKeys:       true,
+			InitializePGPSigningKey: true,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(publisher.StageReleaseTarget(ctx, repository, "1.0.0", "linux-amd64/bin/app", bytes.NewBufferString("app"), nil)).To(Succeed())
+		Expect(publisher.StageChannelsConfig(ctx, storage, repository, &config.TrdlChannels{
+			Groups: []config.TrdlGroup{
+				{Name: "1", Channels: []config.TrdlGroupChannel{{Name: "stable", Version: "1.0.0"}}},
+			},
+		}, StageChannelsConfigOptions{})).To(Succeed())
+		Expect(repository.CommitStaged(ctx)).To(Succeed())
+
+		statuses, err := repository.RoleStatuses()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lo.Map(statuses, func(status RoleStatus, _ int) string { return status.Role })).
+			To(Equal([]string{"root", "targets", "snapshot", "timestamp", DelegatedRoleReleases, ChannelsDelegatedRoleName("1")}))
+
+		root, err := repository.(*Repository).root()
+		Expect(err).NotTo(HaveOccurred())
+		for _, status := range statuses {
+			Expect(status.Version).To(BeNumerically(">=", 1))
+			Expect(status.Expires).To(BeTemporally(">", time.Now()))
+			Expect(status.KeyIDs).NotTo(BeEmpty())
+			Expect(status.Threshold).To(Equal(1))
+
+			if roleData, ok := root.Roles[status.Role]; ok {
+				Expect(status.KeyIDs).To(Equal(roleData.KeyIDs))
+			}
+		}
+	})
+})
@@ -1,53 +1,134 @@
 package publisher
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"time"
 
+	"github.com/samber/lo"
 	"github.com/theupdateframework/go-tuf"
 	"github.com/theupdateframework/go-tuf/data"
 	"github.com/theupdateframework/go-tuf/pkg/keys"
+
+	"github.com/werf/trdl/server/pkg/transit"
 )
 
+var topLevelRoles = []string{"root", "targets", "snapshot", "timestamp"}
+
+// ValidateTopLevelRoles returns an error for the role which is not a top-level TUF role.
+func ValidateTopLevelRoles(roles []string) error {
+	for _, role := range roles {
+		if !lo.Contains(topLevelRoles, role) {
+			return fmt.Errorf("unexpected role %q: expected one of %v", role, topLevelRoles)
+		}
+	}
+
+	return nil
+}
+
+// PrivateKeys are the stored keys of the top-level role.
+type PrivateKeys []*data.PrivateKey
+
+// UnmarshalJSON also accepts a single key, which is how the keys were stored before the roles got multiple keys.
+func (privateKeys *PrivateKeys) UnmarshalJSON(b []byte) error {
+	var single *data.PrivateKey
+	if err := json.Unmarshal(b, &single); err == nil {
+		if single == nil {
+			*privateKeys = nil
+		} else {
+			*privateKeys = PrivateKeys{single}
+		}
+		return nil
+	}
+
+	var multiple []*data.PrivateKey
+	if err := json.Unmarshal(b, &multiple); err != nil {
+		return err
+	}
+	*privateKeys = multiple
+
+	return nil
+}
+
 type TufRepoPrivKeys struct {
-	Root      *data.PrivateKey `json:"root"`
-	Snapshot  *data.PrivateKey `json:"snapshot"`
-	Targets   *data.PrivateKey `json:"targets"`
-	Timestamp *data.PrivateKey `json:"timestamp"`
+	Root      PrivateKeys `json:"root"`
+	Snapshot  PrivateKeys `json:"snapshot"`
+	Targets   PrivateKeys `json:"targets"`
+	Timestamp PrivateKeys `json:"timestamp"`
+
+	// Delegations holds the keys of the delegated targets roles by the role name.
+	Delegations map[string]*data.PrivateKey `json:"delegations,omitempty"`
 }
 
+// SetKeyFromSigner stores the key of the signer for the role unless it is already stored.
+// The delegated targets role has the only key, which is replaced.
 func (keys *TufRepoPrivKeys) SetKeyFromSigner(role string, signer keys.Signer) error {
 	pk, err := signer.MarshalPrivateKey()
-	if err != nil {
+	if errors.Is(err, transit.ErrPrivateKeyNotExportable) {
+		// The role is signed by the Transit key, which is not stored.
+		return nil
+	} else if err != nil {
 		return fmt.Errorf("unable to marshal signer private key: %w", err)
 	}
 
+	roleKeys := keys.roleKeys(role)
+	if roleKeys == nil {
+		if keys.Delegations == nil {
+			keys.Delegations = make(map[string]*data.PrivateKey)
+		}
+		keys.Delegations[role] = pk
+
+		return nil
+	}
+
+	ids := signer.PublicData().IDs()
+	for _, key := range *roleKeys {
+		if lo.Some(publicKeyIDs(key), ids) {
+			return nil
+		}
+	}
+	*roleKeys = append(*roleKeys, pk)
+
+	return nil
+}
+
+// RemoveKey removes the stored key of the top-level role by any of the key IDs.
+func (privKeys *TufRepoPrivKeys) RemoveKey(role, keyID string) {
+	roleKeys := privKeys.roleKeys(role)
+	if roleKeys == nil {
+		panic(fmt.Sprintf("unexpected top-level role %q", role))
+	}
+
+	*roleKeys = lo.Reject(*roleKeys, func(key *data.PrivateKey, _ int) bool {
+		return lo.Contains(publicKeyIDs(key), keyID)
+	})
+}
+
+func (privKeys *TufRepoPrivKeys) roleKeys(role string) *PrivateKeys {
 	switch role {
 	case "root":
-		keys.Root = pk
-
+		return &privKeys.Root
 	case "targets":
-		keys.Targets = pk
-
+		return &privKeys.Targets
 	case "snapshot":
-		keys.Snapshot = pk
-
+		return &privKeys.Snapshot
 	case "timestamp":
-		keys.Timestamp = pk
-
+		return &privKeys.Timestamp
 	default:
-		panic(fmt.Sprintf("unknown role %q", role))
+		return nil
 	}
-
-	return nil
 }
 
 func (privKeys TufRepoPrivKeys) SetupStoreSigners(store tuf.LocalStore) error {
-	for _, role := range []string{"root", "targets", "snapshot", "timestamp"} {
-		signer, err := privKeys.GetSigner(role)
+	for _, role := range append(append([]string{}, topLevelRoles...), privKeys.DelegatedRoles()...) {
+		signers, err := privKeys.GetSigners(role)
 		if err != nil {
-			return fmt.Errorf("unable to get key signer for role %q: %w", role, err)
+			return fmt.Errorf("unable to get key signers for role %q: %w", role, err)
 		}
-		if signer != nil {
+
+		for _, signer := range signers {
 			if err := store.SaveSigner(role, signer); err != nil {
 				return fmt.Errorf("unable to save key signer for role %q into tuf store: %w", role, err)
 			}
@@ -57,51 +138,68 @@ func (privKeys TufRepoPrivKeys) SetupStoreSigners(store tuf.LocalStore) error {
 	return nil
 }
 
-func (privKeys TufRepoPrivKeys) SetupTufRepoSigners(tufRepo *tuf.Repo) error {
-	for _, desc := range []struct {
-		role string
-		key  *data.PrivateKey
-	}{
-		{"root", privKeys.Root},
-		{"targets", privKeys.Targets},
-		{"snapshot", privKeys.Snapshot},
-		{"timestamp", privKeys.Timestamp},
-	} {
-		signer, err := keys.GetSigner(desc.key)
+// SetupTufRepoSigners adds the stored keys to the repository, the root changed by the new keys expires at rootExpires.
+func (privKeys TufRepoPrivKeys) SetupTufRepoSigners(tufRepo *tuf.Repo, rootExpires time.Time) error {
+	root, err := signedRoot(tufRepo)
+	if errors.As(err, &tuf.ErrMissingMetadata{}) {
+		root = data.NewRoot()
+	} else if err != nil {
+		return err
+	}
+
+	for _, role := range topLevelRoles {
+		// The role without the stored keys is signed by an external key, e.g. the Transit one.
+		signers, err := privKeys.GetSigners(role)
 		if err != nil {
-			return fmt.Errorf("unable to get key signer for role %s: %w", desc.role, err)
+			return fmt.Errorf("unable to get key signers for role %s: %w", role, err)
 		}
 
-		if err := tufRepo.AddPrivateKeyWithExpires(desc.role, signer, data.DefaultExpires("root")); err != nil {
-			return fmt.Errorf("unable to add tuf repository private key for role %s: %w", desc.role, err)
+		for _, signer := range signers {
+			// The key revoked by the changes committed before the stored keys were updated must not be trusted again.
+			if roleData, ok := root.Roles[role]; ok && !lo.Some(roleData.KeyIDs, signer.PublicData().IDs()) {
+				continue
+			}
+
+			if err := tufRepo.AddPrivateKeyWithExpires(role, signer, rootExpires); err != nil {
+				return fmt.Errorf("unable to add tuf repository private key for role %s: %w", role, err)
+			}
 		}
 	}
 
 	return nil
 }
 
-func (privKeys TufRepoPrivKeys) GetSigner(role string) (keys.Signer, error) {
-	switch role {
-	case "root":
-		return toSigner(privKeys.Root)
-
-	case "targets":
-		return toSigner(privKeys.Targets)
+// GetSigners returns the signers of the stored role keys.
+func (privKeys TufRepoPrivKeys) GetSigners(role string) ([]keys.Signer, error) {
+	var roleKeys PrivateKeys
+	if ptr := privKeys.roleKeys(role); ptr != nil {
+		roleKeys = *ptr
+	} else if key, ok := privKeys.Delegations[role]; ok {
+		roleKeys = PrivateKeys{key}
+	}
 
-	case "snapshot":
-		return toSigner(privKeys.Snapshot)
+	var signers []keys.Signer
+	for _, key := range roleKeys {
+		signer, err := keys.GetSigner(key)
+		if err != nil {
+			return nil, err
+		}
+		signers = append(signers, signer)
+	}
 
-	case "timestamp":
-		return toSigner(privKeys.Timestamp)
+	return signers, nil
+}
 
-	default:
-		panic(fmt.Sprintf("unknown role %q", role))
-	}
+func (privKeys TufRepoPrivKeys) DelegatedRoles() []string {
+	roles := lo.Keys(privKeys.Delegations)
+	sort.Strings(roles)
+	return roles
 }
 
-func toSigner(key *data.PrivateKey) (keys.Signer, error) {
-	if key == nil {
-		return nil, nil
+func publicKeyIDs(key *data.PrivateKey) []string {
+	signer, err := keys.GetSigner(key)
+	if err != nil {
+		return nil
 	}
-	return keys.GetSigner(key)
+	return signer.PublicData().IDs()
 }
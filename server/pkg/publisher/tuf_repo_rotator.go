@@ -9,6 +9,12 @@ import (
 
 type TufRepoRotator struct {
 	TufRepo TufRepoRotatorAccessor
+
+	// OfflineRootKey disables the root rotation, since the root is signed outside the plugin.
+	// The rotator warns about the root expiring soon instead.
+	OfflineRootKey bool
+
+	Expirations TufExpirations
 }
 
 func NewTufRepoRotator(tufRepo TufRepoRotatorAccessor) *TufRepoRotator {
@@ -27,7 +33,13 @@ func (rotator *TufRepoRotator) Rotate(logger hclog.Logger, now time.Time) error
 		}
 		hitRotationPeriod := rotateAt.Sub(now) <= 0
 
-		if hitRotationPeriod {
+		if hitRotationPeriod && rotator.OfflineRootKey {
+			expiresAt, err := rotator.TufRepo.RootExpires()
+			if err != nil {
+				return fmt.Errorf("unable to get root.json expiration time: %w", err)
+			}
+			logger.Warn(fmt.Sprintf("TUF repository root.json expiring soon at %s: the root key is offline, export the root candidate, sign it with the offline root keys and import it", expiresAt.UTC().Format(time.RFC3339)))
+		} else if hitRotationPeriod {
 			if err := rotator.RotateRoot(now); err != nil {
 				return fmt.Errorf("unable to rotate root.json: %w", err)
 			}
@@ -52,6 +64,22 @@ func (rotator *TufRepoRotator) Rotate(logger hclog.Logger, now time.Time) error
 		}
 	}
 
+	{
+		rotateAt, err := rotator.GetDelegatedTargetsRotateAt()
+		if err != nil {
+			return fmt.Errorf("unable to get delegated targets rotation time: %w", err)
+		}
+		hitRotationPeriod := !rotateAt.IsZero() && rotateAt.Sub(now) <= 0
+
+		if hitRotationPeriod {
+			if err := rotator.RotateDelegatedTargets(now); err != nil {
+				return fmt.Errorf("unable to rotate delegated targets: %w", err)
+			}
+			changedTargets = true
+			logger.Debug(fmt.Sprintf("rotated delegated targets TUF repository roles because of hitRotationPeriod=%v\n", hitRotationPeriod))
+		}
+	}
+
 	{
 		rotateAt, err := rotator.GetSnapshotRotateAt()
 		if err != nil {
@@ -95,56 +123,69 @@ func (rotator *TufRepoRotator) Rotate(logger hclog.Logger, now time.Time) error
 	return nil
 }
 
-// Root expires every year, rotate every 3 month
 func (rotator *TufRepoRotator) GetRootRotateAt() (time.Time, error) {
 	expiresAt, err := rotator.TufRepo.RootExpires()
 	if err != nil {
 		return time.Time{}, fmt.Errorf("unable to get current expires: %w", err)
 	}
-	return expiresAt.AddDate(-1, 0, 0).AddDate(0, 3, 0), nil
+	return rotator.Expirations.refreshAt("root", expiresAt), nil
 }
 
 func (rotator *TufRepoRotator) RotateRoot(now time.Time) error {
-	return rotator.TufRepo.IncrementRootVersionWithExpires(now.AddDate(1, 0, 0))
+	return rotator.TufRepo.IncrementRootVersionWithExpires(rotator.Expirations.expiresAt("root", now))
 }
 
-// Targets expires every 3 month, rotate every 3 weeks
 func (rotator *TufRepoRotator) GetTargetsRotateAt() (time.Time, error) {
 	expiresAt, err := rotator.TufRepo.TargetsExpires()
 	if err != nil {
 		return time.Time{}, fmt.Errorf("unable to get current expires: %w", err)
 	}
-	return expiresAt.AddDate(0, -3, 0).AddDate(0, 0, 21), nil
+	return rotator.Expirations.refreshAt("targets", expiresAt), nil
 }
 
 func (rotator *TufRepoRotator) RotateTargets(now time.Time) error {
-	return rotator.TufRepo.IncrementTargetsVersionWithExpires(now.AddDate(0, 3, 0))
+	return rotator.TufRepo.IncrementTargetsVersionWithExpires(rotator.Expirations.expiresAt("targets", now))
+}
+
+// Delegated targets expire and rotate as the top-level targets.
+// Zero time means there are no delegated targets roles.
+func (rotator *TufRepoRotator) GetDelegatedTargetsRotateAt() (time.Time, error) {
+	expiresAt, err := rotator.TufRepo.DelegatedTargetsExpires()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to get current expires: %w", err)
+	}
+	if expiresAt.IsZero() {
+		return time.Time{}, nil
+	}
+	return rotator.Expirations.refreshAt("targets", expiresAt), nil
+}
+
+func (rotator *TufRepoRotator) RotateDelegatedTargets(now time.Time) error {
+	return rotator.TufRepo.IncrementDelegatedTargetsVersionWithExpires(rotator.Expirations.expiresAt("targets", now))
 }
 
-// Snapshot expires every 7 days, rotate every 2nd day
 func (rotator *TufRepoRotator) GetSnapshotRotateAt() (time.Time, error) {
 	expiresAt, err := rotator.TufRepo.SnapshotExpires()
 	if err != nil {
 		return time.Time{}, fmt.Errorf("unable to get current expires: %w", err)
 	}
-	return expiresAt.AddDate(0, 0, -7).AddDate(0, 0, 2), nil
+	return rotator.Expirations.refreshAt("snapshot", expiresAt), nil
 }
 
 func (rotator *TufRepoRotator) RotateSnapshot(now time.Time) error {
-	return rotator.TufRepo.IncrementSnapshotVersionWithExpires(now.AddDate(0, 0, 7))
+	return rotator.TufRepo.IncrementSnapshotVersionWithExpires(rotator.Expirations.expiresAt("snapshot", now))
 }
 
-// Timestamp expires every day, rotate every 4th hour
 func (rotator *TufRepoRotator) GetTimestampRotateAt() (time.Time, error) {
 	expiresAt, err := rotator.TufRepo.TimestampExpires()
 	if err != nil {
 		return time.Time{}, fmt.Errorf("unable to get current expires: %w", err)
 	}
-	return expiresAt.AddDate(0, 0, -1).Add(time.Hour * 4), nil
+	return rotator.Expirations.refreshAt("timestamp", expiresAt), nil
 }
 
 func (rotator *TufRepoRotator) RotateTimestamp(now time.Time) error {
-	return rotator.TufRepo.IncrementTimestampVersionWithExpires(now.AddDate(0, 0, 1))
+	return rotator.TufRepo.IncrementTimestampVersionWithExpires(rotator.Expirations.expiresAt("timestamp", now))
 }
 
 func (rotator *TufRepoRotator) Commit() error {
@@ -154,11 +195,13 @@ func (rotator *TufRepoRotator) Commit() error {
 type TufRepoRotatorAccessor interface {
 	RootExpires() (time.Time, error)
 	TargetsExpires() (time.Time, error)
+	DelegatedTargetsExpires() (time.Time, error)
 	SnapshotExpires() (time.Time, error)
 	TimestampExpires() (time.Time, error)
 
 	IncrementRootVersionWithExpires(expires time.Time) error
 	IncrementTargetsVersionWithExpires(expires time.Time) error
+	IncrementDelegatedTargetsVersionWithExpires(expires time.Time) error
 	IncrementSnapshotVersionWithExpires(expires time.Time) error
 	IncrementTimestampVersionWithExpires(expires time.Time) error
 
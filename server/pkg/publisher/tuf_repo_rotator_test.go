@@ -1,6 +1,7 @@
 package publisher
 
 import (
+	"bytes"
 	"time"
 
 	"github.com/hashicorp/go-hclog"
@@ -232,13 +233,120 @@ var _ = Describe("TUF repo rotator", func() {
 		_ = prevSnapshotExpires
 		_ = prevTimestampExpires
 	})
+
+	It("should rotate delegated targets roles based on expiration timestamps", func() {
+		now := time.Now()
+
+		testRepo := &testTufRepoRotatorAccessor{
+			rootExpires:             now.AddDate(1, 0, 0),
+			targetsExpires:          now.AddDate(0, 3, 0),
+			delegatedTargetsExpires: now.AddDate(0, 3, 0),
+			snapshotExpires:         now.AddDate(0, 0, 7),
+			timestampExpires:        now.AddDate(0, 0, 1),
+		}
+
+		rotator := NewTufRepoRotator(testRepo)
+
+		By("passed 20 days")
+		now = now.AddDate(0, 0, 20)
+		prevDelegatedTargetsExpires := testRepo.delegatedTargetsExpires
+		Expect(rotator.Rotate(hclog.Default(), now)).To(Succeed())
+		Expect(testRepo.delegatedTargetsExpires).To(Equal(prevDelegatedTargetsExpires))
+
+		By("passed 21 days")
+		now = now.AddDate(0, 0, 1)
+		testRepo.targetsExpires = now.AddDate(0, 3, 0)
+		Expect(rotator.Rotate(hclog.Default(), now)).To(Succeed())
+		// rotate every 21st day (3 weeks)
+		Expect(testRepo.delegatedTargetsExpires).To(Equal(now.AddDate(0, 3, 0)))
+		// rotated not because of rotation period, but as dependant role of delegated targets
+		Expect(testRepo.snapshotExpires).To(Equal(now.AddDate(0, 0, 7)))
+		Expect(testRepo.timestampExpires).To(Equal(now.AddDate(0, 0, 1)))
+	})
+
+	It("should not rotate delegated targets roles if there are no delegations", func() {
+		now := time.Now()
+
+		testRepo := &testTufRepoRotatorAccessor{
+			rootExpires:      now.AddDate(1, 0, 0),
+			targetsExpires:   now.AddDate(0, 3, 0),
+			snapshotExpires:  now.AddDate(0, 0, 7),
+			timestampExpires: now.AddDate(0, 0, 1),
+		}
+
+		Expect(NewTufRepoRotator(testRepo).Rotate(hclog.Default(), now)).To(Succeed())
+		Expect(testRepo.delegatedTargetsExpires).To(BeZero())
+		Expect(testRepo.snapshotExpires).To(Equal(now.AddDate(0, 0, 7)))
+	})
+
+	It("should warn about root expiring soon instead of rotating it if the root key is offline", func() {
+		now := time.Now()
+
+		testRepo := &testTufRepoRotatorAccessor{
+			rootExpires:      now.AddDate(0, 9, 0),
+			targetsExpires:   now,
+			snapshotExpires:  now,
+			timestampExpires: now,
+		}
+
+		rotator := NewTufRepoRotator(testRepo)
+		rotator.OfflineRootKey = true
+
+		logs := bytes.NewBuffer(nil)
+		Expect(rotator.Rotate(hclog.New(&hclog.LoggerOptions{Output: logs}), now)).To(Succeed())
+		Expect(logs.String()).To(ContainSubstring("root.json expiring soon"))
+		Expect(testRepo.rootExpires).To(Equal(now.AddDate(0, 9, 0)))
+		Expect(testRepo.targetsExpires).To(Equal(now.AddDate(0, 3, 0)))
+		Expect(testRepo.snapshotExpires).To(Equal(now.AddDate(0, 0, 7)))
+		Expect(testRepo.timestampExpires).To(Equal(now.AddDate(0, 0, 1)))
+	})
+
+	It("should rotate roles based on the configured expirations", func() {
+		now := time.Now()
+
+		testRepo := &testTufRepoRotatorAccessor{
+			rootExpires:      now.AddDate(1, 0, 0),
+			targetsExpires:   now,
+			snapshotExpires:  now.AddDate(0, 0, 7),
+			timestampExpires: now,
+		}
+
+		rotator := NewTufRepoRotator(testRepo)
+		rotator.Expirations = TufExpirations{
+			Targets:   MetadataExpiration{Expires: 30 * 24 * time.Hour, RefreshLead: 10 * 24 * time.Hour},
+			Timestamp: MetadataExpiration{Expires: 72 * time.Hour, RefreshLead: 48 * time.Hour},
+		}
+
+		Expect(rotator.Rotate(hclog.Default(), now)).To(Succeed())
+		Expect(testRepo.rootExpires).To(Equal(now.AddDate(1, 0, 0)))
+		Expect(testRepo.targetsExpires).To(Equal(now.Add(30 * 24 * time.Hour)))
+		Expect(testRepo.snapshotExpires).To(Equal(now.AddDate(0, 0, 7)))
+		Expect(testRepo.timestampExpires).To(Equal(now.Add(72 * time.Hour)))
+
+		By("keeping the timestamp until the refresh lead")
+		now = now.Add(23 * time.Hour)
+		Expect(rotator.Rotate(hclog.Default(), now)).To(Succeed())
+		Expect(testRepo.timestampExpires).To(Equal(now.Add(-23 * time.Hour).Add(72 * time.Hour)))
+
+		By("refreshing the timestamp within the refresh lead")
+		now = now.Add(2 * time.Hour)
+		Expect(rotator.Rotate(hclog.Default(), now)).To(Succeed())
+		Expect(testRepo.timestampExpires).To(Equal(now.Add(72 * time.Hour)))
+
+		By("refreshing the targets within the refresh lead")
+		now = now.Add(20 * 24 * time.Hour)
+		Expect(rotator.Rotate(hclog.Default(), now)).To(Succeed())
+		Expect(testRepo.targetsExpires).To(Equal(now.Add(30 * 24 * time.Hour)))
+		Expect(testRepo.snapshotExpires).To(Equal(now.AddDate(0, 0, 7)))
+	})
 })
 
 type testTufRepoRotatorAccessor struct {
-	rootExpires      time.Time
-	targetsExpires   time.Time
-	snapshotExpires  time.Time
-	timestampExpires time.Time
+	rootExpires             time.Time
+	targetsExpires          time.Time
+	delegatedTargetsExpires time.Time
+	snapshotExpires         time.Time
+	timestampExpires        time.Time
 }
 
 func (repo *testTufRepoRotatorAccessor) RootExpires() (time.Time, error) {
@@ -249,6 +357,10 @@ func (repo *testTufRepoRotatorAccessor) TargetsExpires() (time.Time, error) {
 	return repo.targetsExpires, nil
 }
 
+func (repo *testTufRepoRotatorAccessor) DelegatedTargetsExpires() (time.Time, error) {
+	return repo.delegatedTargetsExpires, nil
+}
+
 func (repo *testTufRepoRotatorAccessor) SnapshotExpires() (time.Time, error) {
 	return repo.snapshotExpires, nil
 }
@@ -267,6 +379,11 @@ func (repo *testTufRepoRotatorAccessor) IncrementTargetsVersionWithExpires(expir
 	return nil
 }
 
+func (repo *testTufRepoRotatorAccessor) IncrementDelegatedTargetsVersionWithExpires(expires time.Time) error {
+	repo.delegatedTargetsExpires = expires
+	return nil
+}
+
 func (repo *testTufRepoRotatorAccessor) IncrementSnapshotVersionWithExpires(expires time.Time) error {
 	repo.snapshotExpires = expires
 	return nil
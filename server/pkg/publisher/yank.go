@@ -0,0 +1,118 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// yankedTargetCustom is the custom metadata of the yanked/<release> target,
+// the clients read the reason without downloading the target.
+type yankedTargetCustom struct {
+	Reason string `json:"reason"`
+}
+
+type YankReleaseOptions struct {
+	Reason string
+}
+
+type YankedRelease struct {
+	Name   string
+	Reason string
+}
+
+// YankRelease removes the release targets and signatures and publishes the yanked/<release> target with the reason.
+// The release used by any channel is not yanked.
+func (publisher *Publisher) YankRelease(ctx context.Context, storage logical.Storage, repository RepositoryInterface, releaseName string, opts YankReleaseOptions) error {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	targetFiles, err := repository.GetTargetFiles(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting existing targets: %w", err)
+	}
+
+	targetPaths := releaseTargetPaths(targetFiles, releaseName)
+	if len(targetPaths) == 0 {
+		return fmt.Errorf("release %q not found", releaseName)
+	}
+
+	channels, err := repository.GetChannels(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting channels: %w", err)
+	}
+
+	var releaseChannels []string
+	for _, channel := range channels {
+		if channel.Version == releaseName {
+			releaseChannels = append(releaseChannels, path.Join(channel.Group, channel.Name))
+		}
+	}
+
+	if len(releaseChannels) > 0 {
+		return fmt.Errorf("release %q is used by the channels %v", releaseName, releaseChannels)
+	}
+
+	if err := publisher.ensureDelegatedRole(ctx, storage, repository, DelegatedRoleYanked, yankedDelegationPaths()); err != nil {
+		return fmt.Errorf("error initializing yanked delegated role: %w", err)
+	}
+
+	if err := repository.RemoveTargets(ctx, targetPaths); err != nil {
+		return fmt.Errorf("unable to remove release %q targets: %w", releaseName, err)
+	}
+
+	custom, err := json.Marshal(yankedTargetCustom{Reason: opts.Reason})
+	if err != nil {
+		return fmt.Errorf("unable to marshal yanked release %q custom metadata: %w", releaseName, err)
+	}
+
+	yankedPath := path.Join("yanked", releaseName)
+	if err := repository.StageTarget(ctx, yankedPath, bytes.NewBufferString(opts.Reason+"\n"), StageTargetOptions{Custom: custom}); err != nil {
+		return fmt.Errorf("error publishing %q: %w", yankedPath, err)
+	}
+
+	if err := repository.CommitStaged(ctx); err != nil {
+		return fmt.Errorf("unable to commit yanked release %q: %w", releaseName, err)
+	}
+
+	publisher.logger.Info(fmt.Sprintf("Yanked release %q: removed targets %v, reason: %q", releaseName, targetPaths, opts.Reason))
+
+	return nil
+}
+
+// GetYankedReleases returns the yanked releases sorted by name.
+func (publisher *Publisher) GetYankedReleases(ctx context.Context, repository RepositoryInterface) ([]YankedRelease, error) {
+	targetFiles, err := repository.GetTargetFiles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting existing targets: %w", err)
+	}
+
+	var yankedReleases []YankedRelease
+	for targetPath, meta := range targetFiles {
+		releaseName, ok := strings.CutPrefix(targetPath, "yanked/")
+		if !ok {
+			continue
+		}
+
+		var custom yankedTargetCustom
+		if meta.Custom != nil {
+			if err := json.Unmarshal(*meta.Custom, &custom); err != nil {
+				return nil, fmt.Errorf("unable to unmarshal %q custom metadata: %w", targetPath, err)
+			}
+		}
+
+		yankedReleases = append(yankedReleases, YankedRelease{Name: releaseName, Reason: custom.Reason})
+	}
+
+	sort.Slice(yankedReleases, func(i, j int) bool {
+		return yankedReleases[i].Name < yankedReleases[j].Name
+	})
+
+	return yankedReleases, nil
+}
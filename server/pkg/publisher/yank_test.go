@@ -0,0 +1,100 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/trdl/server/pkg/config"
+)
+
+var _ = Describe("Yank release", func() {
+	var (
+		ctx       context.Context
+		storage   logical.Storage
+		publisher *Publisher
+		options   RepositoryOptions
+		repoUrl   string
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		storage = &logical.InmemStorage{}
+		publisher = NewPublisher(hclog.NewNullLogger())
+
+		dir := GinkgoT().TempDir()
+		server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+		DeferCleanup(server.Close)
+
+		options = RepositoryOptions{
+			StorageBackend:          StorageBackendLocal,
+			LocalDirectory:          dir,
+			InitializeTUFKeys:       true,
+			InitializePGPSigningKey: true,
+		}
+		repoUrl = server.URL
+	})
+
+	getRepository := func() *Repository {
+		repository, err := publisher.GetRepository(ctx, storage, options)
+		Expect(err).NotTo(HaveOccurred())
+		return repository.(*Repository)
+	}
+
+	BeforeEach(func() {
+		repository := getRepository()
+		for _, release := range []string{"1.0.0", "1.1.0"} {
+			Expect(publisher.StageReleaseTarget(ctx, repository, release, "linux-amd64/bin/app", bytes.NewBufferString("app "+release), nil)).To(Succeed())
+		}
+		Expect(publisher.StageChannelsConfig(ctx, storage, repository, &config.TrdlChannels{
+			Groups: []config.TrdlGroup{
+				{Name: "1", Channels: []config.TrdlGroupChannel{{Name: "stable", Version: "1.1.0"}}},
+			},
+		}, StageChannelsConfigOptions{})).To(Succeed())
+		Expect(repository.CommitStaged(ctx)).To(Succeed())
+	})
+
+	It("should remove the release targets and publish the yanked release", func() {
+		Expect(publisher.YankRelease(ctx, storage, getRepository(), "1.0.0", YankReleaseOptions{Reason: "broken"})).To(Succeed())
+
+		repository := getRepository()
+		Expect(publisher.GetExistingReleases(ctx, repository)).To(Equal([]string{"1.1.0"}))
+		Expect(publisher.GetYankedReleases(ctx, repository)).To(Equal([]YankedRelease{{Name: "1.0.0", Reason: "broken"}}))
+
+		rootJSON, err := newTestRepositoryFilesystem(options).ReadFileBytes(ctx, "1.root.json")
+		Expect(err).NotTo(HaveOccurred())
+
+		client := newTestTufClient(repoUrl, rootJSON)
+		_, err = client.Update()
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, name := range []string{"releases/1.0.0/linux-amd64/bin/app", "signatures/1.0.0/linux-amd64/bin/app.sig"} {
+			_, err := client.Target(name)
+			Expect(err).To(HaveOccurred(), name)
+		}
+
+		yankedMeta, err := client.Target("yanked/1.0.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(yankedMeta.Custom).NotTo(BeNil())
+		Expect(json.RawMessage(*yankedMeta.Custom)).To(MatchJSON(`{"reason": "broken"}`))
+		Expect(downloadTestTarget(client, "releases/1.1.0/linux-amd64/bin/app")).To(Equal("app 1.1.0"))
+	})
+
+	It("should refuse to yank the release used by the channels", func() {
+		Expect(publisher.YankRelease(ctx, storage, getRepository(), "1.1.0", YankReleaseOptions{Reason: "broken"})).
+			To(MatchError(ContainSubstring(`release "1.1.0" is used by the channels [1/stable]`)))
+		Expect(publisher.GetYankedReleases(ctx, getRepository())).To(BeEmpty())
+	})
+
+	It("should refuse to yank the unknown release", func() {
+		Expect(publisher.YankRelease(ctx, storage, getRepository(), "2.0.0", YankReleaseOptions{Reason: "broken"})).
+			To(MatchError(ContainSubstring(`release "2.0.0" not found`)))
+	})
+})
@@ -18,10 +18,10 @@ var (
 
 const taskReasonInvalidatedTask = "the task canceled due to restart of the plugin"
 
-func (m *Manager) RunTask(ctx context.Context, reqStorage logical.Storage, taskFunc func(context.Context, logical.Storage) error) (string, error) {
+func (m *Manager) RunTask(ctx context.Context, reqStorage logical.Storage, taskFunc func(context.Context, logical.Storage) error, opts TaskOptions) (string, error) {
 	var taskUUID string
-	err := m.doTaskWrap(ctx, reqStorage, taskFunc, func(newTaskFunc func(ctx context.Context) error) error {
-		busy, err := m.isBusy(ctx, reqStorage)
+	err := m.doTaskWrap(ctx, reqStorage, taskFunc, opts, func(newTaskFunc func(ctx context.Context) error) error {
+		busy, err := m.isBusy(ctx, reqStorage, opts.Resources)
 		if err != nil {
 			return err
 		}
@@ -30,15 +30,15 @@ func (m *Manager) RunTask(ctx context.Context, reqStorage logical.Storage, taskF
 			return ErrBusy
 		}
 
-		taskUUID, err = m.queueTask(ctx, newTaskFunc)
+		taskUUID, err = m.queueTask(ctx, newTaskFunc, opts)
 		return err
 	})
 
 	return taskUUID, err
 }
 
-func (m *Manager) AddOptionalTask(ctx context.Context, reqStorage logical.Storage, taskFunc func(context.Context, logical.Storage) error) (string, bool, error) {
-	taskUUID, err := m.RunTask(ctx, reqStorage, taskFunc)
+func (m *Manager) AddOptionalTask(ctx context.Context, reqStorage logical.Storage, taskFunc func(context.Context, logical.Storage) error, opts TaskOptions) (string, bool, error) {
+	taskUUID, err := m.RunTask(ctx, reqStorage, taskFunc, opts)
 	if err != nil {
 		if errors.Is(err, ErrBusy) {
 			return taskUUID, false, nil
@@ -50,11 +50,11 @@ func (m *Manager) AddOptionalTask(ctx context.Context, reqStorage logical.Storag
 	return taskUUID, true, nil
 }
 
-func (m *Manager) AddTask(ctx context.Context, reqStorage logical.Storage, taskFunc func(context.Context, logical.Storage) error) (string, error) {
+func (m *Manager) AddTask(ctx context.Context, reqStorage logical.Storage, taskFunc func(context.Context, logical.Storage) error, opts TaskOptions) (string, error) {
 	var taskUUID string
-	err := m.doTaskWrap(ctx, reqStorage, taskFunc, func(newTaskFunc func(ctx context.Context) error) error {
+	err := m.doTaskWrap(ctx, reqStorage, taskFunc, opts, func(newTaskFunc func(ctx context.Context) error) error {
 		var err error
-		taskUUID, err = m.queueTask(ctx, newTaskFunc)
+		taskUUID, err = m.queueTask(ctx, newTaskFunc, opts)
 
 		return err
 	})
@@ -62,7 +62,7 @@ func (m *Manager) AddTask(ctx context.Context, reqStorage logical.Storage, taskF
 	return taskUUID, err
 }
 
-func (m *Manager) doTaskWrap(ctx context.Context, reqStorage logical.Storage, taskFunc func(context.Context, logical.Storage) error, f func(func(ctx context.Context) error) error) error {
+func (m *Manager) doTaskWrap(ctx context.Context, reqStorage logical.Storage, taskFunc func(context.Context, logical.Storage) error, opts TaskOptions, f func(func(ctx context.Context) error) error) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -79,16 +79,9 @@ func (m *Manager) doTaskWrap(ctx context.Context, reqStorage logical.Storage, ta
 		return fmt.Errorf("unable to get tasks manager configuration: %w", err)
 	}
 
-	var taskTimeoutDuration time.Duration
-	if config != nil {
-		taskTimeoutDuration = config.TaskTimeout
-	} else {
-		taskTimeoutDuration = defaultTaskTimeoutDuration
-	}
-
-	workerTaskFunc := m.WrapTaskFunc(taskFunc, taskTimeoutDuration)
+	m.Worker.SetPoolSize(config.workerPoolSize())
 
-	return f(workerTaskFunc)
+	return f(m.wrapTaskFunc(taskFunc, config, opts.IsRetryableError))
 }
 
 // WrapTaskFunc separates processing of the context and the taskFunc execution in the background
@@ -151,27 +144,68 @@ func (m *Manager) invalidateStorage(ctx context.Context, reqStorage logical.Stor
 	return nil
 }
 
-func (m *Manager) queueTask(ctx context.Context, workerTaskFunc func(context.Context) error) (string, error) {
-	queuedTaskUUID, err := addNewTaskToStorage(ctx, m.Storage)
+func (m *Manager) queueTask(ctx context.Context, workerTaskFunc func(context.Context) error, opts TaskOptions) (string, error) {
+	queuedTaskUUID, err := addNewTaskToStorage(ctx, m.Storage, opts)
 	if err != nil {
 		return "", err
 	}
 
-	m.taskChan <- &worker.Task{Context: context.WithoutCancel(ctx), UUID: queuedTaskUUID, Action: workerTaskFunc}
+	m.enqueueTask(ctx, queuedTaskUUID, opts.Resources, workerTaskFunc)
 
 	return queuedTaskUUID, nil
 }
 
-func (m *Manager) isBusy(ctx context.Context, reqStorage logical.Storage) (bool, error) {
-	// busy if there are running or queued tasks
-	for _, prefix := range []string{storageKeyPrefixRunningTask, storageKeyPrefixQueuedTask} {
+func (m *Manager) enqueueTask(ctx context.Context, queuedTaskUUID string, resources []string, workerTaskFunc func(context.Context) error) {
+	taskCtx := context.WithValue(context.WithoutCancel(ctx), taskUUIDContextKey{}, queuedTaskUUID)
+	taskCtx = context.WithValue(taskCtx, taskWorkerContextKey{}, m.Worker)
+	m.taskChan <- &worker.Task{Context: taskCtx, UUID: queuedTaskUUID, Resources: resources, Action: workerTaskFunc}
+}
+
+type (
+	taskUUIDContextKey   struct{}
+	taskWorkerContextKey struct{}
+)
+
+// TaskUUIDFromContext returns the UUID of the task the context is passed to, the empty string outside of the task.
+func TaskUUIDFromContext(ctx context.Context) string {
+	taskUUID, _ := ctx.Value(taskUUIDContextKey{}).(string)
+	return taskUUID
+}
+
+// LockTaskResource locks the resource for the part of the task, e.g. to serialize the commits of the concurrent tasks.
+// The resource is unlocked by the returned function or on the task completion.
+func LockTaskResource(ctx context.Context, resource string) (func(), error) {
+	w, ok := ctx.Value(taskWorkerContextKey{}).(worker.Interface)
+	if !ok {
+		panic("runtime error: the resource can be locked only by the task")
+	}
+
+	unlock, err := w.LockResource(ctx, TaskUUIDFromContext(ctx), resource)
+	if err != nil {
+		return nil, fmt.Errorf("unable to lock resource %q: %w", resource, err)
+	}
+
+	return unlock, nil
+}
+
+// isBusy returns true if there are running or queued tasks with the resources conflicting with the new task.
+func (m *Manager) isBusy(ctx context.Context, reqStorage logical.Storage, resources []string) (bool, error) {
+	for _, state := range []taskState{taskStateRunning, taskStateQueued} {
+		prefix := taskStorageKeyPrefix(state)
 		list, err := reqStorage.List(ctx, prefix)
 		if err != nil {
 			return false, fmt.Errorf("unable to list %q in storage: %w", prefix, err)
 		}
 
-		if len(list) != 0 {
-			return true, nil
+		for _, uuid := range list {
+			task, err := getTaskFromStorage(ctx, reqStorage, state, uuid)
+			if err != nil {
+				return false, err
+			}
+
+			if task != nil && worker.ResourcesConflict(task.Resources, resources) {
+				return true, nil
+			}
 		}
 	}
 
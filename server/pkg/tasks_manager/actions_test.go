@@ -3,7 +3,9 @@ package tasks_manager
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/sdk/logical"
@@ -22,7 +24,7 @@ func TestManager_RunTask(t *testing.T) {
 	var uuids []string
 	// check the first task
 	{
-		uuid, err := m.RunTask(ctx, storage, noneTask)
+		uuid, err := m.RunTask(ctx, storage, noneTask, TaskOptions{})
 		assert.Nil(t, err)
 		assert.NotEmpty(t, uuid)
 		assert.NotNil(t, m.Storage, "must be initialized on the first action call")
@@ -34,7 +36,7 @@ func TestManager_RunTask(t *testing.T) {
 
 	// check the second task
 	{
-		uuid, err := m.RunTask(ctx, storage, noneTask)
+		uuid, err := m.RunTask(ctx, storage, noneTask, TaskOptions{})
 		if assert.Error(t, err) {
 			assert.Equal(t, err, ErrBusy)
 		}
@@ -58,7 +60,7 @@ func TestManager_RunTaskWithCurrentRunningTask(t *testing.T) {
 	runningTaskUUID := assertAndAddRunningTaskToStorage(t, ctx, storage)
 
 	{
-		uuid, err := m.RunTask(ctx, storage, noneTask)
+		uuid, err := m.RunTask(ctx, storage, noneTask, TaskOptions{})
 		if assert.Error(t, err) {
 			assert.Equal(t, err, ErrBusy)
 		}
@@ -70,7 +72,7 @@ func TestManager_RunTaskWithCurrentRunningTask(t *testing.T) {
 	assert.Nil(t, err)
 
 	{
-		uuid, err := m.RunTask(ctx, storage, noneTask)
+		uuid, err := m.RunTask(ctx, storage, noneTask, TaskOptions{})
 		assert.Nil(t, err)
 		assert.NotEmpty(t, uuid)
 
@@ -81,6 +83,33 @@ func TestManager_RunTaskWithCurrentRunningTask(t *testing.T) {
 	}
 }
 
+// check that Manager.RunTask returns the busy error only for the task with the conflicting resources
+func TestManager_RunTaskWithResources(t *testing.T) {
+	ctx := context.Background()
+	m := initManagerWithoutWorker()
+	storage := &logical.InmemStorage{}
+
+	buildTaskUUID, err := m.RunTask(ctx, storage, noneTask, TaskOptions{Resources: []string{"build"}})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, buildTaskUUID)
+
+	task := <-m.taskChan
+	assert.Equal(t, []string{"build"}, task.Resources)
+
+	_, err = m.RunTask(ctx, storage, noneTask, TaskOptions{Resources: []string{"build", "channels"}})
+	assert.Equal(t, ErrBusy, err)
+
+	_, err = m.RunTask(ctx, storage, noneTask, TaskOptions{})
+	assert.Equal(t, ErrBusy, err, "the task without resources runs exclusively")
+
+	channelsTaskUUID, err := m.RunTask(ctx, storage, noneTask, TaskOptions{Resources: []string{"channels"}})
+	assert.Nil(t, err)
+	assertQueuedTaskInStorage(t, ctx, storage, channelsTaskUUID)
+
+	task = <-m.taskChan
+	assert.Equal(t, channelsTaskUUID, task.UUID)
+}
+
 // check that Manager.RunTask invalidates inconsistent storage
 func TestManager_RunTaskInvalidateStorage(t *testing.T) {
 	ctx := context.Background()
@@ -92,7 +121,7 @@ func TestManager_RunTaskInvalidateStorage(t *testing.T) {
 	runningTaskUUID := assertAndAddRunningTaskToStorage(t, ctx, storage)
 	assert.Nil(t, m.Storage, "must be initialized on the first action call")
 
-	uuid, err := m.RunTask(ctx, storage, noneTask)
+	uuid, err := m.RunTask(ctx, storage, noneTask, TaskOptions{})
 	assert.Nil(t, err)
 	assert.NotEmpty(t, uuid)
 
@@ -134,7 +163,7 @@ func TestManager_AddTask(t *testing.T) {
 	assert.Nil(t, m.Storage, "must be initialized on the first action call")
 	var uuids []string
 	for i := 0; i < 2; i++ {
-		uuid, err := m.AddTask(ctx, storage, noneTask)
+		uuid, err := m.AddTask(ctx, storage, noneTask, TaskOptions{})
 		assert.Nil(t, err)
 		assert.NotEmpty(t, uuid)
 		if i == 0 {
@@ -163,7 +192,7 @@ func TestManager_AddOptionalTask(t *testing.T) {
 	var uuids []string
 	// check the first task
 	{
-		uuid, added, err := m.AddOptionalTask(ctx, storage, noneTask)
+		uuid, added, err := m.AddOptionalTask(ctx, storage, noneTask, TaskOptions{})
 		assert.Nil(t, err)
 		assert.NotEmpty(t, uuid)
 		assert.True(t, added)
@@ -176,7 +205,7 @@ func TestManager_AddOptionalTask(t *testing.T) {
 
 	// check the second task
 	{
-		uuid, added, err := m.AddOptionalTask(ctx, storage, noneTask)
+		uuid, added, err := m.AddOptionalTask(ctx, storage, noneTask, TaskOptions{})
 		assert.Nil(t, err)
 		assert.Empty(t, uuid)
 		assert.False(t, added)
@@ -229,7 +258,7 @@ func TestManager_RunTaskDetachesRequestCtx(t *testing.T) {
 	storage := &logical.InmemStorage{}
 
 	ctx, cancel := context.WithCancel(context.Background())
-	uuid, err := m.RunTask(ctx, storage, noneTask)
+	uuid, err := m.RunTask(ctx, storage, noneTask, TaskOptions{})
 	assert.Nil(t, err)
 	assert.NotEmpty(t, uuid)
 
@@ -240,9 +269,24 @@ func TestManager_RunTaskDetachesRequestCtx(t *testing.T) {
 	assert.NoError(t, task.Context.Err(), "queued task ctx must survive request ctx cancellation")
 }
 
+func TestManager_RunTaskPassesTaskUUID(t *testing.T) {
+	m := initManagerWithoutWorker()
+	storage := &logical.InmemStorage{}
+
+	assert.Empty(t, TaskUUIDFromContext(context.Background()))
+
+	uuid, err := m.RunTask(context.Background(), storage, noneTask, TaskOptions{})
+	assert.Nil(t, err)
+
+	task := <-m.taskChan
+	assert.Equal(t, uuid, TaskUUIDFromContext(task.Context))
+}
+
 func initManagerWithoutWorker() *Manager {
 	taskChan := make(chan *worker.Task, taskChanSize)
-	m := &Manager{taskChan: taskChan, logger: hclog.L()}
+	m := &Manager{taskChan: taskChan, logger: hclog.L(), webhookClient: http.DefaultClient, webhookBackoff: time.Millisecond, resumeFuncs: make(map[string]taskResumer)}
+	m.webhookCtx, m.webhookCancel = context.WithCancel(context.Background())
+	m.Worker = worker.NewWorker(context.Background(), taskChan, m)
 	return m
 }
 
@@ -254,3 +298,29 @@ func assertQueuedTaskInStorage(t *testing.T, ctx context.Context, storage logica
 	assert.NotNil(t, task)
 	assert.Equal(t, task.Status, string(taskStatusQueued))
 }
+
+func TestPutTaskResult(t *testing.T) {
+	m := initManagerWithoutWorker()
+	storage := &logical.InmemStorage{}
+
+	uuid, err := m.RunTask(context.Background(), storage, noneTask, TaskOptions{Operation: "release", Initiator: "token-ci", InitiatorEntityID: "entity"})
+	assert.Nil(t, err)
+
+	task := <-m.taskChan
+	assert.Error(t, PutTaskResult(task.Context, storage, map[string]interface{}{"git_commit": "abc"}), "queued task must not have result")
+
+	assert.Nil(t, switchTaskToRunningInStorage(task.Context, storage, uuid))
+	assert.Nil(t, PutTaskResult(task.Context, storage, map[string]interface{}{"git_commit": "abc"}))
+	assert.Nil(t, switchTaskToCompletedInStorage(task.Context, storage, taskStatusSucceeded, uuid, switchTaskToCompletedInStorageOptions{}))
+
+	completedTask, err := getTaskFromStorage(context.Background(), storage, taskStateCompleted, uuid)
+	assert.Nil(t, err)
+	if assert.NotNil(t, completedTask) {
+		assert.Equal(t, "release", completedTask.Operation)
+		assert.Equal(t, "token-ci", completedTask.Initiator)
+		assert.Equal(t, "entity", completedTask.InitiatorEntityID)
+		assert.Equal(t, map[string]interface{}{"git_commit": "abc"}, completedTask.Result)
+	}
+
+	assert.Panics(t, func() { _ = PutTaskResult(context.Background(), storage, nil) })
+}
@@ -3,35 +3,62 @@ package tasks_manager
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/fatih/structs"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/samber/lo"
 
 	"github.com/werf/trdl/server/pkg/tasks_manager/worker"
 )
 
 const (
-	fieldNameTaskTimeout      = "task_timeout"
-	fieldNameTaskHistoryLimit = "task_history_limit"
-	fieldNameUUID             = "uuid"
-	fieldNameLimit            = "limit"
-	fieldNameOffset           = "offset"
+	fieldNameTaskTimeout       = "task_timeout"
+	fieldNameTaskHistoryLimit  = "task_history_limit"
+	fieldNameResumeTasks       = "resume_interrupted_tasks"
+	fieldNameWorkerPoolSize    = "worker_pool_size"
+	fieldNameTaskMaxAttempts   = "task_max_attempts"
+	fieldNameTaskRetryBackoff  = "task_retry_backoff"
+	fieldNameTaskRetryTimeout  = "task_retry_timeout"
+	fieldNameUUID              = "uuid"
+	fieldNameLimit             = "limit"
+	fieldNameOffset            = "offset"
+	fieldNameOperation         = "operation"
+	fieldNameStatus            = "status"
+	fieldNameCreatedAfter      = "created_after"
+	fieldNameCreatedBefore     = "created_before"
+	fieldNameContinuationToken = "continuation_token"
+	fieldNameWebhookName       = "name"
+	fieldNameWebhookURL        = "url"
+	fieldNameWebhookSecret     = "secret"
+	fieldNameWebhookEvents     = "events"
 
 	fieldDefaultTaskTimeout      = "30m"
 	fieldDefaultTaskHistoryLimit = 10
+	fieldDefaultWorkerPoolSize   = 1
+	fieldDefaultTaskMaxAttempts  = 1
+	fieldDefaultTaskRetryBackoff = "30s"
+	fieldDefaultTaskRetryTimeout = "2h"
 	fieldDefaultLimit            = 500
 
-	defaultTaskTimeoutDuration = 30 * time.Minute
+	defaultTaskTimeoutDuration      = 30 * time.Minute
+	defaultTaskRetryTimeoutDuration = 2 * time.Hour
 )
 
 var (
-	pathPatternConfigure  = "task/configure/?"
-	pathPatternTaskList   = "task/?"
-	pathPatternTaskStatus = "task/" + uuidPattern(fieldNameUUID) + "$"
-	pathPatternTaskCancel = "task/" + uuidPattern(fieldNameUUID) + "/cancel$"
-	pathPatternTaskLog    = "task/" + uuidPattern(fieldNameUUID) + "/log$"
+	pathPatternConfigure             = "task/configure/?"
+	pathPatternTaskList              = "task/?"
+	pathPatternTaskStatus            = "task/" + uuidPattern(fieldNameUUID) + "$"
+	pathPatternTaskCancel            = "task/" + uuidPattern(fieldNameUUID) + "/cancel$"
+	pathPatternTaskLog               = "task/" + uuidPattern(fieldNameUUID) + "/log$"
+	pathPatternTaskWebhookDeliveries = "task/" + uuidPattern(fieldNameUUID) + "/webhooks$"
+	pathPatternWebhookList           = "task/webhook/?$"
+	pathPatternWebhook               = "task/webhook/" + framework.GenericNameRegex(fieldNameWebhookName) + "$"
 
 	errorResponseConfigurationNotFound = logical.ErrorResponse("Configuration not found")
 )
@@ -52,6 +79,31 @@ func (m *Manager) Paths() []*framework.Path {
 					Description: "Task history limit",
 					Default:     fieldDefaultTaskHistoryLimit,
 				},
+				fieldNameResumeTasks: {
+					Type:        framework.TypeBool,
+					Description: "Resume the queued and running tasks interrupted by the restart of the plugin instead of canceling them. The running task is restarted from its last safe checkpoint, the task that cannot be resumed safely is canceled. The resumed tasks use the git credentials from the storage",
+					Default:     false,
+				},
+				fieldNameWorkerPoolSize: {
+					Type:        framework.TypeInt,
+					Description: "The number of tasks running concurrently. The tasks using the same resources (e.g. the release builds) still run one by one",
+					Default:     fieldDefaultWorkerPoolSize,
+				},
+				fieldNameTaskMaxAttempts: {
+					Type:        framework.TypeInt,
+					Description: "The maximum number of attempts of the task failed with a transient error (e.g. a network timeout, a reset connection or an S3 5xx response), 1 disables the retries. The task timeout applies to each attempt, the task retry timeout to all of them",
+					Default:     fieldDefaultTaskMaxAttempts,
+				},
+				fieldNameTaskRetryBackoff: {
+					Type:        framework.TypeDurationSecond,
+					Description: "The delay before the second attempt of the task, doubled for each next attempt",
+					Default:     fieldDefaultTaskRetryBackoff,
+				},
+				fieldNameTaskRetryTimeout: {
+					Type:        framework.TypeDurationSecond,
+					Description: "The total duration of the task attempts and the delays between them, after which the failed task is not retried anymore",
+					Default:     fieldDefaultTaskRetryTimeout,
+				},
 			},
 			Operations: map[logical.Operation]framework.OperationHandler{
 				logical.CreateOperation: &framework.PathOperation{
@@ -71,8 +123,33 @@ func (m *Manager) Paths() []*framework.Path {
 		{
 			Pattern:         pathPatternTaskList,
 			HelpSynopsis:    "Get tasks",
-			HelpDescription: "Get tasks",
-			Fields:          map[string]*framework.FieldSchema{},
+			HelpDescription: "Get tasks sorted by the creation time, the newest first. The task summaries are returned in key_info. If there are more tasks than the limit, the continuation_token is returned to get the next page",
+			Fields: map[string]*framework.FieldSchema{
+				fieldNameStatus: {
+					Type:        framework.TypeString,
+					Description: "List only the tasks with the status: QUEUED, RUNNING, SUCCEEDED, FAILED or CANCELED",
+				},
+				fieldNameOperation: {
+					Type:        framework.TypeString,
+					Description: "List only the tasks of the operation (e.g. release or publish)",
+				},
+				fieldNameCreatedAfter: {
+					Type:        framework.TypeTime,
+					Description: "List only the tasks created after the time (RFC3339 or Unix time)",
+				},
+				fieldNameCreatedBefore: {
+					Type:        framework.TypeTime,
+					Description: "List only the tasks created before the time (RFC3339 or Unix time)",
+				},
+				fieldNameLimit: {
+					Type:        framework.TypeInt,
+					Description: "The maximum number of the tasks to list (all the tasks are listed by default)",
+				},
+				fieldNameContinuationToken: {
+					Type:        framework.TypeString,
+					Description: "The continuation token of the previous page",
+				},
+			},
 			Operations: map[logical.Operation]framework.OperationHandler{
 				logical.ReadOperation: &framework.PathOperation{
 					Description: "Get a list of task UUIDs",
@@ -149,22 +226,116 @@ func (m *Manager) Paths() []*framework.Path {
 				},
 			},
 		},
+		{
+			Pattern:      pathPatternTaskWebhookDeliveries,
+			HelpSynopsis: "Get the task webhook deliveries",
+			Fields: map[string]*framework.FieldSchema{
+				fieldNameUUID: {
+					Type:        framework.TypeNameString,
+					Description: "Task UUID",
+					Required:    true,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Description: "Get the state of the task events delivery to the webhooks",
+					Callback:    m.pathTaskWebhookDeliveriesRead,
+				},
+			},
+		},
+		{
+			Pattern:      pathPatternWebhookList,
+			HelpSynopsis: "List the task webhooks",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Description: "List the task webhook names",
+					Callback:    m.pathWebhookList,
+				},
+			},
+		},
+		{
+			Pattern:         pathPatternWebhook,
+			HelpSynopsis:    "Configure the task webhook",
+			HelpDescription: "The task events are POSTed to the webhook URL as JSON with the task UUID, operation, parameters, status, reason and log tail. The payload is signed with HMAC-SHA256 of the secret in the X-Trdl-Signature header (sha256=<hex>). The failed delivery is retried with the exponential backoff, the delivery in progress is failed on the backend shutdown or the restart of the plugin",
+			Fields: map[string]*framework.FieldSchema{
+				fieldNameWebhookName: {
+					Type:        framework.TypeNameString,
+					Description: "The webhook name",
+					Required:    true,
+				},
+				fieldNameWebhookURL: {
+					Type:        framework.TypeString,
+					Description: "The http(s) URL to POST the task events to",
+					Required:    true,
+				},
+				fieldNameWebhookSecret: {
+					Type:        framework.TypeString,
+					Description: "The secret to sign the payload with",
+					Required:    true,
+				},
+				fieldNameWebhookEvents: {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "The task events to send: started, succeeded or failed (all the events are sent by default)",
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.CreateOperation: &framework.PathOperation{
+					Description: "Configure the task webhook",
+					Callback:    m.pathWebhookCreateOrUpdate,
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Description: "Configure the task webhook",
+					Callback:    m.pathWebhookCreateOrUpdate,
+				},
+				logical.ReadOperation: &framework.PathOperation{
+					Description: "Get the task webhook",
+					Callback:    m.pathWebhookRead,
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Description: "Delete the task webhook",
+					Callback:    m.pathWebhookDelete,
+				},
+			},
+		},
 	}
 }
 
 func (m *Manager) pathConfigureCreateOrUpdate(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
 	taskTimeout := time.Duration(fields.Get(fieldNameTaskTimeout).(int)) * time.Second
 	taskHistoryLimit := fields.Get(fieldNameTaskHistoryLimit).(int)
+	workerPoolSize := fields.Get(fieldNameWorkerPoolSize).(int)
+	taskMaxAttempts := fields.Get(fieldNameTaskMaxAttempts).(int)
+	taskRetryBackoff := time.Duration(fields.Get(fieldNameTaskRetryBackoff).(int)) * time.Second
+	taskRetryTimeout := time.Duration(fields.Get(fieldNameTaskRetryTimeout).(int)) * time.Second
+
+	if workerPoolSize < 1 {
+		return logical.ErrorResponse("Field %q must be positive", fieldNameWorkerPoolSize), nil
+	}
+
+	if taskMaxAttempts < 1 {
+		return logical.ErrorResponse("Field %q must be positive", fieldNameTaskMaxAttempts), nil
+	}
+
+	if taskRetryTimeout <= 0 {
+		return logical.ErrorResponse("Field %q must be positive", fieldNameTaskRetryTimeout), nil
+	}
 
 	cfg := &configuration{
-		TaskTimeout:      taskTimeout,
-		TaskHistoryLimit: taskHistoryLimit,
+		TaskTimeout:            taskTimeout,
+		TaskHistoryLimit:       taskHistoryLimit,
+		ResumeInterruptedTasks: fields.Get(fieldNameResumeTasks).(bool),
+		WorkerPoolSize:         workerPoolSize,
+		TaskMaxAttempts:        taskMaxAttempts,
+		TaskRetryBackoff:       taskRetryBackoff,
+		TaskRetryTimeout:       taskRetryTimeout,
 	}
 
 	if err := putConfiguration(ctx, req.Storage, cfg); err != nil {
 		return nil, fmt.Errorf("unable to save configuration: %w", err)
 	}
 
+	m.Worker.SetPoolSize(cfg.workerPoolSize())
+
 	return nil, nil
 }
 
@@ -180,22 +351,138 @@ func (m *Manager) pathConfigureRead(ctx context.Context, req *logical.Request, _
 
 	data := structs.Map(c)
 	data[fieldNameTaskTimeout] = c.TaskTimeout / time.Second
+	data[fieldNameWorkerPoolSize] = c.workerPoolSize()
+	data[fieldNameTaskMaxAttempts] = c.taskMaxAttempts()
+	data[fieldNameTaskRetryBackoff] = c.TaskRetryBackoff / time.Second
+	data[fieldNameTaskRetryTimeout] = c.taskRetryTimeout() / time.Second
 	return &logical.Response{Data: data}, nil
 }
 
-func (m *Manager) pathTaskList(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
-	var list []string
+func (m *Manager) pathTaskList(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	status := taskStatus(fields.Get(fieldNameStatus).(string))
+	operation := fields.Get(fieldNameOperation).(string)
+	createdAfter := fields.Get(fieldNameCreatedAfter).(time.Time)
+	createdBefore := fields.Get(fieldNameCreatedBefore).(time.Time)
+	limit := fields.Get(fieldNameLimit).(int)
+
+	if status != "" && status != taskStatusQueued && status != taskStatusRunning && !isCompletedTaskStatus(status) {
+		return logical.ErrorResponse("%s validation failed: unexpected task status %q", fieldNameStatus, status), nil
+	}
+
+	if limit < 0 {
+		return logical.ErrorResponse("Field %q cannot be negative", fieldNameLimit), nil
+	}
+
+	var after *taskListPosition
+	if token := fields.Get(fieldNameContinuationToken).(string); token != "" {
+		position, err := parseTaskListContinuationToken(token)
+		if err != nil {
+			return logical.ErrorResponse("%s validation failed: %s", fieldNameContinuationToken, err), nil
+		}
+
+		after = position
+	}
+
+	var tasks []*Task
 	for _, state := range []taskState{taskStateCompleted, taskStateRunning, taskStateQueued} {
+		if status != "" && taskStatusState(status) != state {
+			continue
+		}
+
 		prefix := taskStorageKeyPrefix(state)
-		l, err := req.Storage.List(ctx, prefix)
+		list, err := req.Storage.List(ctx, prefix)
 		if err != nil {
 			return nil, fmt.Errorf("unable to list %q in storage: %w", prefix, err)
 		}
 
-		list = append(list, l...)
+		for _, uuid := range list {
+			task, err := getTaskFromStorage(ctx, req.Storage, state, uuid)
+			if err != nil {
+				return nil, err
+			}
+
+			switch {
+			case task == nil,
+				status != "" && task.Status != string(status),
+				operation != "" && task.Operation != operation,
+				!createdAfter.IsZero() && !task.Created.After(createdAfter),
+				!createdBefore.IsZero() && !task.Created.Before(createdBefore),
+				after != nil && !after.isBefore(task):
+				continue
+			}
+
+			tasks = append(tasks, task)
+		}
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		return newTaskListPosition(tasks[i]).isBefore(tasks[j])
+	})
+
+	var continuationToken string
+	if limit != 0 && len(tasks) > limit {
+		tasks = tasks[:limit]
+		continuationToken = newTaskListPosition(tasks[limit-1]).continuationToken()
+	}
+
+	keys := make([]string, 0, len(tasks))
+	keyInfo := make(map[string]interface{}, len(tasks))
+	for _, task := range tasks {
+		keys = append(keys, task.UUID)
+		keyInfo[task.UUID] = map[string]interface{}{
+			"status":    task.Status,
+			"operation": task.Operation,
+			"params":    task.Params,
+			"initiator": task.Initiator,
+			"created":   task.Created,
+			"modified":  task.Modified,
+		}
+	}
+
+	resp := logical.ListResponseWithInfo(keys, keyInfo)
+	if continuationToken != "" {
+		resp.Data[fieldNameContinuationToken] = continuationToken
+	}
+
+	return resp, nil
+}
+
+// taskListPosition is the position of the task in the list sorted by the creation time, the newest first.
+type taskListPosition struct {
+	created int64
+	uuid    string
+}
+
+func newTaskListPosition(task *Task) *taskListPosition {
+	return &taskListPosition{created: task.Created.UnixNano(), uuid: task.UUID}
+}
+
+func parseTaskListContinuationToken(token string) (*taskListPosition, error) {
+	created, uuid, found := strings.Cut(token, "_")
+	if !found || uuid == "" {
+		return nil, fmt.Errorf("invalid token %q", token)
+	}
+
+	createdUnixNano, err := strconv.ParseInt(created, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token %q", token)
+	}
+
+	return &taskListPosition{created: createdUnixNano, uuid: uuid}, nil
+}
+
+func (p *taskListPosition) continuationToken() string {
+	return fmt.Sprintf("%d_%s", p.created, p.uuid)
+}
+
+// isBefore reports whether the task goes after the position in the list.
+func (p *taskListPosition) isBefore(task *Task) bool {
+	created := task.Created.UnixNano()
+	if created != p.created {
+		return created < p.created
 	}
 
-	return logical.ListResponse(list), nil
+	return task.UUID < p.uuid
 }
 
 func (m *Manager) pathTaskStatus(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
@@ -314,6 +601,106 @@ func (m *Manager) pathTaskLogRead(ctx context.Context, req *logical.Request, fie
 	}, nil
 }
 
+func (m *Manager) pathTaskWebhookDeliveriesRead(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	uuid := fields.Get(fieldNameUUID).(string)
+
+	deliveries, err := getWebhookDeliveries(ctx, req.Storage, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveriesData := lo.Map(deliveries, func(delivery *webhookDelivery, _ int) map[string]interface{} {
+		return map[string]interface{}{
+			"webhook":    delivery.Webhook,
+			"event":      string(delivery.Event),
+			"status":     string(delivery.Status),
+			"attempts":   delivery.Attempts,
+			"last_error": delivery.LastError,
+			"modified":   delivery.Modified,
+		}
+	})
+
+	return &logical.Response{Data: map[string]interface{}{"deliveries": deliveriesData}}, nil
+}
+
+func (m *Manager) pathWebhookList(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	names, err := req.Storage.List(ctx, storageKeyPrefixWebhook)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list %q in storage: %w", storageKeyPrefixWebhook, err)
+	}
+
+	return logical.ListResponse(names), nil
+}
+
+func (m *Manager) pathWebhookCreateOrUpdate(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	webhookURL, err := url.Parse(fields.Get(fieldNameWebhookURL).(string))
+	if err != nil || (webhookURL.Scheme != "http" && webhookURL.Scheme != "https") || webhookURL.Host == "" {
+		return logical.ErrorResponse("%s validation failed: expected http(s) URL", fieldNameWebhookURL), nil
+	}
+
+	secret := fields.Get(fieldNameWebhookSecret).(string)
+	if secret == "" {
+		return logical.ErrorResponse("Field %q must not be empty", fieldNameWebhookSecret), nil
+	}
+
+	events := taskEvents
+	if eventNames := fields.Get(fieldNameWebhookEvents).([]string); len(eventNames) != 0 {
+		events = nil
+		for _, eventName := range eventNames {
+			event := TaskEvent(eventName)
+			if !lo.Contains(taskEvents, event) {
+				return logical.ErrorResponse("%s validation failed: expected %q, %q or %q, got %q", fieldNameWebhookEvents, TaskEventStarted, TaskEventSucceeded, TaskEventFailed, eventName), nil
+			}
+
+			events = append(events, event)
+		}
+	}
+
+	w := &webhook{
+		Name:   fields.Get(fieldNameWebhookName).(string),
+		URL:    webhookURL.String(),
+		Secret: secret,
+		Events: lo.Uniq(events),
+	}
+
+	if err := putWebhook(ctx, req.Storage, w); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (m *Manager) pathWebhookRead(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	name := fields.Get(fieldNameWebhookName).(string)
+
+	w, err := getWebhook(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if w == nil {
+		return logical.ErrorResponse("Webhook %q not found", name), nil
+	}
+
+	// The secret is write-only.
+	return &logical.Response{
+		Data: map[string]interface{}{
+			fieldNameWebhookName:   w.Name,
+			fieldNameWebhookURL:    w.URL,
+			fieldNameWebhookEvents: lo.Map(w.Events, func(event TaskEvent, _ int) string { return string(event) }),
+		},
+	}, nil
+}
+
+func (m *Manager) pathWebhookDelete(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	storageKey := webhookStorageKey(fields.Get(fieldNameWebhookName).(string))
+	if err := req.Storage.Delete(ctx, storageKey); err != nil {
+		return nil, fmt.Errorf("unable to delete %q from storage: %w", storageKey, err)
+	}
+
+	return nil, nil
+}
+
 const uuidPatternRegexp = "(?i:[0-9A-F]{8}-[0-9A-F]{4}-[4][0-9A-F]{3}-[89AB][0-9A-F]{3}-[0-9A-F]{12})"
 
 func uuidPattern(name string) string {
@@ -38,6 +38,10 @@ func TestManager_pathConfigureCreateOrUpdate(t *testing.T) {
 				assert.Equal(t, &configuration{
 					TaskTimeout:      defaultTaskTimeoutDuration,
 					TaskHistoryLimit: fieldDefaultTaskHistoryLimit,
+					WorkerPoolSize:   fieldDefaultWorkerPoolSize,
+					TaskMaxAttempts:  fieldDefaultTaskMaxAttempts,
+					TaskRetryBackoff: 30 * time.Second,
+					TaskRetryTimeout: defaultTaskRetryTimeoutDuration,
 				}, c)
 			})
 
@@ -55,6 +59,11 @@ func TestManager_pathConfigureCreateOrUpdate(t *testing.T) {
 					Data: map[string]interface{}{
 						fieldNameTaskTimeout:      fieldValueTaskTimeout,
 						fieldNameTaskHistoryLimit: fieldValueTaskHistoryLimit,
+						fieldNameResumeTasks:      true,
+						fieldNameWorkerPoolSize:   4,
+						fieldNameTaskMaxAttempts:  3,
+						fieldNameTaskRetryBackoff: "1m",
+						fieldNameTaskRetryTimeout: "10m",
 					},
 					Storage: storage,
 				}
@@ -66,10 +75,45 @@ func TestManager_pathConfigureCreateOrUpdate(t *testing.T) {
 				c, err := getConfiguration(ctx, storage)
 				assert.Nil(t, err)
 				assert.Equal(t, &configuration{
-					TaskTimeout:      expectedTaskTimeout,
-					TaskHistoryLimit: expectedTaskHistoryLimit,
+					TaskTimeout:            expectedTaskTimeout,
+					TaskHistoryLimit:       expectedTaskHistoryLimit,
+					ResumeInterruptedTasks: true,
+					WorkerPoolSize:         4,
+					TaskMaxAttempts:        3,
+					TaskRetryBackoff:       time.Minute,
+					TaskRetryTimeout:       10 * time.Minute,
 				}, c)
 			})
+
+			t.Run("invalid worker pool size", func(t *testing.T) {
+				ctx, b, _, storage := pathTestSetup(t)
+
+				req := &logical.Request{
+					Operation: op,
+					Path:      "task/configure",
+					Data:      map[string]interface{}{fieldNameWorkerPoolSize: 0},
+					Storage:   storage,
+				}
+
+				resp, err := b.HandleRequest(ctx, req)
+				assert.Nil(t, err)
+				assert.Equal(t, logical.ErrorResponse("Field %q must be positive", fieldNameWorkerPoolSize), resp)
+			})
+
+			t.Run("invalid task max attempts", func(t *testing.T) {
+				ctx, b, _, storage := pathTestSetup(t)
+
+				req := &logical.Request{
+					Operation: op,
+					Path:      "task/configure",
+					Data:      map[string]interface{}{fieldNameTaskMaxAttempts: 0},
+					Storage:   storage,
+				}
+
+				resp, err := b.HandleRequest(ctx, req)
+				assert.Nil(t, err)
+				assert.Equal(t, logical.ErrorResponse("Field %q must be positive", fieldNameTaskMaxAttempts), resp)
+			})
 		})
 	}
 }
@@ -100,6 +144,11 @@ func TestManager_pathConfigureRead(t *testing.T) {
 		expectedResponseData := map[string]interface{}{
 			fieldNameTaskTimeout:      expectedTimeout / time.Second,
 			fieldNameTaskHistoryLimit: expectedHistoryLimit,
+			fieldNameResumeTasks:      false,
+			fieldNameWorkerPoolSize:   fieldDefaultWorkerPoolSize,
+			fieldNameTaskMaxAttempts:  fieldDefaultTaskMaxAttempts,
+			fieldNameTaskRetryBackoff: time.Duration(0),
+			fieldNameTaskRetryTimeout: defaultTaskRetryTimeoutDuration / time.Second,
 		}
 
 		err := putConfiguration(ctx, storage, expectedConfig)
@@ -122,37 +171,97 @@ func TestManager_pathConfigureRead(t *testing.T) {
 func TestManager_pathTaskList(t *testing.T) {
 	ctx, b, _, storage := pathTestSetup(t)
 
-	t.Run("empty", func(t *testing.T) {
+	listTasks := func(t *testing.T, data map[string]interface{}) *logical.Response {
 		req := &logical.Request{
 			Operation: logical.ReadOperation,
 			Path:      "task",
-			Data:      make(map[string]interface{}),
+			Data:      data,
 			Storage:   storage,
 		}
 
 		resp, err := b.HandleRequest(ctx, req)
 		assert.Nil(t, err)
-		if assert.NotNil(t, resp) {
-			assert.Equal(t, map[string]interface{}{}, resp.Data)
-		}
+		assert.NotNil(t, resp)
+
+		return resp
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		resp := listTasks(t, make(map[string]interface{}))
+		assert.Equal(t, map[string]interface{}{}, resp.Data)
 	})
 
+	queuedTaskUUID := assertAndAddNewTaskToStorage(t, ctx, storage)
+	runningTaskUUID := assertAndAddRunningTaskToStorage(t, ctx, storage)
+	failedTaskUUID := assertAndAddCompletedTaskToStorage(t, ctx, storage, taskStatusFailed, switchTaskToCompletedInStorageOptions{})
+	releaseTaskUUID, err := addNewTaskToStorage(ctx, storage, TaskOptions{Operation: "release", Initiator: "token-ci"})
+	assert.Nil(t, err)
+
 	t.Run("all", func(t *testing.T) {
-		queuedTaskUUID := assertAndAddNewTaskToStorage(t, ctx, storage)
-		runningTaskUUID := assertAndAddRunningTaskToStorage(t, ctx, storage)
+		resp := listTasks(t, make(map[string]interface{}))
+		assert.Equal(t, []string{releaseTaskUUID, failedTaskUUID, runningTaskUUID, queuedTaskUUID}, resp.Data["keys"])
+		assert.NotContains(t, resp.Data, fieldNameContinuationToken)
 
-		req := &logical.Request{
-			Operation: logical.ReadOperation,
-			Path:      "task",
-			Data:      make(map[string]interface{}),
-			Storage:   storage,
+		releaseTask, err := getTaskFromStorage(ctx, storage, taskStateQueued, releaseTaskUUID)
+		assert.Nil(t, err)
+		if assert.NotNil(t, releaseTask) {
+			assert.Equal(t, map[string]interface{}{
+				"status":    string(taskStatusQueued),
+				"operation": "release",
+				"params":    map[string]string(nil),
+				"initiator": "token-ci",
+				"created":   releaseTask.Created,
+				"modified":  releaseTask.Modified,
+			}, resp.Data["key_info"].(map[string]interface{})[releaseTaskUUID])
 		}
+	})
 
-		resp, err := b.HandleRequest(ctx, req)
+	t.Run("status", func(t *testing.T) {
+		resp := listTasks(t, map[string]interface{}{fieldNameStatus: string(taskStatusFailed)})
+		assert.Equal(t, []string{failedTaskUUID}, resp.Data["keys"])
+
+		resp = listTasks(t, map[string]interface{}{fieldNameStatus: "UNKNOWN"})
+		assert.True(t, resp.IsError())
+	})
+
+	t.Run("operation", func(t *testing.T) {
+		resp := listTasks(t, map[string]interface{}{fieldNameOperation: "release"})
+		assert.Equal(t, []string{releaseTaskUUID}, resp.Data["keys"])
+	})
+
+	t.Run("created", func(t *testing.T) {
+		runningTask, err := getTaskFromStorage(ctx, storage, taskStateRunning, runningTaskUUID)
 		assert.Nil(t, err)
-		if assert.NotNil(t, resp) {
-			assert.Equal(t, map[string]interface{}{"keys": []string{runningTaskUUID, queuedTaskUUID}}, resp.Data)
+
+		resp := listTasks(t, map[string]interface{}{fieldNameCreatedAfter: runningTask.Created.Format(time.RFC3339Nano)})
+		assert.Equal(t, []string{releaseTaskUUID, failedTaskUUID}, resp.Data["keys"])
+
+		resp = listTasks(t, map[string]interface{}{fieldNameCreatedBefore: runningTask.Created.Format(time.RFC3339Nano)})
+		assert.Equal(t, []string{queuedTaskUUID}, resp.Data["keys"])
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		var keys []string
+		var token string
+		for page := 0; ; page++ {
+			resp := listTasks(t, map[string]interface{}{fieldNameLimit: 3, fieldNameContinuationToken: token})
+			if !assert.False(t, resp.IsError()) || !assert.Less(t, page, 2) {
+				return
+			}
+
+			keys = append(keys, resp.Data["keys"].([]string)...)
+
+			nextToken, ok := resp.Data[fieldNameContinuationToken].(string)
+			if !ok {
+				break
+			}
+			token = nextToken
 		}
+
+		assert.Equal(t, []string{releaseTaskUUID, failedTaskUUID, runningTaskUUID, queuedTaskUUID}, keys)
+
+		resp := listTasks(t, map[string]interface{}{fieldNameContinuationToken: "invalid"})
+		assert.True(t, resp.IsError())
 	})
 }
 
@@ -205,11 +314,22 @@ func TestManager_pathTaskStatus(t *testing.T) {
 			assert.Nil(t, err)
 			if assert.NotNil(t, resp) {
 				expectedResponseData := map[string]interface{}{
-					"uuid":     testTask.UUID,
-					"status":   testTask.Status,
-					"reason":   testTask.Reason,
-					"created":  testTask.Created,
-					"modified": testTask.Modified,
+					"uuid":      testTask.UUID,
+					"status":    testTask.Status,
+					"reason":    testTask.Reason,
+					"operation": testTask.Operation,
+					"params":    testTask.Params,
+					"created":   testTask.Created,
+					"modified":  testTask.Modified,
+
+					"initiator":           testTask.Initiator,
+					"initiator_entity_id": testTask.InitiatorEntityID,
+					"result":              testTask.Result,
+					"checkpoint":          testTask.Checkpoint,
+					"checkpoint_data":     testTask.CheckpointData,
+					"resources":           testTask.Resources,
+					"attempts":            testTask.Attempts,
+					"attempt_errors":      testTask.AttemptErrors,
 				}
 
 				assert.Equal(t, expectedResponseData, resp.Data)
@@ -240,7 +360,7 @@ func TestManager_pathTaskCancel(t *testing.T) {
 		startedCh := make(chan bool)
 		taskFunc := testTaskAction(startedCh)
 
-		uuid, err := m.AddTask(ctx, storage, taskFunc)
+		uuid, err := m.AddTask(ctx, storage, taskFunc, TaskOptions{})
 		assert.Nil(t, err)
 		assert.NotEmpty(t, uuid)
 
@@ -314,7 +434,7 @@ func TestManager_pathTaskLog(t *testing.T) {
 	t.Run(string(taskStateRunning), func(t *testing.T) {
 		msgCh := make(chan string)
 		msgSentCh := make(chan bool)
-		uuid, err := m.RunTask(ctx, storage, taskActionWithLogCh(msgCh, msgSentCh))
+		uuid, err := m.RunTask(ctx, storage, taskActionWithLogCh(msgCh, msgSentCh), TaskOptions{})
 		assert.Nil(t, err)
 
 		var expectedLog string
@@ -439,7 +559,7 @@ func getIntPointer(val int) *int {
 }
 
 func assertAndAddNewTaskToStorage(t *testing.T, ctx context.Context, storage logical.Storage) string {
-	taskUUID, err := addNewTaskToStorage(ctx, storage)
+	taskUUID, err := addNewTaskToStorage(ctx, storage, TaskOptions{})
 	assert.Nil(t, err)
 	assert.NotEmpty(t, taskUUID)
 
@@ -516,3 +636,82 @@ func taskActionWithLogCh(msgCh chan string, msgSentCh chan bool) func(context.Co
 		}
 	}
 }
+
+func TestManager_pathWebhook(t *testing.T) {
+	ctx, b, _, storage := pathTestSetup(t)
+
+	t.Run("invalid url", func(t *testing.T) {
+		req := &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "task/webhook/ci",
+			Data: map[string]interface{}{
+				fieldNameWebhookURL:    "ftp://example.com",
+				fieldNameWebhookSecret: "secret",
+			},
+			Storage: storage,
+		}
+
+		resp, err := b.HandleRequest(ctx, req)
+		assert.Nil(t, err)
+		assert.Equal(t, logical.ErrorResponse("%s validation failed: expected http(s) URL", fieldNameWebhookURL), resp)
+	})
+
+	t.Run("invalid event", func(t *testing.T) {
+		req := &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "task/webhook/ci",
+			Data: map[string]interface{}{
+				fieldNameWebhookURL:    "https://example.com/hook",
+				fieldNameWebhookSecret: "secret",
+				fieldNameWebhookEvents: "queued",
+			},
+			Storage: storage,
+		}
+
+		resp, err := b.HandleRequest(ctx, req)
+		assert.Nil(t, err)
+		assert.True(t, resp.IsError())
+	})
+
+	t.Run("create, read, list and delete", func(t *testing.T) {
+		req := &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "task/webhook/ci",
+			Data: map[string]interface{}{
+				fieldNameWebhookURL:    "https://example.com/hook",
+				fieldNameWebhookSecret: "secret",
+				fieldNameWebhookEvents: "failed,succeeded",
+			},
+			Storage: storage,
+		}
+
+		resp, err := b.HandleRequest(ctx, req)
+		assert.Nil(t, err)
+		assert.Nil(t, resp)
+
+		req = &logical.Request{Operation: logical.ReadOperation, Path: "task/webhook/ci", Storage: storage}
+		resp, err = b.HandleRequest(ctx, req)
+		assert.Nil(t, err)
+		if assert.NotNil(t, resp) {
+			assert.Equal(t, map[string]interface{}{
+				fieldNameWebhookName:   "ci",
+				fieldNameWebhookURL:    "https://example.com/hook",
+				fieldNameWebhookEvents: []string{"failed", "succeeded"},
+			}, resp.Data)
+		}
+
+		req = &logical.Request{Operation: logical.ListOperation, Path: "task/webhook/", Storage: storage}
+		resp, err = b.HandleRequest(ctx, req)
+		assert.Nil(t, err)
+		assert.Equal(t, logical.ListResponse([]string{"ci"}), resp)
+
+		req = &logical.Request{Operation: logical.DeleteOperation, Path: "task/webhook/ci", Storage: storage}
+		_, err = b.HandleRequest(ctx, req)
+		assert.Nil(t, err)
+
+		req = &logical.Request{Operation: logical.ReadOperation, Path: "task/webhook/ci", Storage: storage}
+		resp, err = b.HandleRequest(ctx, req)
+		assert.Nil(t, err)
+		assert.Equal(t, logical.ErrorResponse("Webhook %q not found", "ci"), resp)
+	})
+}
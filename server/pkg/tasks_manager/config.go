@@ -5,6 +5,47 @@ import "time"
 const storageKeyConfiguration = "tasks_manager_configuration"
 
 type configuration struct {
-	TaskTimeout      time.Duration `structs:"task_timeout" json:"task_timeout"`
-	TaskHistoryLimit int           `structs:"task_history_limit" json:"task_history_limit"`
+	TaskTimeout            time.Duration `structs:"task_timeout" json:"task_timeout"`
+	TaskHistoryLimit       int           `structs:"task_history_limit" json:"task_history_limit"`
+	ResumeInterruptedTasks bool          `structs:"resume_interrupted_tasks" json:"resume_interrupted_tasks"`
+	WorkerPoolSize         int           `structs:"worker_pool_size" json:"worker_pool_size"`
+	TaskMaxAttempts        int           `structs:"task_max_attempts" json:"task_max_attempts"`
+	TaskRetryBackoff       time.Duration `structs:"task_retry_backoff" json:"task_retry_backoff"`
+	TaskRetryTimeout       time.Duration `structs:"task_retry_timeout" json:"task_retry_timeout"`
+}
+
+// taskTimeout returns the default timeout if the configuration is not set.
+func (c *configuration) taskTimeout() time.Duration {
+	if c == nil {
+		return defaultTaskTimeoutDuration
+	}
+
+	return c.TaskTimeout
+}
+
+// workerPoolSize returns the default pool size if the configuration is not set or has been stored before the option was added.
+func (c *configuration) workerPoolSize() int {
+	if c == nil || c.WorkerPoolSize < 1 {
+		return fieldDefaultWorkerPoolSize
+	}
+
+	return c.WorkerPoolSize
+}
+
+// taskMaxAttempts returns the single attempt if the configuration is not set or has been stored before the option was added.
+func (c *configuration) taskMaxAttempts() int {
+	if c == nil || c.TaskMaxAttempts < 1 {
+		return fieldDefaultTaskMaxAttempts
+	}
+
+	return c.TaskMaxAttempts
+}
+
+// taskRetryTimeout returns the default retry timeout if the configuration is not set or has been stored before the option was added.
+func (c *configuration) taskRetryTimeout() time.Duration {
+	if c == nil || c.TaskRetryTimeout <= 0 {
+		return defaultTaskRetryTimeoutDuration
+	}
+
+	return c.TaskRetryTimeout
 }
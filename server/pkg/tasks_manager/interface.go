@@ -8,11 +8,11 @@ import (
 
 type ActionsInterface interface {
 	// RunTask runs task or returns busy error
-	RunTask(ctx context.Context, reqStorage logical.Storage, taskFunc func(ctx context.Context, storage logical.Storage) error) (string, error)
+	RunTask(ctx context.Context, reqStorage logical.Storage, taskFunc func(ctx context.Context, storage logical.Storage) error, opts TaskOptions) (string, error)
 
 	// AddTask adds task to queue
-	AddTask(ctx context.Context, reqStorage logical.Storage, taskFunc func(ctx context.Context, storage logical.Storage) error) (string, error)
+	AddTask(ctx context.Context, reqStorage logical.Storage, taskFunc func(ctx context.Context, storage logical.Storage) error, opts TaskOptions) (string, error)
 
 	// AddOptionalTask adds task to queue if empty
-	AddOptionalTask(ctx context.Context, reqStorage logical.Storage, taskFunc func(ctx context.Context, storage logical.Storage) error) (string, bool, error)
+	AddOptionalTask(ctx context.Context, reqStorage logical.Storage, taskFunc func(ctx context.Context, storage logical.Storage) error, opts TaskOptions) (string, bool, error)
 }
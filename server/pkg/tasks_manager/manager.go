@@ -2,7 +2,10 @@ package tasks_manager
 
 import (
 	"context"
+	"fmt"
+	"net/http"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/sdk/logical"
@@ -10,7 +13,12 @@ import (
 	"github.com/werf/trdl/server/pkg/tasks_manager/worker"
 )
 
-const taskChanSize = 128
+const (
+	taskChanSize = 128
+
+	webhookTimeout        = 10 * time.Second
+	defaultWebhookBackoff = time.Second
+)
 
 type Manager struct {
 	Storage logical.Storage
@@ -19,16 +27,37 @@ type Manager struct {
 	logger   hclog.Logger
 	taskChan chan *worker.Task
 	mu       sync.Mutex
+
+	webhookClient  *http.Client
+	webhookBackoff time.Duration
+	webhookCtx     context.Context
+	webhookCancel  context.CancelFunc
+	webhookWG      sync.WaitGroup
+
+	resumeFuncs map[string]taskResumer
 }
 
 func NewManager(logger hclog.Logger) *Manager {
-	m := &Manager{taskChan: make(chan *worker.Task, taskChanSize), logger: logger}
+	m := &Manager{
+		taskChan:       make(chan *worker.Task, taskChanSize),
+		logger:         logger,
+		webhookClient:  &http.Client{Timeout: webhookTimeout},
+		webhookBackoff: defaultWebhookBackoff,
+		resumeFuncs:    make(map[string]taskResumer),
+	}
+	m.webhookCtx, m.webhookCancel = context.WithCancel(context.Background())
 	m.Worker = worker.NewWorker(context.Background(), m.taskChan, m)
 	go m.Worker.Start()
 
 	return m
 }
 
+// Clean cancels the webhook deliveries in progress and waits for them to finish on the backend shutdown.
+func (m *Manager) Clean(_ context.Context) {
+	m.webhookCancel()
+	m.webhookWG.Wait()
+}
+
 func (m *Manager) TaskStartedCallback(ctx context.Context, uuid string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -36,6 +65,8 @@ func (m *Manager) TaskStartedCallback(ctx context.Context, uuid string) {
 	if err := switchTaskToRunningInStorage(ctx, m.Storage, uuid); err != nil {
 		panic("runtime error: " + err.Error())
 	}
+
+	m.notifyWebhooksOrLog(ctx, uuid, TaskEventStarted, nil)
 }
 
 func (m *Manager) TaskSucceededCallback(ctx context.Context, uuid string, log []byte) {
@@ -47,6 +78,8 @@ func (m *Manager) TaskSucceededCallback(ctx context.Context, uuid string, log []
 	}); err != nil {
 		panic("runtime error: " + err.Error())
 	}
+
+	m.notifyWebhooksOrLog(ctx, uuid, TaskEventSucceeded, log)
 }
 
 func (m *Manager) TaskFailedCallback(ctx context.Context, uuid string, log []byte, taskErr error) {
@@ -59,4 +92,13 @@ func (m *Manager) TaskFailedCallback(ctx context.Context, uuid string, log []byt
 	}); err != nil {
 		panic("runtime error: " + err.Error())
 	}
+
+	m.notifyWebhooksOrLog(ctx, uuid, TaskEventFailed, log)
+}
+
+// notifyWebhooksOrLog does not fail the task callback, the task state is already stored.
+func (m *Manager) notifyWebhooksOrLog(ctx context.Context, uuid string, event TaskEvent, log []byte) {
+	if err := m.notifyWebhooks(ctx, uuid, event, log); err != nil {
+		m.logger.Error(fmt.Sprintf("Unable to notify webhooks about task %q event %q: %s", uuid, event, err))
+	}
 }
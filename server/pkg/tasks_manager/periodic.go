@@ -92,6 +92,10 @@ func (m *Manager) cleanupTaskHistory(ctx context.Context, req *logical.Request)
 		if err := req.Storage.Delete(ctx, taskLogStorageKey(task.UUID)); err != nil {
 			return err
 		}
+
+		if err := deleteWebhookDeliveries(ctx, req.Storage, task.UUID); err != nil {
+			return err
+		}
 	}
 
 	return nil
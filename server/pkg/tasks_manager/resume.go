@@ -0,0 +1,129 @@
+package tasks_manager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// TaskResumeFunc returns the function to resume the task interrupted by the restart of the plugin.
+// The running task is resumed from its checkpoint (see PutTaskCheckpoint), the queued task has no checkpoint.
+// The nil function is returned if the task cannot be resumed safely.
+type TaskResumeFunc func(ctx context.Context, storage logical.Storage, task *Task) (func(context.Context, logical.Storage) error, error)
+
+type RegisterTaskResumeFuncOptions struct {
+	// IsRetryableError enables the retries of the resumed tasks, as TaskOptions.IsRetryableError does for the new ones.
+	IsRetryableError func(err error) bool
+}
+
+type taskResumer struct {
+	resumeFunc       TaskResumeFunc
+	isRetryableError func(err error) bool
+}
+
+// RegisterTaskResumeFunc registers the function to resume the interrupted tasks of the operation.
+// The tasks are resumed only if enabled by the configuration, the tasks of the other operations are canceled.
+func (m *Manager) RegisterTaskResumeFunc(operation string, resumeFunc TaskResumeFunc, opts RegisterTaskResumeFuncOptions) {
+	m.resumeFuncs[operation] = taskResumer{resumeFunc: resumeFunc, isRetryableError: opts.IsRetryableError}
+}
+
+// InitializeFunc cancels or resumes the tasks interrupted by the restart of the plugin right on the backend initialization.
+// The webhook deliveries left pending by the restart are failed.
+func (m *Manager) InitializeFunc(ctx context.Context, req *logical.InitializationRequest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Storage = req.Storage
+
+	config, err := getConfiguration(ctx, req.Storage)
+	if err != nil {
+		return fmt.Errorf("unable to get tasks manager configuration: %w", err)
+	}
+
+	m.Worker.SetPoolSize(config.workerPoolSize())
+
+	if err := failInterruptedWebhookDeliveries(ctx, req.Storage); err != nil {
+		return fmt.Errorf("unable to fail interrupted webhook deliveries: %w", err)
+	}
+
+	if config == nil || !config.ResumeInterruptedTasks {
+		if err := m.invalidateStorage(ctx, req.Storage); err != nil {
+			return fmt.Errorf("unable to invalidate storage: %w", err)
+		}
+
+		return nil
+	}
+
+	if err := m.resumeTasks(ctx, req.Storage, config); err != nil {
+		return fmt.Errorf("unable to resume tasks: %w", err)
+	}
+
+	return nil
+}
+
+func (m *Manager) resumeTasks(ctx context.Context, storage logical.Storage, config *configuration) error {
+	var tasks []*Task
+	for _, state := range []taskState{taskStateRunning, taskStateQueued} {
+		prefix := taskStorageKeyPrefix(state)
+		list, err := storage.List(ctx, prefix)
+		if err != nil {
+			return fmt.Errorf("unable to list %q in storage: %w", prefix, err)
+		}
+
+		for _, uuid := range list {
+			task, err := getTaskFromStorage(ctx, storage, state, uuid)
+			if err != nil {
+				return err
+			}
+
+			if task != nil {
+				tasks = append(tasks, task)
+			}
+		}
+	}
+
+	// The tasks are resumed in the order they were queued.
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].Created.Before(tasks[j].Created)
+	})
+
+	for _, task := range tasks {
+		reason := taskReasonInvalidatedTask
+
+		var taskFunc func(context.Context, logical.Storage) error
+		resumer, ok := m.resumeFuncs[task.Operation]
+		if ok {
+			f, err := resumer.resumeFunc(ctx, storage, task)
+			if err != nil {
+				reason = fmt.Sprintf("%s: unable to resume the task: %s", taskReasonInvalidatedTask, err)
+			}
+
+			taskFunc = f
+		}
+
+		if taskFunc == nil {
+			m.logger.Warn(fmt.Sprintf("Task %q (operation %q, checkpoint %q) cannot be resumed: canceling", task.UUID, task.Operation, task.Checkpoint))
+
+			if err := switchTaskToCompletedInStorage(ctx, storage, taskStatusCanceled, task.UUID, switchTaskToCompletedInStorageOptions{
+				reason: reason,
+			}); err != nil {
+				return fmt.Errorf("unable to invalidate task %q: %w", task.UUID, err)
+			}
+
+			continue
+		}
+
+		if task.Status == string(taskStatusRunning) {
+			if err := switchTaskToQueuedInStorage(ctx, storage, task.UUID); err != nil {
+				return fmt.Errorf("unable to requeue task %q: %w", task.UUID, err)
+			}
+		}
+
+		m.enqueueTask(ctx, task.UUID, task.Resources, m.wrapTaskFunc(taskFunc, config, resumer.isRetryableError))
+		m.logger.Info(fmt.Sprintf("Task %q (operation %q, checkpoint %q) resumed", task.UUID, task.Operation, task.Checkpoint))
+	}
+
+	return nil
+}
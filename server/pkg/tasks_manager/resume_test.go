@@ -0,0 +1,185 @@
+package tasks_manager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/werf/logboek"
+)
+
+func TestManager_InitializeFunc(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("invalidate", func(t *testing.T) {
+		m := initManagerWithoutWorker()
+		storage := &logical.InmemStorage{}
+
+		queuedTaskUUID := assertAndAddNewTaskToStorage(t, ctx, storage)
+		runningTaskUUID := assertAndAddRunningTaskToStorage(t, ctx, storage)
+
+		m.RegisterTaskResumeFunc("", func(context.Context, logical.Storage, *Task) (func(context.Context, logical.Storage) error, error) {
+			t.Fatal("tasks must not be resumed unless enabled")
+			return nil, nil
+		}, RegisterTaskResumeFuncOptions{})
+
+		assert.Nil(t, m.InitializeFunc(ctx, &logical.InitializationRequest{Storage: storage}))
+		assert.Equal(t, storage, m.Storage)
+
+		for _, uuid := range []string{queuedTaskUUID, runningTaskUUID} {
+			assertCanceledTaskInStorage(t, ctx, storage, uuid, taskReasonInvalidatedTask)
+		}
+		assert.Empty(t, m.taskChan)
+	})
+
+	t.Run("resume queued", func(t *testing.T) {
+		m, storage := resumeTestSetup(t, ctx)
+
+		taskUUID, err := addNewTaskToStorage(ctx, storage, TaskOptions{Operation: "release", Params: map[string]string{"git_tag": "v1.0.0"}})
+		assert.Nil(t, err)
+
+		var resumedTask *Task
+		m.RegisterTaskResumeFunc("release", func(_ context.Context, _ logical.Storage, task *Task) (func(context.Context, logical.Storage) error, error) {
+			resumedTask = task
+			return noneTask, nil
+		}, RegisterTaskResumeFuncOptions{})
+
+		assert.Nil(t, m.InitializeFunc(ctx, &logical.InitializationRequest{Storage: storage}))
+
+		if assert.NotNil(t, resumedTask) {
+			assert.Equal(t, map[string]string{"git_tag": "v1.0.0"}, resumedTask.Params)
+			assert.Empty(t, resumedTask.Checkpoint)
+		}
+
+		assertQueuedTaskInStorage(t, ctx, storage, taskUUID)
+		assertResumedTaskInQueue(t, m, taskUUID)
+	})
+
+	t.Run("resume running from checkpoint", func(t *testing.T) {
+		m, storage := resumeTestSetup(t, ctx)
+
+		taskUUID := assertAndAddRunningTaskWithCheckpointToStorage(t, ctx, storage, "release", "committed", map[string]string{"git_commit": "abc"})
+
+		var resumedTask *Task
+		m.RegisterTaskResumeFunc("release", func(_ context.Context, _ logical.Storage, task *Task) (func(context.Context, logical.Storage) error, error) {
+			resumedTask = task
+			return noneTask, nil
+		}, RegisterTaskResumeFuncOptions{})
+
+		assert.Nil(t, m.InitializeFunc(ctx, &logical.InitializationRequest{Storage: storage}))
+
+		if assert.NotNil(t, resumedTask) {
+			assert.Equal(t, "committed", resumedTask.Checkpoint)
+			assert.Equal(t, map[string]string{"git_commit": "abc"}, resumedTask.CheckpointData)
+		}
+
+		assertQueuedTaskInStorage(t, ctx, storage, taskUUID)
+		assertResumedTaskInQueue(t, m, taskUUID)
+
+		// the resumed task goes through the usual lifecycle
+		m.TaskStartedCallback(ctx, taskUUID)
+		runningTask, err := getTaskFromStorage(ctx, storage, taskStateRunning, taskUUID)
+		assert.Nil(t, err)
+		if assert.NotNil(t, runningTask) {
+			assert.Equal(t, "committed", runningTask.Checkpoint)
+		}
+	})
+
+	t.Run("resume retryable", func(t *testing.T) {
+		m, storage := resumeTestSetup(t, ctx)
+		assert.Nil(t, putConfiguration(ctx, storage, &configuration{TaskTimeout: defaultTaskTimeoutDuration, TaskMaxAttempts: 2, TaskRetryBackoff: time.Millisecond, ResumeInterruptedTasks: true}))
+
+		taskUUID := assertAndAddRunningTaskWithCheckpointToStorage(t, ctx, storage, "release", "committed", nil)
+
+		var calls int
+		m.RegisterTaskResumeFunc("release", func(context.Context, logical.Storage, *Task) (func(context.Context, logical.Storage) error, error) {
+			return func(context.Context, logical.Storage) error {
+				calls++
+				if calls == 1 {
+					return errTransient
+				}
+
+				return nil
+			}, nil
+		}, RegisterTaskResumeFuncOptions{IsRetryableError: isTransientError})
+
+		assert.Nil(t, m.InitializeFunc(ctx, &logical.InitializationRequest{Storage: storage}))
+
+		if assert.Len(t, m.taskChan, 1) {
+			task := <-m.taskChan
+			m.TaskStartedCallback(ctx, taskUUID)
+			assert.Nil(t, task.Action(logboek.NewContext(task.Context, logboek.DefaultLogger())))
+		}
+		assert.Equal(t, 2, calls)
+
+		runningTask, err := getTaskFromStorage(ctx, storage, taskStateRunning, taskUUID)
+		assert.Nil(t, err)
+		if assert.NotNil(t, runningTask) {
+			assert.Equal(t, 2, runningTask.Attempts)
+			assert.Equal(t, []string{"transient"}, runningTask.AttemptErrors)
+		}
+	})
+
+	t.Run("not resumable", func(t *testing.T) {
+		m, storage := resumeTestSetup(t, ctx)
+
+		unsafeTaskUUID := assertAndAddRunningTaskWithCheckpointToStorage(t, ctx, storage, "release", "committing", nil)
+		failedTaskUUID := assertAndAddRunningTaskWithCheckpointToStorage(t, ctx, storage, "publish", "", nil)
+		unregisteredTaskUUID := assertAndAddRunningTaskWithCheckpointToStorage(t, ctx, storage, "rotate_keys", "", nil)
+
+		m.RegisterTaskResumeFunc("release", func(context.Context, logical.Storage, *Task) (func(context.Context, logical.Storage) error, error) {
+			return nil, nil
+		}, RegisterTaskResumeFuncOptions{})
+		m.RegisterTaskResumeFunc("publish", func(context.Context, logical.Storage, *Task) (func(context.Context, logical.Storage) error, error) {
+			return nil, errors.New("configuration not found")
+		}, RegisterTaskResumeFuncOptions{})
+
+		assert.Nil(t, m.InitializeFunc(ctx, &logical.InitializationRequest{Storage: storage}))
+
+		assertCanceledTaskInStorage(t, ctx, storage, unsafeTaskUUID, taskReasonInvalidatedTask)
+		assertCanceledTaskInStorage(t, ctx, storage, failedTaskUUID, taskReasonInvalidatedTask+": unable to resume the task: configuration not found")
+		assertCanceledTaskInStorage(t, ctx, storage, unregisteredTaskUUID, taskReasonInvalidatedTask)
+		assert.Empty(t, m.taskChan)
+	})
+}
+
+func resumeTestSetup(t *testing.T, ctx context.Context) (*Manager, logical.Storage) {
+	m := initManagerWithoutWorker()
+	storage := &logical.InmemStorage{}
+	assert.Nil(t, putConfiguration(ctx, storage, &configuration{TaskTimeout: defaultTaskTimeoutDuration, ResumeInterruptedTasks: true}))
+
+	return m, storage
+}
+
+func assertAndAddRunningTaskWithCheckpointToStorage(t *testing.T, ctx context.Context, storage logical.Storage, operation, checkpoint string, data map[string]string) string {
+	taskUUID, err := addNewTaskToStorage(ctx, storage, TaskOptions{Operation: operation})
+	assert.Nil(t, err)
+	assert.Nil(t, switchTaskToRunningInStorage(ctx, storage, taskUUID))
+
+	taskCtx := context.WithValue(ctx, taskUUIDContextKey{}, taskUUID)
+	assert.Nil(t, PutTaskCheckpoint(taskCtx, storage, checkpoint, data))
+
+	return taskUUID
+}
+
+func assertResumedTaskInQueue(t *testing.T, m *Manager, taskUUID string) {
+	if assert.Len(t, m.taskChan, 1) {
+		task := <-m.taskChan
+		assert.Equal(t, taskUUID, task.UUID)
+		assert.Equal(t, taskUUID, TaskUUIDFromContext(task.Context))
+		assert.Nil(t, task.Action(task.Context))
+	}
+}
+
+func assertCanceledTaskInStorage(t *testing.T, ctx context.Context, storage logical.Storage, taskUUID, reason string) {
+	task, err := getTaskFromStorage(ctx, storage, taskStateCompleted, taskUUID)
+	assert.Nil(t, err)
+	if assert.NotNil(t, task) {
+		assert.Equal(t, string(taskStatusCanceled), task.Status)
+		assert.Equal(t, reason, task.Reason)
+	}
+}
@@ -0,0 +1,67 @@
+package tasks_manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/werf/logboek"
+)
+
+// wrapTaskFunc applies the task timeout to each attempt of the task and retries the task with the retryable error.
+func (m *Manager) wrapTaskFunc(taskFunc func(context.Context, logical.Storage) error, config *configuration, isRetryableError func(error) bool) func(ctx context.Context) error {
+	workerTaskFunc := m.WrapTaskFunc(taskFunc, config.taskTimeout())
+	if isRetryableError != nil && config.taskMaxAttempts() > 1 {
+		workerTaskFunc = m.retryTaskFunc(workerTaskFunc, isRetryableError, config.taskMaxAttempts(), config.TaskRetryBackoff, config.taskRetryTimeout())
+	}
+
+	return workerTaskFunc
+}
+
+// retryTaskFunc runs the task again if it fails with the retryable error, until the attempts are exhausted.
+// The delay between the attempts is doubled each time, the canceled task is not retried.
+// No attempt is started after the retry timeout since the first attempt.
+func (m *Manager) retryTaskFunc(workerTaskFunc func(context.Context) error, isRetryableError func(error) bool, maxAttempts int, backoff, retryTimeout time.Duration) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		startedAt := time.Now()
+
+		for attempt := 1; ; attempt++ {
+			err := workerTaskFunc(ctx)
+			if err == nil || attempt >= maxAttempts || errors.Is(err, ErrContextCanceled) || !isRetryableError(err) {
+				return err
+			}
+
+			if time.Since(startedAt)+backoff > retryTimeout {
+				logboek.Context(ctx).Warn().LogF("Attempt %d of %d failed: %s\nNot retrying: the retry timeout %s is exceeded\n", attempt, maxAttempts, err, retryTimeout)
+				m.logger.Warn(fmt.Sprintf("Task %q attempt %d of %d failed: %s: not retrying: the retry timeout %s is exceeded", TaskUUIDFromContext(ctx), attempt, maxAttempts, err, retryTimeout))
+
+				return err
+			}
+
+			logboek.Context(ctx).Warn().LogF("Attempt %d of %d failed: %s\nRetrying in %s\n", attempt, maxAttempts, err, backoff)
+			m.logger.Warn(fmt.Sprintf("Task %q attempt %d of %d failed: %s: retrying in %s", TaskUUIDFromContext(ctx), attempt, maxAttempts, err, backoff))
+
+			if err := putTaskAttempt(ctx, m.Storage, attempt+1, err); err != nil {
+				return fmt.Errorf("unable to record task attempt: %w", err)
+			}
+
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return ErrContextCanceled
+			}
+
+			backoff *= 2
+		}
+	}
+}
+
+// putTaskAttempt records the failed attempt of the running task and the number of the next one.
+func putTaskAttempt(ctx context.Context, storage logical.Storage, attempt int, attemptErr error) error {
+	return updateRunningTaskInStorage(ctx, storage, func(task *Task) {
+		task.Attempts = attempt
+		task.AttemptErrors = append(task.AttemptErrors, attemptErr.Error())
+	})
+}
@@ -0,0 +1,87 @@
+package tasks_manager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/werf/logboek"
+)
+
+var errTransient = errors.New("transient")
+
+func isTransientError(err error) bool { return errors.Is(err, errTransient) }
+
+// check that the task is retried on the retryable error and the attempts are recorded
+func TestManager_retryTaskFunc(t *testing.T) {
+	ctx := context.Background()
+	m := initManagerWithoutWorker()
+	storage := &logical.InmemStorage{}
+	m.Storage = storage
+
+	runTaskWithRetryTimeout := func(t *testing.T, retryTimeout time.Duration, errs ...error) (*Task, int, error) {
+		taskUUID := assertAndAddRunningTaskToStorage(t, ctx, storage)
+		taskCtx := context.WithValue(ctx, taskUUIDContextKey{}, taskUUID)
+		taskCtx = logboek.NewContext(taskCtx, logboek.DefaultLogger())
+
+		var calls int
+		err := m.retryTaskFunc(func(context.Context) error {
+			calls++
+			return errs[calls-1]
+		}, isTransientError, 3, time.Millisecond, retryTimeout)(taskCtx)
+
+		task, getErr := getTaskFromStorage(ctx, storage, taskStateRunning, taskUUID)
+		assert.Nil(t, getErr)
+
+		return task, calls, err
+	}
+
+	runTask := func(t *testing.T, errs ...error) (*Task, int, error) {
+		return runTaskWithRetryTimeout(t, time.Hour, errs...)
+	}
+
+	t.Run("succeeded after retries", func(t *testing.T) {
+		task, calls, err := runTask(t, errTransient, errTransient, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, calls)
+		if assert.NotNil(t, task) {
+			assert.Equal(t, 3, task.Attempts)
+			assert.Equal(t, []string{"transient", "transient"}, task.AttemptErrors)
+		}
+	})
+
+	t.Run("attempts exhausted", func(t *testing.T) {
+		_, calls, err := runTask(t, errTransient, errTransient, errTransient)
+		assert.Equal(t, errTransient, err)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("retry timeout exceeded", func(t *testing.T) {
+		task, calls, err := runTaskWithRetryTimeout(t, time.Microsecond, errTransient, nil)
+		assert.Equal(t, errTransient, err)
+		assert.Equal(t, 1, calls)
+		if assert.NotNil(t, task) {
+			assert.Empty(t, task.AttemptErrors)
+		}
+	})
+
+	t.Run("not retryable error", func(t *testing.T) {
+		permanentErr := errors.New("permanent")
+		task, calls, err := runTask(t, errTransient, permanentErr)
+		assert.Equal(t, permanentErr, err)
+		assert.Equal(t, 2, calls)
+		if assert.NotNil(t, task) {
+			assert.Equal(t, 2, task.Attempts)
+		}
+	})
+
+	t.Run("canceled task", func(t *testing.T) {
+		_, calls, err := runTask(t, ErrContextCanceled)
+		assert.Equal(t, ErrContextCanceled, err)
+		assert.Equal(t, 1, calls)
+	})
+}
@@ -35,17 +35,46 @@ const (
 var taskStateStatusesCompleted = []taskStatus{taskStatusSucceeded, taskStatusFailed, taskStatusCanceled}
 
 type Task struct {
-	UUID     string    `structs:"uuid" json:"uuid"`
-	Status   string    `structs:"status" json:"status"`
-	Reason   string    `structs:"reason" json:"reason"`
-	Created  time.Time `structs:"created" json:"created"`
-	Modified time.Time `structs:"modified" json:"modified"`
+	UUID              string                 `structs:"uuid" json:"uuid"`
+	Status            string                 `structs:"status" json:"status"`
+	Reason            string                 `structs:"reason" json:"reason"`
+	Operation         string                 `structs:"operation" json:"operation"`
+	Params            map[string]string      `structs:"params" json:"params"`
+	Initiator         string                 `structs:"initiator" json:"initiator"`
+	InitiatorEntityID string                 `structs:"initiator_entity_id" json:"initiator_entity_id"`
+	Result            map[string]interface{} `structs:"result" json:"result"`
+	Checkpoint        string                 `structs:"checkpoint" json:"checkpoint"`
+	CheckpointData    map[string]string      `structs:"checkpoint_data" json:"checkpoint_data"`
+	Resources         []string               `structs:"resources" json:"resources"`
+	Attempts          int                    `structs:"attempts" json:"attempts"`
+	AttemptErrors     []string               `structs:"attempt_errors" json:"attempt_errors"`
+	Created           time.Time              `structs:"created" json:"created"`
+	Modified          time.Time              `structs:"modified" json:"modified"`
 }
 
-func newTask() *Task {
+// TaskOptions describes the task for the task records, the webhook notifications, the scheduling and the retries.
+type TaskOptions struct {
+	Operation         string
+	Params            map[string]string
+	Initiator         string
+	InitiatorEntityID string
+	// Resources are locked for the whole task, so the tasks with the common resources run one by one.
+	// The task without resources runs exclusively.
+	Resources []string
+	// IsRetryableError enables the retries of the task failed with the transient error, e.g. a network error.
+	// The task must be safe to run again, the number of attempts and the backoff are configured.
+	IsRetryableError func(err error) bool
+}
+
+func newTask(opts TaskOptions) *Task {
 	task := &Task{}
 	task.UUID = uuid.NewV4().String()
 	task.Status = string(taskStatusQueued)
+	task.Operation = opts.Operation
+	task.Params = opts.Params
+	task.Initiator = opts.Initiator
+	task.InitiatorEntityID = opts.InitiatorEntityID
+	task.Resources = opts.Resources
 
 	tNow := time.Now()
 	task.Created = tNow
@@ -54,8 +83,8 @@ func newTask() *Task {
 	return task
 }
 
-func addNewTaskToStorage(ctx context.Context, storage logical.Storage) (string, error) {
-	queuedTask := newTask()
+func addNewTaskToStorage(ctx context.Context, storage logical.Storage, opts TaskOptions) (string, error) {
+	queuedTask := newTask(opts)
 	storageKey := taskStorageKey(taskStateQueued, queuedTask.UUID)
 	entry, err := logical.StorageEntryJSON(storageKey, queuedTask)
 	if err != nil {
@@ -89,6 +118,7 @@ func switchTaskToRunningInStorage(ctx context.Context, storage logical.Storage,
 	{
 		runningTask := prevTask
 		runningTask.Status = string(taskStatusRunning)
+		runningTask.Attempts = 1
 		runningTask.Modified = time.Now()
 		runningTaskState := taskStateRunning
 
@@ -112,6 +142,85 @@ func switchTaskToRunningInStorage(ctx context.Context, storage logical.Storage,
 	return nil
 }
 
+// PutTaskResult stores the result payload of the running task the context is passed to.
+func PutTaskResult(ctx context.Context, storage logical.Storage, result map[string]interface{}) error {
+	return updateRunningTaskInStorage(ctx, storage, func(task *Task) {
+		task.Result = result
+	})
+}
+
+// PutTaskCheckpoint stores the checkpoint the running task the context is passed to has reached.
+// The checkpoint and its data are passed to the TaskResumeFunc if the task is interrupted by the restart of the plugin.
+func PutTaskCheckpoint(ctx context.Context, storage logical.Storage, checkpoint string, data map[string]string) error {
+	return updateRunningTaskInStorage(ctx, storage, func(task *Task) {
+		task.Checkpoint = checkpoint
+		task.CheckpointData = data
+	})
+}
+
+func updateRunningTaskInStorage(ctx context.Context, storage logical.Storage, update func(task *Task)) error {
+	uuid := TaskUUIDFromContext(ctx)
+	if uuid == "" {
+		panic("runtime error: the running task can be updated only within the task")
+	}
+
+	task, err := getTaskFromStorage(ctx, storage, taskStateRunning, uuid)
+	if err != nil {
+		return err
+	}
+
+	if task == nil {
+		return fmt.Errorf("running task %q not found in storage", uuid)
+	}
+
+	update(task)
+	task.Modified = time.Now()
+
+	storageKey := taskStorageKey(taskStateRunning, uuid)
+	entry, err := logical.StorageEntryJSON(storageKey, task)
+	if err != nil {
+		return fmt.Errorf("unable to prepare storage entry JSON: %w", err)
+	}
+
+	if err := storage.Put(ctx, entry); err != nil {
+		return fmt.Errorf("unable to put %q into storage: %w", storageKey, err)
+	}
+
+	return nil
+}
+
+// switchTaskToQueuedInStorage returns the interrupted running task to the queue.
+func switchTaskToQueuedInStorage(ctx context.Context, storage logical.Storage, uuid string) error {
+	task, err := getTaskFromStorage(ctx, storage, taskStateRunning, uuid)
+	if err != nil {
+		return err
+	}
+
+	if task == nil {
+		return fmt.Errorf("running task %q must be in storage", uuid)
+	}
+
+	task.Status = string(taskStatusQueued)
+	task.Modified = time.Now()
+
+	storageKey := taskStorageKey(taskStateQueued, uuid)
+	entry, err := logical.StorageEntryJSON(storageKey, task)
+	if err != nil {
+		return fmt.Errorf("unable to prepare storage entry JSON: %w", err)
+	}
+
+	if err := storage.Put(ctx, entry); err != nil {
+		return fmt.Errorf("unable to put %q into storage: %w", storageKey, err)
+	}
+
+	prevStorageKey := taskStorageKey(taskStateRunning, uuid)
+	if err := storage.Delete(ctx, prevStorageKey); err != nil {
+		return fmt.Errorf("unable to delete %q from storage: %w", prevStorageKey, err)
+	}
+
+	return nil
+}
+
 type switchTaskToCompletedInStorageOptions struct {
 	reason string
 	log    []byte
@@ -0,0 +1,383 @@
+package tasks_manager
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/samber/lo"
+)
+
+type (
+	TaskEvent             string
+	webhookDeliveryStatus string
+)
+
+const (
+	TaskEventStarted   TaskEvent = "started"
+	TaskEventSucceeded TaskEvent = "succeeded"
+	TaskEventFailed    TaskEvent = "failed"
+
+	webhookDeliveryStatusPending   webhookDeliveryStatus = "PENDING"
+	webhookDeliveryStatusDelivered webhookDeliveryStatus = "DELIVERED"
+	webhookDeliveryStatusFailed    webhookDeliveryStatus = "FAILED"
+
+	storageKeyPrefixWebhook         = "tasks_manager_webhook/"
+	storageKeyPrefixWebhookDelivery = "task_webhook_delivery/"
+
+	webhookSignatureHeader = "X-Trdl-Signature"
+	webhookEventHeader     = "X-Trdl-Event"
+
+	webhookMaxAttempts = 5
+	webhookLogTailSize = 4096
+
+	webhookReasonCanceled    = "the delivery is canceled by the backend shutdown"
+	webhookReasonInterrupted = "the delivery is interrupted by the restart of the plugin"
+)
+
+var taskEvents = []TaskEvent{TaskEventStarted, TaskEventSucceeded, TaskEventFailed}
+
+type webhook struct {
+	Name   string      `json:"name"`
+	URL    string      `json:"url"`
+	Secret string      `json:"secret"`
+	Events []TaskEvent `json:"events"`
+}
+
+type webhookPayload struct {
+	Event             TaskEvent              `json:"event"`
+	TaskUUID          string                 `json:"task_uuid"`
+	Status            string                 `json:"status"`
+	Reason            string                 `json:"reason"`
+	Operation         string                 `json:"operation"`
+	Params            map[string]string      `json:"params"`
+	Initiator         string                 `json:"initiator"`
+	InitiatorEntityID string                 `json:"initiator_entity_id"`
+	Result            map[string]interface{} `json:"result"`
+	LogTail           string                 `json:"log_tail"`
+	Created           time.Time              `json:"created"`
+	Modified          time.Time              `json:"modified"`
+}
+
+type webhookDelivery struct {
+	Webhook   string                `json:"webhook"`
+	Event     TaskEvent             `json:"event"`
+	Status    webhookDeliveryStatus `json:"status"`
+	Attempts  int                   `json:"attempts"`
+	LastError string                `json:"last_error"`
+	Modified  time.Time             `json:"modified"`
+}
+
+// notifyWebhooks sends the task event to the subscribed webhooks in the background.
+// The delivery is retried with the exponential backoff, the delivery state is stored per task.
+// The deliveries in progress are canceled on the backend shutdown (see Manager.Clean).
+func (m *Manager) notifyWebhooks(ctx context.Context, taskUUID string, event TaskEvent, log []byte) error {
+	webhooks, err := listWebhooks(ctx, m.Storage)
+	if err != nil {
+		return err
+	}
+
+	webhooks = lo.Filter(webhooks, func(w *webhook, _ int) bool { return lo.Contains(w.Events, event) })
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	var task *Task
+	for _, state := range []taskState{taskStateRunning, taskStateCompleted} {
+		if task, err = getTaskFromStorage(ctx, m.Storage, state, taskUUID); err != nil {
+			return err
+		}
+
+		if task != nil {
+			break
+		}
+	}
+
+	if task == nil {
+		return fmt.Errorf("task %q not found in storage", taskUUID)
+	}
+
+	if len(log) > webhookLogTailSize {
+		log = log[len(log)-webhookLogTailSize:]
+	}
+
+	body, err := json.Marshal(webhookPayload{
+		Event:             event,
+		TaskUUID:          task.UUID,
+		Status:            task.Status,
+		Reason:            task.Reason,
+		Operation:         task.Operation,
+		Params:            task.Params,
+		Initiator:         task.Initiator,
+		InitiatorEntityID: task.InitiatorEntityID,
+		Result:            task.Result,
+		LogTail:           string(log),
+		Created:           task.Created,
+		Modified:          task.Modified,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to marshal webhook payload: %w", err)
+	}
+
+	for _, w := range webhooks {
+		delivery := &webhookDelivery{Webhook: w.Name, Event: event, Status: webhookDeliveryStatusPending, Modified: time.Now()}
+		if err := putWebhookDelivery(ctx, m.Storage, taskUUID, delivery); err != nil {
+			return err
+		}
+
+		m.webhookWG.Add(1)
+		go func(w *webhook, delivery *webhookDelivery) {
+			defer m.webhookWG.Done()
+			m.deliverWebhook(m.webhookCtx, m.Storage, taskUUID, w, body, delivery)
+		}(w, delivery)
+	}
+
+	return nil
+}
+
+func (m *Manager) deliverWebhook(ctx context.Context, storage logical.Storage, taskUUID string, w *webhook, body []byte, delivery *webhookDelivery) {
+	backoff := m.webhookBackoff
+	for {
+		delivery.Attempts++
+
+		retryable, err := m.postWebhook(ctx, w, delivery.Event, body)
+		switch {
+		case err == nil:
+			delivery.Status = webhookDeliveryStatusDelivered
+			delivery.LastError = ""
+		case ctx.Err() != nil:
+			delivery.Status = webhookDeliveryStatusFailed
+			delivery.LastError = webhookReasonCanceled
+		case !retryable || delivery.Attempts >= webhookMaxAttempts:
+			delivery.Status = webhookDeliveryStatusFailed
+			delivery.LastError = err.Error()
+		default:
+			delivery.LastError = err.Error()
+		}
+		delivery.Modified = time.Now()
+
+		if err := putWebhookDelivery(context.WithoutCancel(ctx), storage, taskUUID, delivery); err != nil {
+			m.logger.Error(fmt.Sprintf("Unable to store webhook %q delivery state of task %q: %s", w.Name, taskUUID, err))
+		}
+
+		if delivery.Status != webhookDeliveryStatusPending {
+			if delivery.Status == webhookDeliveryStatusFailed {
+				m.logger.Warn(fmt.Sprintf("Webhook %q delivery of task %q event %q failed after %d attempts: %s", w.Name, taskUUID, delivery.Event, delivery.Attempts, delivery.LastError))
+			}
+
+			return
+		}
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			delivery.Status = webhookDeliveryStatusFailed
+			delivery.LastError = webhookReasonCanceled
+			delivery.Modified = time.Now()
+
+			if err := putWebhookDelivery(context.WithoutCancel(ctx), storage, taskUUID, delivery); err != nil {
+				m.logger.Error(fmt.Sprintf("Unable to store webhook %q delivery state of task %q: %s", w.Name, taskUUID, err))
+			}
+
+			return
+		}
+	}
+}
+
+// failInterruptedWebhookDeliveries fails the deliveries left pending by the restart of the plugin.
+func failInterruptedWebhookDeliveries(ctx context.Context, storage logical.Storage) error {
+	list, err := storage.List(ctx, storageKeyPrefixWebhookDelivery)
+	if err != nil {
+		return fmt.Errorf("unable to list %q in storage: %w", storageKeyPrefixWebhookDelivery, err)
+	}
+
+	for _, taskPrefix := range list {
+		taskUUID := strings.TrimSuffix(taskPrefix, "/")
+
+		deliveries, err := getWebhookDeliveries(ctx, storage, taskUUID)
+		if err != nil {
+			return err
+		}
+
+		for _, delivery := range deliveries {
+			if delivery.Status != webhookDeliveryStatusPending {
+				continue
+			}
+
+			delivery.Status = webhookDeliveryStatusFailed
+			delivery.LastError = webhookReasonInterrupted
+			delivery.Modified = time.Now()
+
+			if err := putWebhookDelivery(ctx, storage, taskUUID, delivery); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// postWebhook sends the payload signed with the webhook secret.
+// The network errors, 429 and 5xx responses are retryable.
+func (m *Manager) postWebhook(ctx context.Context, w *webhook, event TaskEvent, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("prepare request: %w", err)
+	}
+
+	mac := hmac.New(sha256.New, []byte(w.Secret))
+	mac.Write(body)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookEventHeader, string(event))
+	req.Header.Set(webhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := m.webhookClient.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retryable, fmt.Errorf("unexpected response status %q", resp.Status)
+}
+
+func webhookStorageKey(name string) string {
+	return storageKeyPrefixWebhook + name
+}
+
+func getWebhook(ctx context.Context, storage logical.Storage, name string) (*webhook, error) {
+	storageKey := webhookStorageKey(name)
+	entry, err := storage.Get(ctx, storageKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get %q from storage: %w", storageKey, err)
+	}
+
+	if entry == nil {
+		return nil, nil
+	}
+
+	w := new(webhook)
+	if err := entry.DecodeJSON(w); err != nil {
+		return nil, fmt.Errorf("unable to decode %q: %w", storageKey, err)
+	}
+
+	return w, nil
+}
+
+func listWebhooks(ctx context.Context, storage logical.Storage) ([]*webhook, error) {
+	names, err := storage.List(ctx, storageKeyPrefixWebhook)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list %q in storage: %w", storageKeyPrefixWebhook, err)
+	}
+
+	var webhooks []*webhook
+	for _, name := range names {
+		w, err := getWebhook(ctx, storage, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if w != nil {
+			webhooks = append(webhooks, w)
+		}
+	}
+
+	return webhooks, nil
+}
+
+func putWebhook(ctx context.Context, storage logical.Storage, w *webhook) error {
+	storageKey := webhookStorageKey(w.Name)
+	entry, err := logical.StorageEntryJSON(storageKey, w)
+	if err != nil {
+		return fmt.Errorf("unable to prepare storage entry JSON: %w", err)
+	}
+
+	if err := storage.Put(ctx, entry); err != nil {
+		return fmt.Errorf("unable to put %q into storage: %w", storageKey, err)
+	}
+
+	return nil
+}
+
+func webhookDeliveryStorageKeyPrefix(taskUUID string) string {
+	return storageKeyPrefixWebhookDelivery + taskUUID + "/"
+}
+
+func putWebhookDelivery(ctx context.Context, storage logical.Storage, taskUUID string, delivery *webhookDelivery) error {
+	storageKey := path.Join(webhookDeliveryStorageKeyPrefix(taskUUID), string(delivery.Event)+"-"+delivery.Webhook)
+	entry, err := logical.StorageEntryJSON(storageKey, delivery)
+	if err != nil {
+		return fmt.Errorf("unable to prepare storage entry JSON: %w", err)
+	}
+
+	if err := storage.Put(ctx, entry); err != nil {
+		return fmt.Errorf("unable to put %q into storage: %w", storageKey, err)
+	}
+
+	return nil
+}
+
+func getWebhookDeliveries(ctx context.Context, storage logical.Storage, taskUUID string) ([]*webhookDelivery, error) {
+	prefix := webhookDeliveryStorageKeyPrefix(taskUUID)
+	keys, err := storage.List(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list %q in storage: %w", prefix, err)
+	}
+
+	sort.Strings(keys)
+
+	var deliveries []*webhookDelivery
+	for _, key := range keys {
+		entry, err := storage.Get(ctx, prefix+key)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get %q from storage: %w", prefix+key, err)
+		}
+
+		if entry == nil {
+			continue
+		}
+
+		delivery := new(webhookDelivery)
+		if err := entry.DecodeJSON(delivery); err != nil {
+			return nil, fmt.Errorf("unable to decode %q: %w", prefix+key, err)
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, nil
+}
+
+func deleteWebhookDeliveries(ctx context.Context, storage logical.Storage, taskUUID string) error {
+	prefix := webhookDeliveryStorageKeyPrefix(taskUUID)
+	keys, err := storage.List(ctx, prefix)
+	if err != nil {
+		return fmt.Errorf("unable to list %q in storage: %w", prefix, err)
+	}
+
+	for _, key := range keys {
+		if err := storage.Delete(ctx, prefix+key); err != nil {
+			return fmt.Errorf("unable to delete %q from storage: %w", prefix+key, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,187 @@
+package tasks_manager
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManager_notifyWebhooks(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("signed payload", func(t *testing.T) {
+		m, storage := webhookTestSetup()
+
+		payloadCh := make(chan webhookPayload, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			assert.Nil(t, err)
+
+			mac := hmac.New(sha256.New, []byte("secret"))
+			mac.Write(body)
+			assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), r.Header.Get(webhookSignatureHeader))
+			assert.Equal(t, string(TaskEventSucceeded), r.Header.Get(webhookEventHeader))
+
+			var payload webhookPayload
+			assert.Nil(t, json.Unmarshal(body, &payload))
+			payloadCh <- payload
+		}))
+		defer server.Close()
+
+		assert.Nil(t, putWebhook(ctx, storage, &webhook{Name: "ci", URL: server.URL, Secret: "secret", Events: taskEvents}))
+
+		taskUUID := assertAndAddCompletedTaskWithOptionsToStorage(t, ctx, storage, TaskOptions{Operation: "release", Params: map[string]string{"git_tag": "v0.0.1"}})
+		assert.Nil(t, m.notifyWebhooks(ctx, taskUUID, TaskEventSucceeded, []byte("done")))
+
+		payload := <-payloadCh
+		assert.Equal(t, TaskEventSucceeded, payload.Event)
+		assert.Equal(t, taskUUID, payload.TaskUUID)
+		assert.Equal(t, string(taskStatusSucceeded), payload.Status)
+		assert.Equal(t, "release", payload.Operation)
+		assert.Equal(t, map[string]string{"git_tag": "v0.0.1"}, payload.Params)
+		assert.Equal(t, "done", payload.LogTail)
+
+		assertWebhookDelivery(t, ctx, storage, taskUUID, webhookDeliveryStatusDelivered, 1)
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		m, storage := webhookTestSetup()
+
+		var requests int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			if atomic.AddInt32(&requests, 1) < 3 {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}))
+		defer server.Close()
+
+		assert.Nil(t, putWebhook(ctx, storage, &webhook{Name: "ci", URL: server.URL, Secret: "secret", Events: taskEvents}))
+
+		taskUUID := assertAndAddCompletedTaskWithOptionsToStorage(t, ctx, storage, TaskOptions{})
+		assert.Nil(t, m.notifyWebhooks(ctx, taskUUID, TaskEventSucceeded, nil))
+
+		assertWebhookDelivery(t, ctx, storage, taskUUID, webhookDeliveryStatusDelivered, 3)
+	})
+
+	t.Run("non-retryable", func(t *testing.T) {
+		m, storage := webhookTestSetup()
+
+		var requests int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		assert.Nil(t, putWebhook(ctx, storage, &webhook{Name: "ci", URL: server.URL, Secret: "secret", Events: taskEvents}))
+
+		taskUUID := assertAndAddCompletedTaskWithOptionsToStorage(t, ctx, storage, TaskOptions{})
+		assert.Nil(t, m.notifyWebhooks(ctx, taskUUID, TaskEventSucceeded, nil))
+
+		assertWebhookDelivery(t, ctx, storage, taskUUID, webhookDeliveryStatusFailed, 1)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	})
+
+	t.Run("canceled on clean", func(t *testing.T) {
+		m, storage := webhookTestSetup()
+		m.webhookBackoff = time.Hour
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		assert.Nil(t, putWebhook(ctx, storage, &webhook{Name: "ci", URL: server.URL, Secret: "secret", Events: taskEvents}))
+
+		taskUUID := assertAndAddCompletedTaskWithOptionsToStorage(t, ctx, storage, TaskOptions{})
+		assert.Nil(t, m.notifyWebhooks(ctx, taskUUID, TaskEventSucceeded, nil))
+
+		assert.Eventually(t, func() bool {
+			deliveries, err := getWebhookDeliveries(ctx, storage, taskUUID)
+			return err == nil && len(deliveries) == 1 && deliveries[0].Attempts == 1
+		}, 5*time.Second, 10*time.Millisecond)
+
+		m.Clean(ctx)
+
+		deliveries, err := getWebhookDeliveries(ctx, storage, taskUUID)
+		assert.Nil(t, err)
+		if assert.Len(t, deliveries, 1) {
+			assert.Equal(t, webhookDeliveryStatusFailed, deliveries[0].Status)
+			assert.Equal(t, webhookReasonCanceled, deliveries[0].LastError)
+		}
+	})
+
+	t.Run("not subscribed", func(t *testing.T) {
+		m, storage := webhookTestSetup()
+
+		assert.Nil(t, putWebhook(ctx, storage, &webhook{Name: "ci", URL: "http://127.0.0.1:0", Secret: "secret", Events: []TaskEvent{TaskEventFailed}}))
+
+		taskUUID := assertAndAddCompletedTaskWithOptionsToStorage(t, ctx, storage, TaskOptions{})
+		assert.Nil(t, m.notifyWebhooks(ctx, taskUUID, TaskEventSucceeded, nil))
+
+		deliveries, err := getWebhookDeliveries(ctx, storage, taskUUID)
+		assert.Nil(t, err)
+		assert.Empty(t, deliveries)
+	})
+}
+
+func Test_failInterruptedWebhookDeliveries(t *testing.T) {
+	ctx := context.Background()
+	storage := &logical.InmemStorage{}
+
+	assert.Nil(t, putWebhookDelivery(ctx, storage, "UUID", &webhookDelivery{Webhook: "ci", Event: TaskEventStarted, Status: webhookDeliveryStatusDelivered, Attempts: 1}))
+	assert.Nil(t, putWebhookDelivery(ctx, storage, "UUID", &webhookDelivery{Webhook: "ci", Event: TaskEventSucceeded, Status: webhookDeliveryStatusPending, Attempts: 2}))
+
+	assert.Nil(t, failInterruptedWebhookDeliveries(ctx, storage))
+
+	deliveries, err := getWebhookDeliveries(ctx, storage, "UUID")
+	assert.Nil(t, err)
+	if assert.Len(t, deliveries, 2) {
+		assert.Equal(t, webhookDeliveryStatusDelivered, deliveries[0].Status)
+		assert.Equal(t, webhookDeliveryStatusFailed, deliveries[1].Status)
+		assert.Equal(t, webhookReasonInterrupted, deliveries[1].LastError)
+		assert.Equal(t, 2, deliveries[1].Attempts)
+	}
+}
+
+func webhookTestSetup() (*Manager, logical.Storage) {
+	m := initManagerWithoutWorker()
+	m.Storage = &logical.InmemStorage{}
+
+	return m, m.Storage
+}
+
+func assertAndAddCompletedTaskWithOptionsToStorage(t *testing.T, ctx context.Context, storage logical.Storage, opts TaskOptions) string {
+	taskUUID, err := addNewTaskToStorage(ctx, storage, opts)
+	assert.Nil(t, err)
+	assert.Nil(t, switchTaskToRunningInStorage(ctx, storage, taskUUID))
+	assert.Nil(t, switchTaskToCompletedInStorage(ctx, storage, taskStatusSucceeded, taskUUID, switchTaskToCompletedInStorageOptions{}))
+
+	return taskUUID
+}
+
+func assertWebhookDelivery(t *testing.T, ctx context.Context, storage logical.Storage, taskUUID string, status webhookDeliveryStatus, attempts int) {
+	assert.Eventually(t, func() bool {
+		deliveries, err := getWebhookDeliveries(ctx, storage, taskUUID)
+		return err == nil && len(deliveries) == 1 && deliveries[0].Status == status
+	}, 5*time.Second, 10*time.Millisecond)
+
+	deliveries, err := getWebhookDeliveries(ctx, storage, taskUUID)
+	assert.Nil(t, err)
+	if assert.Len(t, deliveries, 1) {
+		assert.Equal(t, "ci", deliveries[0].Webhook)
+		assert.Equal(t, TaskEventSucceeded, deliveries[0].Event)
+		assert.Equal(t, attempts, deliveries[0].Attempts)
+	}
+}
@@ -6,6 +6,8 @@ type Interface interface {
 	Start()
 	CancelRunningJobByTaskUUID(uuid string) bool
 	HoldRunningJobByTaskUUID(uuid string, do func(job *Job)) bool
+	SetPoolSize(size int)
+	LockResource(ctx context.Context, uuid, resource string) (func(), error)
 }
 
 type TaskCallbacksInterface interface {
@@ -8,6 +8,7 @@ import (
 
 type Job struct {
 	taskUUID      string
+	resources     []string
 	action        func() error
 	ctx           context.Context
 	ctxCancelFunc context.CancelFunc
@@ -17,7 +18,9 @@ type Job struct {
 type Task struct {
 	Context context.Context
 	UUID    string
-	Action  func(ctx context.Context) error
+	// Resources are locked while the task is running, the task without resources runs exclusively.
+	Resources []string
+	Action    func(ctx context.Context) error
 }
 
 func newJob(task *Task) *Job {
@@ -29,6 +32,7 @@ func newJob(task *Task) *Job {
 		ctx:           jobContext,
 		ctxCancelFunc: jobCtxCancelFunc,
 		taskUUID:      task.UUID,
+		resources:     task.Resources,
 		action:        func() error { return task.Action(jobContext) },
 		buff:          buff,
 	}
@@ -2,41 +2,107 @@ package worker
 
 import (
 	"context"
+	"fmt"
 	"sync"
+
+	"github.com/samber/lo"
 )
 
-type Worker struct {
-	ctx        context.Context
-	currentJob *Job
-	taskChan   chan *Task
-	callbacks  TaskCallbacksInterface
+const defaultPoolSize = 1
 
-	mu sync.Mutex
+type Worker struct {
+	ctx       context.Context
+	taskChan  chan *Task
+	callbacks TaskCallbacksInterface
+
+	mu       sync.Mutex
+	poolSize int
+	queue    []*Task
+	jobs     map[string]*Job
+	// resources are the locked resources by the holder task UUID.
+	resources map[string]string
+	// releasedCh is closed when a resource is released.
+	releasedCh chan struct{}
+	scheduleCh chan struct{}
+	jobsWg     sync.WaitGroup
 }
 
 func NewWorker(ctx context.Context, taskChan chan *Task, callbacks TaskCallbacksInterface) Interface {
-	return &Worker{ctx: ctx, taskChan: taskChan, callbacks: callbacks}
+	return &Worker{
+		ctx:        ctx,
+		taskChan:   taskChan,
+		callbacks:  callbacks,
+		poolSize:   defaultPoolSize,
+		jobs:       make(map[string]*Job),
+		resources:  make(map[string]string),
+		releasedCh: make(chan struct{}),
+		scheduleCh: make(chan struct{}, 1),
+	}
 }
 
+// Start runs the queued tasks in the pool until the worker context is done and then waits for the running jobs.
+// The task is started as soon as there is a free slot in the pool and its resources are not locked,
+// the task is not overtaken by the later tasks with the conflicting resources.
 func (w *Worker) Start() {
 	for {
 		select {
 		case task := <-w.taskChan:
-			func() {
-				job := newJob(task)
-				w.setCurrentJob(job)
-				defer w.resetCurrentJob()
-
-				w.callbacks.TaskStartedCallback(w.ctx, job.taskUUID)
-				if err := job.action(); err != nil {
-					w.callbacks.TaskFailedCallback(w.ctx, job.taskUUID, job.Log(), err)
-				} else {
-					w.callbacks.TaskSucceededCallback(w.ctx, job.taskUUID, job.Log())
-				}
-			}()
+			w.mu.Lock()
+			w.queue = append(w.queue, task)
+			w.mu.Unlock()
+		case <-w.scheduleCh:
 		case <-w.ctx.Done():
+			w.jobsWg.Wait()
 			return
 		}
+
+		w.schedule()
+	}
+}
+
+func (w *Worker) SetPoolSize(size int) {
+	if size < 1 {
+		panic(fmt.Sprintf("unexpected worker pool size %d", size))
+	}
+
+	w.mu.Lock()
+	w.poolSize = size
+	w.mu.Unlock()
+
+	w.wakeUp()
+}
+
+// LockResource waits until the resource is released by the other jobs and locks it for the running job.
+// The resource is unlocked by the returned function or on the job completion.
+func (w *Worker) LockResource(ctx context.Context, uuid, resource string) (func(), error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		w.mu.Lock()
+		holder, locked := w.resources[resource]
+		if !locked {
+			w.resources[resource] = uuid
+			w.mu.Unlock()
+
+			return func() { w.unlockResource(uuid, resource) }, nil
+		}
+
+		if holder == uuid {
+			w.mu.Unlock()
+
+			return func() {}, nil
+		}
+
+		releasedCh := w.releasedCh
+		w.mu.Unlock()
+
+		select {
+		case <-releasedCh:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
 	}
 }
 
@@ -44,11 +110,12 @@ func (w *Worker) HoldRunningJobByTaskUUID(uuid string, do func(job *Job)) bool {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
-	if w.currentJob == nil || w.currentJob.taskUUID != uuid {
+	job, ok := w.jobs[uuid]
+	if !ok {
 		return false
 	}
 
-	do(w.currentJob)
+	do(job)
 
 	return true
 }
@@ -57,24 +124,110 @@ func (w *Worker) CancelRunningJobByTaskUUID(uuid string) bool {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
-	if w.currentJob != nil && w.currentJob.taskUUID == uuid {
-		w.currentJob.ctxCancelFunc()
-		return true
+	job, ok := w.jobs[uuid]
+	if !ok {
+		return false
 	}
 
-	return false
+	job.ctxCancelFunc()
+
+	return true
 }
 
-func (w *Worker) setCurrentJob(job *Job) {
+func (w *Worker) schedule() {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
-	w.currentJob = job
+	var queue, skipped []*Task
+	for _, task := range w.queue {
+		if len(w.jobs) < w.poolSize && w.canStart(task) && !lo.SomeBy(skipped, func(t *Task) bool { return ResourcesConflict(t.Resources, task.Resources) }) {
+			w.startJob(task)
+			continue
+		}
+
+		queue = append(queue, task)
+		skipped = append(skipped, task)
+	}
+
+	w.queue = queue
+}
+
+func (w *Worker) canStart(task *Task) bool {
+	for _, job := range w.jobs {
+		if len(job.resources) == 0 || len(task.Resources) == 0 {
+			return false
+		}
+	}
+
+	return !lo.SomeBy(task.Resources, func(resource string) bool {
+		_, locked := w.resources[resource]
+		return locked
+	})
+}
+
+func (w *Worker) startJob(task *Task) {
+	job := newJob(task)
+	w.jobs[job.taskUUID] = job
+	for _, resource := range job.resources {
+		w.resources[resource] = job.taskUUID
+	}
+
+	w.jobsWg.Add(1)
+	go w.runJob(job)
+}
+
+func (w *Worker) runJob(job *Job) {
+	defer w.jobsWg.Done()
+	defer w.completeJob(job)
+
+	w.callbacks.TaskStartedCallback(w.ctx, job.taskUUID)
+	if err := job.action(); err != nil {
+		w.callbacks.TaskFailedCallback(w.ctx, job.taskUUID, job.Log(), err)
+	} else {
+		w.callbacks.TaskSucceededCallback(w.ctx, job.taskUUID, job.Log())
+	}
 }
 
-func (w *Worker) resetCurrentJob() {
+func (w *Worker) completeJob(job *Job) {
 	w.mu.Lock()
-	defer w.mu.Unlock()
+	delete(w.jobs, job.taskUUID)
+	for resource, holder := range w.resources {
+		if holder == job.taskUUID {
+			delete(w.resources, resource)
+		}
+	}
+	w.notifyReleased()
+	w.mu.Unlock()
+
+	w.wakeUp()
+}
+
+func (w *Worker) unlockResource(uuid, resource string) {
+	w.mu.Lock()
+	if w.resources[resource] == uuid {
+		delete(w.resources, resource)
+		w.notifyReleased()
+	}
+	w.mu.Unlock()
+
+	w.wakeUp()
+}
+
+// notifyReleased wakes up the jobs waiting for the resources, must be called with the lock held.
+func (w *Worker) notifyReleased() {
+	close(w.releasedCh)
+	w.releasedCh = make(chan struct{})
+}
+
+func (w *Worker) wakeUp() {
+	select {
+	case w.scheduleCh <- struct{}{}:
+	default:
+	}
+}
 
-	w.currentJob = nil
+// ResourcesConflict returns true if the tasks with the resources cannot run concurrently.
+// The task without resources conflicts with any task.
+func ResourcesConflict(a, b []string) bool {
+	return len(a) == 0 || len(b) == 0 || lo.Some(a, b)
 }
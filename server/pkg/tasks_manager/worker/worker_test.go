@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -222,6 +223,118 @@ func TestWorker_HoldRunningJobByTaskUUID(t *testing.T) {
 	mockedTasksCallbacks.AssertExpectations(t)
 }
 
+func TestWorker_ConcurrentJobs(t *testing.T) {
+	ctx := context.Background()
+	workerCtx, workerCtxCancelFunc := context.WithCancel(ctx)
+	workerFinishedChan := make(chan bool)
+	taskChan := make(chan *Task, 3)
+
+	mockedTasksCallbacks := &MockedTasksCallbacks{}
+	mockedTasksCallbacks.On(TaskStartedCallback, mock.Anything).Return()
+	mockedTasksCallbacks.On(TaskSucceededCallback, mock.Anything, mock.Anything).Return()
+
+	w := NewWorker(workerCtx, taskChan, mockedTasksCallbacks)
+	w.SetPoolSize(2)
+
+	build1Channels, build1 := testTask("build-1", "build")
+	build2Channels, build2 := testTask("build-2", "build")
+	channelsChannels, channels := testTask("channels", "channels")
+	taskChan <- build1
+	taskChan <- build2
+	taskChan <- channels
+
+	go func() {
+		w.Start()
+		workerFinishedChan <- true
+	}()
+
+	// the task with the other resources overtakes the task waiting for the locked resource
+	<-build1Channels.startedCh
+	<-channelsChannels.startedCh
+
+	select {
+	case <-build2Channels.startedCh:
+		t.Fatal("the task must wait for the locked resource")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	build1Channels.doneCh <- true
+	<-build1Channels.completedCh
+	<-build2Channels.startedCh
+
+	build2Channels.doneCh <- true
+	<-build2Channels.completedCh
+	channelsChannels.doneCh <- true
+	<-channelsChannels.completedCh
+
+	workerCtxCancelFunc()
+	<-workerFinishedChan
+
+	mockedTasksCallbacks.AssertNumberOfCalls(t, TaskSucceededCallback, 3)
+}
+
+func TestWorker_LockResource(t *testing.T) {
+	ctx := context.Background()
+	workerCtx, workerCtxCancelFunc := context.WithCancel(ctx)
+	workerFinishedChan := make(chan bool)
+	taskChan := make(chan *Task, 2)
+
+	mockedTasksCallbacks := &MockedTasksCallbacks{}
+	mockedTasksCallbacks.On(TaskStartedCallback, mock.Anything).Return()
+	mockedTasksCallbacks.On(TaskSucceededCallback, mock.Anything, mock.Anything).Return()
+
+	w := NewWorker(workerCtx, taskChan, mockedTasksCallbacks)
+	w.SetPoolSize(2)
+
+	lockedCh := make(chan string)
+	unlockCh := make(chan bool)
+	lockingTask := func(uuid string) *Task {
+		return &Task{
+			Context:   context.Background(),
+			UUID:      uuid,
+			Resources: []string{uuid},
+			Action: func(ctx context.Context) error {
+				unlock, err := w.LockResource(ctx, uuid, "commit")
+				if err != nil {
+					return err
+				}
+				defer unlock()
+
+				lockedCh <- uuid
+				<-unlockCh
+
+				return nil
+			},
+		}
+	}
+
+	taskChan <- lockingTask("1")
+	taskChan <- lockingTask("2")
+
+	go func() {
+		w.Start()
+		workerFinishedChan <- true
+	}()
+
+	first := <-lockedCh
+
+	select {
+	case <-lockedCh:
+		t.Fatal("the resource must be locked by the first task")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlockCh <- true
+	second := <-lockedCh
+	assert.NotEqual(t, first, second)
+	unlockCh <- true
+
+	workerCtxCancelFunc()
+	<-workerFinishedChan
+
+	mockedTasksCallbacks.AssertNumberOfCalls(t, TaskSucceededCallback, 2)
+}
+
 type testTaskChannels struct {
 	startedCh   chan bool
 	msgCh       chan string
@@ -230,7 +343,7 @@ type testTaskChannels struct {
 	completedCh chan bool
 }
 
-func testTask(uuid string) (testTaskChannels, *Task) {
+func testTask(uuid string, resources ...string) (testTaskChannels, *Task) {
 	channels := testTaskChannels{
 		startedCh:   make(chan bool),
 		msgCh:       make(chan string),
@@ -240,9 +353,10 @@ func testTask(uuid string) (testTaskChannels, *Task) {
 	}
 
 	task := &Task{
-		Context: context.Background(),
-		UUID:    uuid,
-		Action:  testTaskAction(channels),
+		Context:   context.Background(),
+		UUID:      uuid,
+		Resources: resources,
+		Action:    testTaskAction(channels),
 	}
 
 	return channels, task
@@ -0,0 +1,158 @@
+package transit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+
+	"github.com/werf/trdl/server/pkg/util"
+)
+
+const (
+	fieldNameAddress   = "address"
+	fieldNameToken     = "token"
+	fieldNameNamespace = "namespace"
+	fieldNameCACert    = "ca_cert"
+	fieldNameMount     = "mount"
+
+	fieldNameKeyNameSuffix = "_key_name"
+)
+
+func keyNameFieldName(role string) string {
+	return role + fieldNameKeyNameSuffix
+}
+
+func Paths() []*framework.Path {
+	fields := map[string]*framework.FieldSchema{
+		fieldNameAddress: {
+			Type:        framework.TypeString,
+			Description: "The address of the Vault server with the Transit secrets engine",
+			Required:    true,
+		},
+		fieldNameToken: {
+			Type:        framework.TypeString,
+			Description: "The token allowed to read, sign with and rotate the configured Transit keys",
+			Required:    true,
+		},
+		fieldNameNamespace: {
+			Type:        framework.TypeString,
+			Description: "The Vault namespace of the Transit secrets engine",
+			Required:    false,
+		},
+		fieldNameCACert: {
+			Type:        framework.TypeString,
+			Description: "The PEM-encoded CA certificate to verify the Vault server certificate",
+			Required:    false,
+		},
+		fieldNameMount: {
+			Type:        framework.TypeString,
+			Description: "The mount path of the Transit secrets engine",
+			Default:     "transit",
+			Required:    false,
+		},
+	}
+
+	for _, role := range Roles {
+		fields[keyNameFieldName(role)] = &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: fmt.Sprintf("The name of the ed25519 Transit key to sign the TUF %s metadata with. The key stored in the plugin is used if not set", role),
+			Required:    false,
+		}
+	}
+
+	return []*framework.Path{
+		{
+			Pattern:         "configure/tuf_transit/?",
+			HelpSynopsis:    "Configure TUF signing with Vault Transit keys",
+			HelpDescription: "Configure Vault Transit keys to sign the TUF repository metadata, so that the private keys never leave Vault Transit. The current key of the role is replaced with the Transit key on the next publication",
+			Fields:          fields,
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.CreateOperation: &framework.PathOperation{
+					Description: "Configure TUF signing with Vault Transit keys",
+					Callback:    pathConfigureTransitCreateOrUpdate,
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Description: "Configure TUF signing with Vault Transit keys",
+					Callback:    pathConfigureTransitCreateOrUpdate,
+				},
+				logical.ReadOperation: &framework.PathOperation{
+					Description: "Read the Vault Transit configuration",
+					Callback:    pathConfigureTransitRead,
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Description: "Reset the Vault Transit configuration. The Transit keys are replaced with new keys stored in the plugin on the next publication. The root key cannot be replaced without the root Transit key, so the root is considered offline then",
+					Callback:    pathConfigureTransitDelete,
+				},
+			},
+		},
+	}
+}
+
+func pathConfigureTransitCreateOrUpdate(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	if errResp := util.CheckRequiredFields(req, fields); errResp != nil {
+		return errResp, nil
+	}
+
+	cfg := &Configuration{
+		Address:   fields.Get(fieldNameAddress).(string),
+		Token:     fields.Get(fieldNameToken).(string),
+		Namespace: fields.Get(fieldNameNamespace).(string),
+		CACert:    fields.Get(fieldNameCACert).(string),
+		Mount:     fields.Get(fieldNameMount).(string),
+		KeyNames:  make(map[string]string),
+	}
+
+	for _, role := range Roles {
+		if keyName := fields.Get(keyNameFieldName(role)).(string); keyName != "" {
+			cfg.KeyNames[role] = keyName
+		}
+	}
+
+	if len(cfg.KeyNames) == 0 {
+		return logical.ErrorResponse("At least one of the %s, %s, %s or %s fields must be set", keyNameFieldName(Roles[0]), keyNameFieldName(Roles[1]), keyNameFieldName(Roles[2]), keyNameFieldName(Roles[3])), nil
+	}
+
+	if _, err := cfg.Signers(ctx); err != nil {
+		return logical.ErrorResponse("Vault Transit validation failed: %s", err), nil
+	}
+
+	if err := PutConfiguration(ctx, req.Storage, cfg); err != nil {
+		return nil, fmt.Errorf("unable to put transit configuration into storage: %w", err)
+	}
+
+	return nil, nil
+}
+
+func pathConfigureTransitRead(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	cfg, err := GetConfiguration(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get transit configuration: %w", err)
+	}
+
+	if cfg == nil {
+		return logical.ErrorResponse("Vault Transit configuration not found"), nil
+	}
+
+	respData := map[string]interface{}{
+		fieldNameAddress:   cfg.Address,
+		fieldNameNamespace: cfg.Namespace,
+		fieldNameCACert:    cfg.CACert,
+		fieldNameMount:     cfg.Mount,
+	}
+
+	for _, role := range Roles {
+		respData[keyNameFieldName(role)] = cfg.KeyNames[role]
+	}
+
+	return &logical.Response{Data: respData}, nil
+}
+
+func pathConfigureTransitDelete(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	if err := DeleteConfiguration(ctx, req.Storage); err != nil {
+		return nil, fmt.Errorf("unable to delete transit configuration: %w", err)
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,139 @@
+package transit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/werf/trdl/server/pkg/transit/testutil"
+)
+
+type pathConfigureTransitCallbacksSuite struct {
+	suite.Suite
+	ctx     context.Context
+	backend logical.Backend
+	req     *logical.Request
+	storage logical.Storage
+	server  *testutil.Server
+}
+
+func (suite *pathConfigureTransitCallbacksSuite) SetupTest() {
+	ctx := context.Background()
+	b := &framework.Backend{}
+	b.Paths = Paths()
+	storage := &logical.InmemStorage{}
+	config := logical.TestBackendConfig()
+	config.StorageView = storage
+	err := b.Setup(ctx, config)
+	assert.Nil(suite.T(), err)
+
+	suite.ctx = ctx
+	suite.backend = b
+	suite.req = &logical.Request{Storage: storage, Path: "configure/tuf_transit"}
+	suite.storage = storage
+
+	suite.server = testutil.NewServer()
+	suite.server.CreateKey("tuf-targets")
+}
+
+func (suite *pathConfigureTransitCallbacksSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *pathConfigureTransitCallbacksSuite) data() map[string]interface{} {
+	return map[string]interface{}{
+		fieldNameAddress:            suite.server.URL,
+		fieldNameToken:              testutil.Token,
+		keyNameFieldName("targets"): "tuf-targets",
+	}
+}
+
+func (suite *pathConfigureTransitCallbacksSuite) TestCreateOrUpdate() {
+	suite.req.Operation = logical.CreateOperation
+	suite.req.Data = suite.data()
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), resp)
+
+	cfg, err := GetConfiguration(suite.ctx, suite.storage)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), &Configuration{
+		Address:  suite.server.URL,
+		Token:    testutil.Token,
+		Mount:    "transit",
+		KeyNames: map[string]string{"targets": "tuf-targets"},
+	}, cfg)
+
+	suite.req.Operation = logical.ReadOperation
+	suite.req.Data = nil
+
+	resp, err = suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "tuf-targets", resp.Data[keyNameFieldName("targets")])
+	assert.Equal(suite.T(), "", resp.Data[keyNameFieldName("root")])
+	assert.NotContains(suite.T(), resp.Data, fieldNameToken)
+}
+
+func (suite *pathConfigureTransitCallbacksSuite) TestCreateOrUpdate_RequiredFields() {
+	suite.req.Operation = logical.CreateOperation
+
+	for _, fieldName := range []string{fieldNameAddress, fieldNameToken} {
+		suite.Run(fieldName, func() {
+			data := suite.data()
+			delete(data, fieldName)
+			suite.req.Data = data
+
+			resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+			assert.Nil(suite.T(), err)
+			assert.Equal(suite.T(), logical.ErrorResponse("Required field %q must be set", fieldName), resp)
+		})
+	}
+}
+
+func (suite *pathConfigureTransitCallbacksSuite) TestCreateOrUpdate_NoKeyNames() {
+	suite.req.Operation = logical.CreateOperation
+	suite.req.Data = suite.data()
+	delete(suite.req.Data, keyNameFieldName("targets"))
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), resp.IsError())
+}
+
+func (suite *pathConfigureTransitCallbacksSuite) TestCreateOrUpdate_InvalidKey() {
+	suite.req.Operation = logical.CreateOperation
+	suite.req.Data = suite.data()
+	suite.req.Data[keyNameFieldName("root")] = "missing"
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), resp.IsError())
+	assert.Contains(suite.T(), resp.Error().Error(), `transit key "missing" not found`)
+
+	cfg, err := GetConfiguration(suite.ctx, suite.storage)
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), cfg)
+}
+
+func (suite *pathConfigureTransitCallbacksSuite) TestDelete() {
+	assert.Nil(suite.T(), PutConfiguration(suite.ctx, suite.storage, &Configuration{Address: suite.server.URL}))
+
+	suite.req.Operation = logical.DeleteOperation
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), resp)
+
+	cfg, err := GetConfiguration(suite.ctx, suite.storage)
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), cfg)
+}
+
+func TestPathConfigureTransitCallbacks(t *testing.T) {
+	suite.Run(t, new(pathConfigureTransitCallbacksSuite))
+}
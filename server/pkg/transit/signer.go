@@ -0,0 +1,150 @@
+package transit
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/theupdateframework/go-tuf/data"
+	"github.com/theupdateframework/go-tuf/pkg/keys"
+)
+
+const keyTypeEd25519 = "ed25519"
+
+var ErrPrivateKeyNotExportable = errors.New("the private key is kept in Vault Transit and cannot be exported")
+
+// Signer signs the TUF metadata with the ed25519 Vault Transit key.
+// The signer is pinned to the key version, so that a new key version is never used before it is trusted by the TUF root.
+type Signer struct {
+	client     *api.Client
+	mount      string
+	keyName    string
+	keyVersion int
+	publicKey  ed25519.PublicKey
+}
+
+var _ keys.Signer = (*Signer)(nil)
+
+// NewSigner returns the signer for the latest version of the Transit key.
+func NewSigner(ctx context.Context, client *api.Client, mount, keyName string) (*Signer, error) {
+	secret, err := client.Logical().ReadWithContext(ctx, path.Join(mount, "keys", keyName))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read transit key %q: %w", keyName, err)
+	}
+
+	if secret == nil {
+		return nil, fmt.Errorf("transit key %q not found in the %q mount", keyName, mount)
+	}
+
+	if keyType := fmt.Sprint(secret.Data["type"]); keyType != keyTypeEd25519 {
+		return nil, fmt.Errorf("transit key %q has unsupported type %q: expected %q", keyName, keyType, keyTypeEd25519)
+	}
+
+	latestVersion, err := strconv.Atoi(fmt.Sprint(secret.Data["latest_version"]))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse transit key %q latest version: %w", keyName, err)
+	}
+
+	versions, ok := secret.Data["keys"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("transit key %q has no key versions", keyName)
+	}
+
+	version, ok := versions[strconv.Itoa(latestVersion)].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("transit key %q has no version %d", keyName, latestVersion)
+	}
+
+	publicKey, err := base64.StdEncoding.DecodeString(fmt.Sprint(version["public_key"]))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode transit key %q public key: %w", keyName, err)
+	}
+
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("transit key %q has invalid public key size %d", keyName, len(publicKey))
+	}
+
+	return &Signer{
+		client:     client,
+		mount:      mount,
+		keyName:    keyName,
+		keyVersion: latestVersion,
+		publicKey:  publicKey,
+	}, nil
+}
+
+func (s *Signer) KeyName() string {
+	return s.keyName
+}
+
+func (s *Signer) KeyVersion() int {
+	return s.keyVersion
+}
+
+// Rotate creates a new version of the Transit key and returns the signer for it.
+func (s *Signer) Rotate(ctx context.Context) (*Signer, error) {
+	if _, err := s.client.Logical().WriteWithContext(ctx, path.Join(s.mount, "keys", s.keyName, "rotate"), nil); err != nil {
+		return nil, fmt.Errorf("unable to rotate transit key %q: %w", s.keyName, err)
+	}
+
+	return NewSigner(ctx, s.client, s.mount, s.keyName)
+}
+
+func (s *Signer) SignMessage(message []byte) ([]byte, error) {
+	secret, err := s.client.Logical().WriteWithContext(context.Background(), path.Join(s.mount, "sign", s.keyName), map[string]interface{}{
+		"input":       base64.StdEncoding.EncodeToString(message),
+		"key_version": s.keyVersion,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to sign with transit key %q: %w", s.keyName, err)
+	}
+
+	if secret == nil {
+		return nil, fmt.Errorf("unable to sign with transit key %q: empty response", s.keyName)
+	}
+
+	// The signature has the format vault:v<version>:<base64 signature>.
+	parts := strings.SplitN(fmt.Sprint(secret.Data["signature"]), ":", 3)
+	if len(parts) != 3 || parts[0] != "vault" || parts[1] != "v"+strconv.Itoa(s.keyVersion) {
+		return nil, fmt.Errorf("unexpected transit key %q signature format", s.keyName)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode transit key %q signature: %w", s.keyName, err)
+	}
+
+	if !ed25519.Verify(s.publicKey, message, signature) {
+		return nil, fmt.Errorf("transit key %q signature does not match the public key of version %d", s.keyName, s.keyVersion)
+	}
+
+	return signature, nil
+}
+
+func (s *Signer) PublicData() *data.PublicKey {
+	value, _ := json.Marshal(struct {
+		Public data.HexBytes `json:"public"`
+	}{Public: data.HexBytes(s.publicKey)})
+
+	return &data.PublicKey{
+		Type:       data.KeyTypeEd25519,
+		Scheme:     data.KeySchemeEd25519,
+		Algorithms: data.HashAlgorithms,
+		Value:      value,
+	}
+}
+
+func (s *Signer) MarshalPrivateKey() (*data.PrivateKey, error) {
+	return nil, ErrPrivateKeyNotExportable
+}
+
+func (s *Signer) UnmarshalPrivateKey(_ *data.PrivateKey) error {
+	return ErrPrivateKeyNotExportable
+}
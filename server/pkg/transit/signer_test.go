@@ -0,0 +1,111 @@
+package transit
+
+import (
+	"context"
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/theupdateframework/go-tuf/pkg/keys"
+
+	"github.com/werf/trdl/server/pkg/transit/testutil"
+)
+
+func newTestConfiguration(server *testutil.Server, keyNames map[string]string) *Configuration {
+	return &Configuration{
+		Address:  server.URL,
+		Token:    testutil.Token,
+		Mount:    "transit",
+		KeyNames: keyNames,
+	}
+}
+
+func TestSigner_SignMessage(t *testing.T) {
+	server := testutil.NewServer()
+	defer server.Close()
+	server.CreateKey("tuf-targets")
+
+	client, err := newTestConfiguration(server, nil).NewClient()
+	require.NoError(t, err)
+
+	signer, err := NewSigner(context.Background(), client, "transit", "tuf-targets")
+	require.NoError(t, err)
+
+	message := []byte("message")
+	signature, err := signer.SignMessage(message)
+	require.NoError(t, err)
+	assert.True(t, ed25519.Verify(server.PublicKey("tuf-targets", 1), message, signature))
+
+	verifier, err := keys.GetVerifier(signer.PublicData())
+	require.NoError(t, err)
+	assert.NoError(t, verifier.Verify(message, signature))
+
+	_, err = signer.MarshalPrivateKey()
+	assert.ErrorIs(t, err, ErrPrivateKeyNotExportable)
+}
+
+func TestSigner_Rotate(t *testing.T) {
+	server := testutil.NewServer()
+	defer server.Close()
+	server.CreateKey("tuf-root")
+
+	client, err := newTestConfiguration(server, nil).NewClient()
+	require.NoError(t, err)
+
+	signer, err := NewSigner(context.Background(), client, "transit", "tuf-root")
+	require.NoError(t, err)
+
+	rotatedSigner, err := signer.Rotate(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, signer.KeyVersion())
+	assert.Equal(t, 2, rotatedSigner.KeyVersion())
+	assert.NotEqual(t, signer.PublicData().IDs(), rotatedSigner.PublicData().IDs())
+
+	// The previous version is still usable to cross-sign with the old key.
+	message := []byte("message")
+	signature, err := signer.SignMessage(message)
+	require.NoError(t, err)
+	assert.True(t, ed25519.Verify(server.PublicKey("tuf-root", 1), message, signature))
+
+	signature, err = rotatedSigner.SignMessage(message)
+	require.NoError(t, err)
+	assert.True(t, ed25519.Verify(server.PublicKey("tuf-root", 2), message, signature))
+}
+
+func TestGetSigners(t *testing.T) {
+	server := testutil.NewServer()
+	defer server.Close()
+	server.CreateKey("tuf-targets")
+	server.CreateKey("tuf-snapshot")
+
+	ctx := context.Background()
+	storage := &logical.InmemStorage{}
+
+	signers, err := GetSigners(ctx, storage)
+	require.NoError(t, err)
+	assert.Nil(t, signers)
+
+	require.NoError(t, PutConfiguration(ctx, storage, newTestConfiguration(server, map[string]string{
+		"targets":  "tuf-targets",
+		"snapshot": "tuf-snapshot",
+	})))
+
+	signers, err = GetSigners(ctx, storage)
+	require.NoError(t, err)
+	assert.Len(t, signers, 2)
+	assert.Equal(t, "tuf-targets", signers["targets"].(*Signer).KeyName())
+	assert.Equal(t, "tuf-snapshot", signers["snapshot"].(*Signer).KeyName())
+}
+
+func TestNewSigner_KeyNotFound(t *testing.T) {
+	server := testutil.NewServer()
+	defer server.Close()
+
+	client, err := newTestConfiguration(server, nil).NewClient()
+	require.NoError(t, err)
+
+	_, err = NewSigner(context.Background(), client, "transit", "missing")
+	assert.ErrorContains(t, err, `transit key "missing" not found`)
+}
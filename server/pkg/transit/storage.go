@@ -0,0 +1,124 @@
+package transit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/theupdateframework/go-tuf/pkg/keys"
+)
+
+const storageKeyConfiguration = "tuf_transit"
+
+// Roles are the TUF roles, which keys can be kept in Vault Transit.
+var Roles = []string{"root", "targets", "snapshot", "timestamp"}
+
+type Configuration struct {
+	Address   string `json:"address"`
+	Token     string `json:"token"`
+	Namespace string `json:"namespace"`
+	CACert    string `json:"ca_cert"`
+	Mount     string `json:"mount"`
+
+	// KeyNames are the Transit key names by the TUF role.
+	// The roles without a Transit key are signed by the keys stored in the plugin.
+	KeyNames map[string]string `json:"key_names"`
+}
+
+func (cfg *Configuration) NewClient() (*api.Client, error) {
+	config := api.DefaultConfig()
+	if config.Error != nil {
+		return nil, fmt.Errorf("unable to init vault client config: %w", config.Error)
+	}
+
+	config.Address = cfg.Address
+
+	if cfg.CACert != "" {
+		if err := config.ConfigureTLS(&api.TLSConfig{CACertBytes: []byte(cfg.CACert)}); err != nil {
+			return nil, fmt.Errorf("unable to configure vault client tls: %w", err)
+		}
+	}
+
+	client, err := api.NewClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to init vault client: %w", err)
+	}
+
+	client.SetToken(cfg.Token)
+
+	if cfg.Namespace != "" {
+		client.SetNamespace(cfg.Namespace)
+	}
+
+	return client, nil
+}
+
+// Signers returns the Transit signers by the TUF role.
+func (cfg *Configuration) Signers(ctx context.Context) (map[string]keys.Signer, error) {
+	client, err := cfg.NewClient()
+	if err != nil {
+		return nil, err
+	}
+
+	signers := make(map[string]keys.Signer)
+	for _, role := range Roles {
+		keyName, ok := cfg.KeyNames[role]
+		if !ok {
+			continue
+		}
+
+		signer, err := NewSigner(ctx, client, cfg.Mount, keyName)
+		if err != nil {
+			return nil, fmt.Errorf("unable to init %s role signer: %w", role, err)
+		}
+
+		signers[role] = signer
+	}
+
+	return signers, nil
+}
+
+// GetSigners returns the Transit signers by the TUF role or nil if Vault Transit is not configured.
+func GetSigners(ctx context.Context, storage logical.Storage) (map[string]keys.Signer, error) {
+	cfg, err := GetConfiguration(ctx, storage)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get transit configuration: %w", err)
+	}
+
+	if cfg == nil {
+		return nil, nil
+	}
+
+	return cfg.Signers(ctx)
+}
+
+func GetConfiguration(ctx context.Context, storage logical.Storage) (*Configuration, error) {
+	entry, err := storage.Get(ctx, storageKeyConfiguration)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	cfg := new(Configuration)
+	if err := entry.DecodeJSON(cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func PutConfiguration(ctx context.Context, storage logical.Storage, cfg *Configuration) error {
+	entry, err := logical.StorageEntryJSON(storageKeyConfiguration, cfg)
+	if err != nil {
+		return err
+	}
+
+	return storage.Put(ctx, entry)
+}
+
+func DeleteConfiguration(ctx context.Context, storage logical.Storage) error {
+	return storage.Delete(ctx, storageKeyConfiguration)
+}
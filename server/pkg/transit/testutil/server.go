@@ -0,0 +1,152 @@
+package testutil
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const Token = "transit-test-token"
+
+// Server is a minimal fake of the Vault Transit secrets engine with ed25519 keys mounted at transit/.
+type Server struct {
+	*httptest.Server
+
+	mu   sync.Mutex
+	keys map[string][]ed25519.PrivateKey
+}
+
+func NewServer() *Server {
+	s := &Server{keys: make(map[string][]ed25519.PrivateKey)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// CreateKey creates the ed25519 key with the first version.
+func (s *Server) CreateKey(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[name] = []ed25519.PrivateKey{generateKey()}
+}
+
+// PublicKey returns the public key of the key version starting from 1.
+func (s *Server) PublicKey(name string, version int) ed25519.PublicKey {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.keys[name][version-1].Public().(ed25519.PublicKey)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Vault-Token") != Token {
+		writeError(w, http.StatusForbidden, "permission denied")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/v1/transit/"), "/")
+
+	switch {
+	case len(parts) == 2 && parts[0] == "keys" && r.Method == http.MethodGet:
+		versions, ok := s.keys[parts[1]]
+		if !ok {
+			writeError(w, http.StatusNotFound, "")
+			return
+		}
+
+		keysData := make(map[string]interface{})
+		for i, key := range versions {
+			keysData[strconv.Itoa(i+1)] = map[string]interface{}{
+				"public_key": base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+			}
+		}
+
+		writeData(w, map[string]interface{}{
+			"type":           "ed25519",
+			"latest_version": len(versions),
+			"keys":           keysData,
+		})
+
+	case len(parts) == 3 && parts[0] == "keys" && parts[2] == "rotate" && r.Method != http.MethodGet:
+		if _, ok := s.keys[parts[1]]; !ok {
+			writeError(w, http.StatusNotFound, "")
+			return
+		}
+
+		s.keys[parts[1]] = append(s.keys[parts[1]], generateKey())
+		w.WriteHeader(http.StatusNoContent)
+
+	case len(parts) == 2 && parts[0] == "sign" && r.Method != http.MethodGet:
+		versions, ok := s.keys[parts[1]]
+		if !ok {
+			writeError(w, http.StatusBadRequest, "signing key not found")
+			return
+		}
+
+		var req struct {
+			Input      string `json:"input"`
+			KeyVersion int    `json:"key_version"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if req.KeyVersion == 0 {
+			req.KeyVersion = len(versions)
+		}
+
+		if req.KeyVersion < 1 || req.KeyVersion > len(versions) {
+			writeError(w, http.StatusBadRequest, "invalid key version")
+			return
+		}
+
+		input, err := base64.StdEncoding.DecodeString(req.Input)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		signature := ed25519.Sign(versions[req.KeyVersion-1], input)
+		writeData(w, map[string]interface{}{
+			"signature": fmt.Sprintf("vault:v%d:%s", req.KeyVersion, base64.StdEncoding.EncodeToString(signature)),
+		})
+
+	default:
+		writeError(w, http.StatusNotFound, "")
+	}
+}
+
+func generateKey() ed25519.PrivateKey {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		panic(fmt.Sprintf("unable to generate ed25519 key: %s", err))
+	}
+	return key
+}
+
+func writeData(w http.ResponseWriter, data map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	errs := []string{}
+	if message != "" {
+		errs = append(errs, message)
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"errors": errs})
+}
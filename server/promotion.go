@@ -0,0 +1,129 @@
+package server
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/samber/lo"
+
+	"github.com/werf/trdl/server/pkg/config"
+	"github.com/werf/trdl/server/pkg/publisher"
+)
+
+// promotionRule points the channel at the released version if the prerelease part of the version matches the pattern.
+type promotionRule struct {
+	// PrereleasePattern is the path.Match pattern, the empty one matches the versions without prerelease part only.
+	PrereleasePattern string
+	Group             string
+	Channel           string
+}
+
+// parsePromotionRules parses the <prerelease pattern>=<group>/<channel> rules,
+// the channels of the rules must be among the <group>/<channel> auto-promotable channels.
+func parsePromotionRules(rules, autoPromotableChannels []string) ([]promotionRule, error) {
+	for _, groupChannel := range autoPromotableChannels {
+		group, channel, ok := strings.Cut(groupChannel, "/")
+		if !ok {
+			return nil, fmt.Errorf("bad auto-promotable channel %q: expected <group>/<channel>", groupChannel)
+		}
+
+		if err := validateChannelsGroupName(group); err != nil {
+			return nil, fmt.Errorf("bad auto-promotable channel %q: %w", groupChannel, err)
+		}
+
+		if err := validateChannelName(channel); err != nil {
+			return nil, fmt.Errorf("bad auto-promotable channel %q: %w", groupChannel, err)
+		}
+	}
+
+	var result []promotionRule
+	for _, rule := range rules {
+		pattern, groupChannel, ok := strings.Cut(rule, "=")
+		if !ok {
+			return nil, fmt.Errorf("bad rule %q: expected <prerelease pattern>=<group>/<channel>", rule)
+		}
+
+		group, channel, ok := strings.Cut(groupChannel, "/")
+		if !ok {
+			return nil, fmt.Errorf("bad rule %q: expected <prerelease pattern>=<group>/<channel>", rule)
+		}
+
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("bad rule %q prerelease pattern: %w", rule, err)
+		}
+
+		if err := validateChannelsGroupName(group); err != nil {
+			return nil, fmt.Errorf("bad rule %q: %w", rule, err)
+		}
+
+		if err := validateChannelName(channel); err != nil {
+			return nil, fmt.Errorf("bad rule %q: %w", rule, err)
+		}
+
+		if !lo.Contains(autoPromotableChannels, groupChannel) {
+			return nil, fmt.Errorf("bad rule %q: channel %q is not marked auto-promotable", rule, groupChannel)
+		}
+
+		result = append(result, promotionRule{PrereleasePattern: pattern, Group: group, Channel: channel})
+	}
+
+	return result, nil
+}
+
+// promotedChannelsConfig returns the channels to point at the release by the rules.
+// The release must belong to the channel group, the channel is never moved backwards.
+func promotedChannelsConfig(rules []promotionRule, releaseName string, publishedChannels []publisher.PublishedChannel) *config.TrdlChannels {
+	releaseVersion := semver.MustParse(releaseName)
+
+	publishedVersions := make(map[string]string)
+	for _, channel := range publishedChannels {
+		publishedVersions[path.Join(channel.Group, channel.Name)] = channel.Version
+	}
+
+	result := &config.TrdlChannels{}
+	for _, rule := range rules {
+		if matched, _ := path.Match(rule.PrereleasePattern, releaseVersion.Prerelease()); !matched {
+			continue
+		}
+
+		if !isVersionInGroup(releaseVersion, rule.Group) {
+			continue
+		}
+
+		if publishedVersion, err := semver.NewVersion(publishedVersions[path.Join(rule.Group, rule.Channel)]); err == nil && !publishedVersion.LessThan(releaseVersion) {
+			continue
+		}
+
+		groupIndex := lo.IndexOf(lo.Map(result.Groups, func(group config.TrdlGroup, _ int) string { return group.Name }), rule.Group)
+		if groupIndex == -1 {
+			result.Groups = append(result.Groups, config.TrdlGroup{Name: rule.Group})
+			groupIndex = len(result.Groups) - 1
+		}
+
+		group := &result.Groups[groupIndex]
+		if !lo.ContainsBy(group.Channels, func(channel config.TrdlGroupChannel) bool { return channel.Name == rule.Channel }) {
+			group.Channels = append(group.Channels, config.TrdlGroupChannel{Name: rule.Channel, Version: releaseName})
+		}
+	}
+
+	return result
+}
+
+// isVersionInGroup checks the version starts with the group, e.g. 1.2.3 belongs to the groups 1, 1.2 and 1.2.3.
+func isVersionInGroup(version *semver.Version, group string) bool {
+	versionParts := []string{fmt.Sprint(version.Major()), fmt.Sprint(version.Minor()), fmt.Sprint(version.Patch())}
+	groupParts := strings.Split(group, ".")
+	if len(groupParts) > len(versionParts) {
+		return false
+	}
+
+	for i, groupPart := range groupParts {
+		if groupPart != versionParts[i] {
+			return false
+		}
+	}
+
+	return true
+}
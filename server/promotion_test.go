@@ -0,0 +1,65 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/werf/trdl/server/pkg/config"
+	"github.com/werf/trdl/server/pkg/publisher"
+)
+
+func TestPromotedChannelsConfig(t *testing.T) {
+	rules, err := parsePromotionRules(
+		[]string{"alpha.*=1/alpha", "alpha.*=1.2/alpha", "=1/stable", "beta.*=2/beta"},
+		[]string{"1/alpha", "1.2/alpha", "1/stable", "2/beta"},
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	publishedChannels := []publisher.PublishedChannel{
+		{Group: "1", Name: "alpha", Version: "1.3.0-alpha.1"},
+		{Group: "1", Name: "stable", Version: "1.1.0"},
+	}
+
+	for name, test := range map[string]struct {
+		releaseName string
+		expected    *config.TrdlChannels
+	}{
+		"prerelease matching the rules": {
+			releaseName: "1.2.0-alpha.2",
+			expected: &config.TrdlChannels{Groups: []config.TrdlGroup{
+				{Name: "1.2", Channels: []config.TrdlGroupChannel{{Name: "alpha", Version: "1.2.0-alpha.2"}}},
+			}},
+		},
+		"newer prerelease": {
+			releaseName: "1.3.0-alpha.2",
+			expected: &config.TrdlChannels{Groups: []config.TrdlGroup{
+				{Name: "1", Channels: []config.TrdlGroupChannel{{Name: "alpha", Version: "1.3.0-alpha.2"}}},
+			}},
+		},
+		"final release": {
+			releaseName: "1.2.0",
+			expected: &config.TrdlChannels{Groups: []config.TrdlGroup{
+				{Name: "1", Channels: []config.TrdlGroupChannel{{Name: "stable", Version: "1.2.0"}}},
+			}},
+		},
+		"older final release": {
+			releaseName: "1.0.5",
+			expected:    &config.TrdlChannels{},
+		},
+		"release of another group": {
+			releaseName: "3.0.0-beta.1",
+			expected:    &config.TrdlChannels{},
+		},
+		"prerelease not matching the rules": {
+			releaseName: "2.0.0-rc.1",
+			expected:    &config.TrdlChannels{},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, promotedChannelsConfig(rules, test.releaseName, publishedChannels))
+		})
+	}
+}
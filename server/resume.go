@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/logical"
+
+	trdlGit "github.com/werf/trdl/server/pkg/git"
+	"github.com/werf/trdl/server/pkg/publisher"
+	"github.com/werf/trdl/server/pkg/tasks_manager"
+)
+
+// The checkpoints of the release and publish tasks.
+// The task interrupted before the commit is restarted from the beginning, since the staged state is not committed.
+// The task interrupted after the commit is resumed from the recording of the committed state.
+// The task interrupted during the commit cannot be resumed safely.
+const (
+	taskCheckpointCommitting = "committing"
+	taskCheckpointCommitted  = "committed"
+)
+
+func (b *Backend) resumeReleaseTask(ctx context.Context, storage logical.Storage, task *tasks_manager.Task) (func(context.Context, logical.Storage) error, error) {
+	cfg, publisherRepository, audit, err := b.prepareTaskResume(ctx, storage, task, AuditOperationRelease)
+	if err != nil {
+		return nil, err
+	}
+
+	gitTag := task.Params["git_tag"]
+	if gitTag == "" {
+		return nil, errors.New("git tag not found in the task params")
+	}
+
+	switch task.Checkpoint {
+	case "":
+		gitUsername, gitPassword, err := getStoredGitCredential(ctx, storage)
+		if err != nil {
+			return nil, err
+		}
+
+		releaseName := strings.TrimPrefix(gitTag, "v")
+		releaseExists, errResp, err := b.checkReleaseNotPublished(ctx, publisherRepository, releaseName, task.Params["force"] == "true")
+		if err != nil {
+			return nil, err
+		}
+
+		if errResp != nil {
+			return nil, errResp.Error()
+		}
+
+		return b.releaseTask(cfg, publisherRepository, gitTag, gitUsername, gitPassword, releaseExists, audit), nil
+	case taskCheckpointCommitted:
+		gitCommit := task.CheckpointData["git_commit"]
+
+		var stagedTargets []string
+		if targets := task.CheckpointData["staged_targets"]; targets != "" {
+			stagedTargets = strings.Split(targets, ",")
+		}
+
+		return func(ctx context.Context, storage logical.Storage) error {
+			return b.finishReleaseTask(ctx, storage, publisherRepository, gitTag, gitCommit, stagedTargets, audit)
+		}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (b *Backend) resumePublishTask(ctx context.Context, storage logical.Storage, task *tasks_manager.Task) (func(context.Context, logical.Storage) error, error) {
+	cfg, publisherRepository, audit, err := b.prepareTaskResume(ctx, storage, task, AuditOperationPublish)
+	if err != nil {
+		return nil, err
+	}
+
+	switch task.Checkpoint {
+	case "":
+		gitUsername, gitPassword, err := getStoredGitCredential(ctx, storage)
+		if err != nil {
+			return nil, err
+		}
+
+		lastPublishedGitCommit, err := getLastPublishedGitCommit(ctx, storage, cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		return b.publishTask(cfg, publisherRepository, gitUsername, gitPassword, lastPublishedGitCommit, audit), nil
+	case taskCheckpointCommitted:
+		headCommit := task.CheckpointData["git_commit"]
+
+		return func(ctx context.Context, storage logical.Storage) error {
+			return b.finishPublishTask(ctx, storage, publisherRepository, headCommit, audit)
+		}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (b *Backend) prepareTaskResume(ctx context.Context, storage logical.Storage, task *tasks_manager.Task, operation AuditOperation) (*configuration, publisher.RepositoryInterface, *auditEntry, error) {
+	cfg, err := getConfiguration(ctx, storage)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("unable to get configuration from storage: %w", err)
+	}
+
+	if cfg == nil {
+		return nil, nil, nil, errors.New("configuration not found")
+	}
+
+	publisherRepository, err := b.getTaskPublisherRepository(ctx, storage, cfg)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error getting publisher repository: %w", err)
+	}
+
+	audit := &auditEntry{
+		Operation:   operation,
+		DisplayName: task.Initiator,
+		EntityID:    task.InitiatorEntityID,
+		Details:     map[string]string{"resumed": "true"},
+	}
+
+	return cfg, publisherRepository, audit, nil
+}
+
+// getStoredGitCredential returns the git credential from the storage, since the credential passed with the request is not persisted.
+func getStoredGitCredential(ctx context.Context, storage logical.Storage) (string, string, error) {
+	gitCredential, err := trdlGit.GetGitCredential(ctx, storage)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to get git credential from storage: %w", err)
+	}
+
+	if gitCredential == nil {
+		return "", "", nil
+	}
+
+	return gitCredential.Username, gitCredential.Password, nil
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/werf/trdl/server/pkg/publisher"
+	"github.com/werf/trdl/server/pkg/tasks_manager"
+)
+
+type ResumeSuite struct {
+	CommonSuite
+}
+
+func (suite *ResumeSuite) SetupTest() {
+	suite.CommonSuite.SetupTest()
+
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+
+	suite.mockedPublisher.On("GetRepository").Return(nil)
+}
+
+func (suite *ResumeSuite) releaseTask(checkpoint string, params map[string]string) *tasks_manager.Task {
+	return &tasks_manager.Task{
+		UUID:           "UUID",
+		Operation:      string(AuditOperationRelease),
+		Params:         params,
+		Initiator:      "token-ci",
+		Checkpoint:     checkpoint,
+		CheckpointData: map[string]string{"git_commit": "abc", "staged_targets": "linux-amd64/bin/app"},
+	}
+}
+
+func (suite *ResumeSuite) TestReleaseBeforeCommit() {
+	suite.mockedPublisher.On("GetYankedReleases").Return([]publisher.YankedRelease(nil))
+	suite.mockedPublisher.On("GetExistingReleases").Return([]string{"1.0.0"})
+
+	taskFunc, err := suite.backend.resumeReleaseTask(suite.ctx, suite.storage, suite.releaseTask("", map[string]string{"git_tag": fieldGitTagValidValue}))
+	assert.Nil(suite.T(), err)
+	assert.NotNil(suite.T(), taskFunc)
+}
+
+func (suite *ResumeSuite) TestReleasePublishedWhileInterrupted() {
+	suite.mockedPublisher.On("GetYankedReleases").Return([]publisher.YankedRelease(nil))
+	suite.mockedPublisher.On("GetExistingReleases").Return([]string{"1.0.1"})
+
+	taskFunc, err := suite.backend.resumeReleaseTask(suite.ctx, suite.storage, suite.releaseTask("", map[string]string{"git_tag": fieldGitTagValidValue}))
+	assert.EqualError(suite.T(), err, `Release "1.0.1" is already published, set "force" to rebuild it`)
+	assert.Nil(suite.T(), taskFunc)
+
+	taskFunc, err = suite.backend.resumeReleaseTask(suite.ctx, suite.storage, suite.releaseTask("", map[string]string{"git_tag": fieldGitTagValidValue, "force": "true"}))
+	assert.Nil(suite.T(), err)
+	assert.NotNil(suite.T(), taskFunc)
+}
+
+func (suite *ResumeSuite) TestReleaseDuringCommit() {
+	taskFunc, err := suite.backend.resumeReleaseTask(suite.ctx, suite.storage, suite.releaseTask(taskCheckpointCommitting, map[string]string{"git_tag": fieldGitTagValidValue}))
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), taskFunc)
+}
+
+func (suite *ResumeSuite) TestReleaseAfterCommit() {
+	taskFunc, err := suite.backend.resumeReleaseTask(suite.ctx, suite.storage, suite.releaseTask(taskCheckpointCommitted, map[string]string{"git_tag": fieldGitTagValidValue}))
+	assert.Nil(suite.T(), err)
+	assert.NotNil(suite.T(), taskFunc)
+
+	suite.mockedPublisher.AssertNotCalled(suite.T(), "GetExistingReleases")
+}
+
+func (suite *ResumeSuite) TestReleaseWithoutGitTag() {
+	taskFunc, err := suite.backend.resumeReleaseTask(suite.ctx, suite.storage, suite.releaseTask("", nil))
+	assert.EqualError(suite.T(), err, "git tag not found in the task params")
+	assert.Nil(suite.T(), taskFunc)
+}
+
+func (suite *ResumeSuite) TestPublish() {
+	for _, test := range []struct {
+		checkpoint  string
+		isResumable bool
+	}{
+		{checkpoint: "", isResumable: true},
+		{checkpoint: taskCheckpointCommitting, isResumable: false},
+		{checkpoint: taskCheckpointCommitted, isResumable: true},
+	} {
+		task := &tasks_manager.Task{Operation: string(AuditOperationPublish), Checkpoint: test.checkpoint}
+
+		taskFunc, err := suite.backend.resumePublishTask(suite.ctx, suite.storage, task)
+		assert.Nil(suite.T(), err)
+		assert.Equal(suite.T(), test.isResumable, taskFunc != nil, "checkpoint %q", test.checkpoint)
+	}
+}
+
+func (suite *ResumeSuite) TestConfigurationNotFound() {
+	err := suite.storage.Delete(suite.ctx, storageKeyConfiguration)
+	assert.Nil(suite.T(), err)
+
+	taskFunc, err := suite.backend.resumePublishTask(suite.ctx, suite.storage, &tasks_manager.Task{Operation: string(AuditOperationPublish)})
+	assert.EqualError(suite.T(), err, "configuration not found")
+	assert.Nil(suite.T(), taskFunc)
+}
+
+func TestResume(t *testing.T) {
+	suite.Run(t, new(ResumeSuite))
+}
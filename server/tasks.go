@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/samber/lo"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/werf/trdl/server/pkg/publisher"
+	"github.com/werf/trdl/server/pkg/tasks_manager"
+)
+
+// The resources of the tasks, the tasks with the common resources run one by one.
+// The release build and the channels publication run concurrently and only their commits are serialized:
+// the commit staged on top of the outdated repository state is re-applied to the committed one.
+const (
+	taskResourceBuild     = "build"
+	taskResourceChannels  = "channels"
+	taskResourceTufCommit = "tuf-commit"
+)
+
+// lockChannels locks the channels for the part of the task promoting them, so it does not overlap with the channels publication.
+func lockChannels(ctx context.Context) (func(), error) {
+	return tasks_manager.LockTaskResource(ctx, taskResourceChannels)
+}
+
+// lockTufCommit locks the TUF repository commit for the task, the periodic TUF metadata update waits for it.
+func lockTufCommit(ctx context.Context) (func(), error) {
+	return tasks_manager.LockTaskResource(ctx, taskResourceTufCommit)
+}
+
+// getTaskPublisherRepository returns the repository the task stages its changes to.
+// The retried task gets the new one to drop the changes staged by the failed attempt.
+func (b *Backend) getTaskPublisherRepository(ctx context.Context, storage logical.Storage, cfg *configuration) (publisher.RepositoryInterface, error) {
+	opts := cfg.RepositoryOptions()
+	opts.InitializeTUFKeys = true
+	opts.InitializePGPSigningKey = true
+
+	return b.Publisher.GetRepository(ctx, storage, opts)
+}
+
+// discardStagedChanges deletes the targets uploaded by the failed task, the task gets the new repository handle to continue.
+func (b *Backend) discardStagedChanges(ctx context.Context, publisherRepository publisher.RepositoryInterface) {
+	if err := publisherRepository.DiscardStaged(ctx); err != nil {
+		b.Logger().Warn(fmt.Sprintf("Unable to discard the staged changes of the failed task: %s", err))
+	}
+}
+
+var retryableAwsErrorCodes = []string{
+	request.ErrCodeRequestError,
+	request.ErrCodeResponseTimeout,
+	"RequestTimeout",
+	"SlowDown",
+	"InternalError",
+	"ServiceUnavailable",
+}
+
+// isRetryableTaskError returns true for the transient S3, network and buildkitd connection errors: the timeouts,
+// the reset connections, the S3 5xx and 429 responses. The unknown host, the refused connection, the TLS errors,
+// the validation and signature verification errors are not retried.
+func isRetryableTaskError(err error) bool {
+	if isPermanentNetworkError(err) {
+		return false
+	}
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && lo.Contains(retryableAwsErrorCodes, awsErr.Code()) {
+		return true
+	}
+
+	var requestFailure awserr.RequestFailure
+	if errors.As(err, &requestFailure) {
+		return requestFailure.StatusCode() >= http.StatusInternalServerError || requestFailure.StatusCode() == http.StatusTooManyRequests
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+
+	return status.Code(err) == codes.Unavailable
+}
+
+// isPermanentNetworkError returns true for the network errors, which are not fixed by the retry.
+// The S3 errors are checked along with their original errors.
+func isPermanentNetworkError(err error) bool {
+	for err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return true
+		}
+
+		var certErr *tls.CertificateVerificationError
+		var recordHeaderErr tls.RecordHeaderError
+		var alertErr tls.AlertError
+		var unknownAuthorityErr x509.UnknownAuthorityError
+		var hostnameErr x509.HostnameError
+		var certInvalidErr x509.CertificateInvalidError
+		if errors.Is(err, syscall.ECONNREFUSED) || errors.As(err, &certErr) || errors.As(err, &recordHeaderErr) || errors.As(err, &alertErr) ||
+			errors.As(err, &unknownAuthorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &certInvalidErr) {
+			return true
+		}
+
+		var awsErr awserr.Error
+		if !errors.As(err, &awsErr) {
+			return false
+		}
+		err = awsErr.OrigErr()
+	}
+
+	return false
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIsRetryableTaskError(t *testing.T) {
+	for name, test := range map[string]struct {
+		err       error
+		retryable bool
+	}{
+		"s3 5xx": {
+			err:       awserr.NewRequestFailure(awserr.New("InternalError", "internal error", nil), http.StatusInternalServerError, "id"),
+			retryable: true,
+		},
+		"s3 slow down": {
+			err:       fmt.Errorf("unable to publish release target %q: %w", "target", awserr.NewRequestFailure(awserr.New("SlowDown", "slow down", nil), http.StatusServiceUnavailable, "id")),
+			retryable: true,
+		},
+		"s3 request timeout": {
+			err:       awserr.NewRequestFailure(awserr.New("RequestTimeout", "request timeout", nil), http.StatusBadRequest, "id"),
+			retryable: true,
+		},
+		"s3 request error": {
+			err:       awserr.New(request.ErrCodeRequestError, "send request failed", errors.New("connection reset")),
+			retryable: true,
+		},
+		"s3 access denied": {
+			err:       awserr.NewRequestFailure(awserr.New("AccessDenied", "access denied", nil), http.StatusForbidden, "id"),
+			retryable: false,
+		},
+		"s3 request error of unknown host": {
+			err:       awserr.New(request.ErrCodeRequestError, "send request failed", &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "s3.example.com", IsNotFound: true}}),
+			retryable: false,
+		},
+		"network timeout": {
+			err:       fmt.Errorf("unable to clone git repository: %w", &net.OpError{Op: "read", Err: os.ErrDeadlineExceeded}),
+			retryable: true,
+		},
+		"connection reset": {
+			err:       fmt.Errorf("unable to clone git repository: %w", &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}),
+			retryable: true,
+		},
+		"connection refused": {
+			err:       fmt.Errorf("unable to clone git repository: %w", &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}),
+			retryable: false,
+		},
+		"unknown host": {
+			err:       fmt.Errorf("unable to clone git repository: %w", &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "git.example.com", IsNotFound: true}}),
+			retryable: false,
+		},
+		"tls error": {
+			err:       fmt.Errorf("unable to clone git repository: %w", &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}),
+			retryable: false,
+		},
+		"other network error": {
+			err:       fmt.Errorf("unable to clone git repository: %w", &net.OpError{Op: "dial", Err: errors.New("network is unreachable")}),
+			retryable: false,
+		},
+		"eof": {
+			err:       fmt.Errorf("unable to clone git repository: %w", io.EOF),
+			retryable: true,
+		},
+		"unexpected eof": {
+			err:       fmt.Errorf("error reading next tar artifact header: %w", io.ErrUnexpectedEOF),
+			retryable: true,
+		},
+		"buildkitd disconnect": {
+			err:       fmt.Errorf("unable to build release artifacts: build failed: %w", status.Error(codes.Unavailable, "connection closed")),
+			retryable: true,
+		},
+		"build failure": {
+			err:       fmt.Errorf("unable to build release artifacts: build failed: %w", status.Error(codes.Unknown, "process exited with code 1")),
+			retryable: false,
+		},
+		"signature verification failure": {
+			err:       fmt.Errorf("signature verification failed: %w", errors.New("not enough verified signatures")),
+			retryable: false,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.retryable, isRetryableTaskError(test.err))
+		})
+	}
+}